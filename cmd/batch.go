@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/dmcleish91/go_todo_api/internal/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// maxBatchOperations caps the number of operations accepted by POST /v1/tasks/batch.
+const maxBatchOperations = 100
+
+const (
+	batchModeAtomic     = "atomic"
+	batchModeBestEffort = "best_effort"
+)
+
+// batchOperation is one entry of a batch request. Data holds the body the matching
+// single-item endpoint accepts: a NewTask for create, a Task for update and a TaskMove for move.
+type batchOperation struct {
+	Ref    string          `json:"ref"`
+	Op     string          `json:"op"`
+	TaskID uuid.UUID       `json:"task_id"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// batchResult is the outcome of one operation. Status is the HTTP status the single-item
+// endpoint would have answered with. completed records that a complete operation switched an
+// open task to completed, for the metrics.
+type batchResult struct {
+	Status int `json:"status"`
+	Data   any `json:"data,omitempty"`
+	Error  any `json:"error,omitempty"`
+
+	completed bool
+}
+
+// BatchTasks handles POST /v1/tasks/batch
+// All operations run in one transaction. In atomic mode the first failure rolls everything back;
+// in best_effort mode each operation runs in its own savepoint and failures are skipped.
+func (app *application) BatchTasks(c echo.Context) error {
+	var input struct {
+		Mode       string           `json:"mode"`
+		Operations []batchOperation `json:"operations"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	if input.Mode == "" {
+		input.Mode = batchModeAtomic
+	}
+	v := models.NewValidator()
+	v.Check(input.Mode == batchModeAtomic || input.Mode == batchModeBestEffort, "mode", "Mode must be atomic or best_effort")
+	v.Check(len(input.Operations) > 0, "operations", "At least one operation is required")
+	v.Check(len(input.Operations) <= maxBatchOperations, "operations", fmt.Sprintf("At most %d operations are allowed", maxBatchOperations))
+	refs := map[string]bool{}
+	for _, op := range input.Operations {
+		v.Check(op.Ref != "", "operations", "Every operation needs a ref")
+		v.Check(!refs[op.Ref], "operations", "Operation refs must be unique")
+		refs[op.Ref] = true
+	}
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+
+	rules, err := app.completionRules(uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	results := make(map[string]batchResult, len(input.Operations))
+	failed := -1
+	err = app.tasks.Atomic(ctx, func(tasks models.TaskStore) error {
+		for i, op := range input.Operations {
+			result, err := runInSavepoint(ctx, tasks, func(sp models.TaskStore) batchResult {
+				return app.runBatchOperation(sp, uid, rules, op)
+			})
+			if err != nil {
+				return err
+			}
+			results[op.Ref] = result
+			if result.Status >= 400 && input.Mode == batchModeAtomic {
+				failed = i
+				return errBatchOperationFailed
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchOperationFailed) {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	if failed >= 0 {
+		for i, op := range input.Operations {
+			switch {
+			case i < failed:
+				results[op.Ref] = batchResult{Status: http.StatusFailedDependency, Error: "Rolled back because another operation failed"}
+			case i > failed:
+				results[op.Ref] = batchResult{Status: http.StatusFailedDependency, Error: "Not attempted because another operation failed"}
+			}
+		}
+		status := results[input.Operations[failed].Ref].Status
+		return c.JSON(status, map[string]any{"mode": input.Mode, "committed": false, "results": results})
+	}
+
+	app.countBatchOperations(input.Operations, results)
+	return c.JSON(http.StatusOK, map[string]any{"mode": input.Mode, "committed": true, "results": results})
+}
+
+// countBatchOperations adds the committed creates and completions of a batch to the task
+// metrics. It runs after the commit so that rolled-back operations aren't counted, and skips
+// completions of tasks that were already completed.
+func (app *application) countBatchOperations(ops []batchOperation, results map[string]batchResult) {
+	for _, op := range ops {
+		if results[op.Ref].Status >= 400 {
+			continue
+		}
+		switch op.Op {
+		case "create":
+			app.metrics.tasksCreated.Inc()
+		case "complete":
+			if results[op.Ref].completed {
+				app.metrics.tasksCompleted.Inc()
+			}
+		}
+	}
+}
+
+// errBatchOperationFailed rolls back a savepoint, or the whole batch in atomic mode, after an
+// operation failed. The failure itself is reported in the operation's batchResult.
+var errBatchOperationFailed = errors.New("batch operation failed")
+
+// runInSavepoint runs fn against a savepoint of tasks, releasing the savepoint on success and
+// rolling back to it when fn reports a failure.
+func runInSavepoint(ctx context.Context, tasks models.TaskStore, fn func(models.TaskStore) batchResult) (batchResult, error) {
+	var result batchResult
+	err := tasks.Atomic(ctx, func(sp models.TaskStore) error {
+		result = fn(sp)
+		if result.Status >= 400 {
+			return errBatchOperationFailed
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchOperationFailed) {
+		err = nil
+	}
+	return result, err
+}
+
+// runBatchOperation applies a single operation with the same validation and error mapping
+// as the matching single-item handler.
+func (app *application) runBatchOperation(tasks models.TaskStore, userID uuid.UUID, rules models.CompletionRules, op batchOperation) batchResult {
+	switch op.Op {
+	case "create":
+		var input models.NewTask
+		if err := json.Unmarshal(op.Data, &input); err != nil {
+			return batchResult{Status: http.StatusBadRequest, Error: "Invalid input"}
+		}
+		v := models.NewValidator()
+		if err := app.validateNewTask(v, input, userID); err != nil {
+			return batchError(err)
+		}
+		if !v.Valid() {
+			return batchResult{Status: http.StatusUnprocessableEntity, Error: v.Errors}
+		}
+		created, err := tasks.AddTask(input, userID)
+		if err != nil {
+			return batchError(err)
+		}
+		return batchResult{Status: http.StatusCreated, Data: created}
+
+	case "update":
+		var task models.Task
+		if err := json.Unmarshal(op.Data, &task); err != nil {
+			return batchResult{Status: http.StatusBadRequest, Error: "Invalid input"}
+		}
+		if op.TaskID != uuid.Nil {
+			task.TaskID = op.TaskID
+		}
+		task.UserID = userID
+		v := models.NewValidator()
+		if err := app.validateTaskProject(v, task.ProjectID, task.AssigneeID, userID); err != nil {
+			return batchError(err)
+		}
+		if !v.Valid() {
+			return batchResult{Status: http.StatusUnprocessableEntity, Error: v.Errors}
+		}
+		updated, err := tasks.EditTaskByID(task)
+		if err != nil {
+			return batchError(err)
+		}
+		return batchResult{Status: http.StatusOK, Data: updated}
+
+	case "complete":
+		completed, changed, err := tasks.SetTaskCompleted(op.TaskID, userID, true, nil, rules)
+		if err != nil {
+			return batchError(err)
+		}
+		return batchResult{Status: http.StatusOK, Data: completed, completed: changed}
+
+	case "move":
+		var move models.TaskMove
+		if err := json.Unmarshal(op.Data, &move); err != nil {
+			return batchResult{Status: http.StatusBadRequest, Error: "Invalid input"}
+		}
+		v := models.NewValidator()
+		validateTaskMove(v, move)
+		if !v.Valid() {
+			return batchResult{Status: http.StatusUnprocessableEntity, Error: v.Errors}
+		}
+		moved, err := tasks.MoveTask(op.TaskID, userID, move)
+		if err != nil {
+			return batchError(err)
+		}
+		return batchResult{Status: http.StatusOK, Data: moved}
+
+	case "delete":
+		rowsAffected, err := tasks.DeleteTaskByID(op.TaskID, userID)
+		if err != nil {
+			return batchError(err)
+		}
+		// Like DELETE /v1/tasks, deleting a task that doesn't exist succeeds with rows_affected 0.
+		return batchResult{Status: http.StatusOK, Data: map[string]int64{"rows_affected": rowsAffected}}
+	}
+
+	return batchResult{Status: http.StatusUnprocessableEntity, Error: map[string]string{"op": "Op must be one of create, update, complete, move or delete"}}
+}
+
+// batchError maps a model error to the status the single-item handlers use for it.
+func batchError(err error) batchResult {
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		return batchResult{Status: http.StatusNotFound, Error: "Task not found"}
+	case errors.Is(err, models.ErrUnknownLabel):
+		return batchResult{Status: http.StatusUnprocessableEntity, Error: map[string]string{"label_ids": "Labels must exist and belong to you"}}
+	case errors.Is(err, models.ErrMoveIntoSubtree):
+		return batchResult{Status: http.StatusUnprocessableEntity, Error: "Cannot move a task under itself or one of its subtasks"}
+	case errors.Is(err, models.ErrTaskBlocked):
+		return batchResult{Status: http.StatusConflict, Error: "Task is blocked by open tasks"}
+	}
+	return batchResult{Status: http.StatusInternalServerError, Error: err.Error()}
+}
@@ -2,18 +2,352 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
 )
 
-func CreateDatabaseConnection(url string) *pgxpool.Pool {
-	conn, err := pgxpool.New(context.Background(), url)
+// envPrefix is prepended to every setting's name in the environment and in config files, so
+// that names like USER and PORT don't collide with the shell's own variables.
+const envPrefix = "TODO_"
+
+// Config is the server's configuration. It is loaded once at startup by LoadConfig.
+type Config struct {
+	// ListenAddr is the address the HTTP server listens on.
+	ListenAddr string
+	// ShutdownTimeout is how long in-flight requests get to finish once shutdown starts.
+	ShutdownTimeout time.Duration
+	// MetricsAddr is a separate address for /metrics. When empty, /metrics is served on
+	// ListenAddr if MetricsToken is set, and not at all otherwise.
+	MetricsAddr string
+	// MetricsToken is the bearer token /metrics requires, if any.
+	MetricsToken string
+	// CORSOrigins are the origins allowed to make cross-origin requests.
+	CORSOrigins []string
+	// JWTSigningKey verifies the Supabase access tokens sent with /v1 requests.
+	JWTSigningKey []byte
+
+	// StorageBackend is "postgres" or "sqlite".
+	StorageBackend string
+	// SQLitePath is the database file used by the sqlite backend.
+	SQLitePath string
+	// Database is the connection used by the postgres backend.
+	Database DatabaseConfig
+	// MigrateOnStart applies pending migrations before the server starts.
+	MigrateOnStart bool
+
+	// EnforceBlockers refuses to complete tasks that still have open blockers.
+	EnforceBlockers bool
+	// IdempotencyTTL is how long a stored response can be replayed for its Idempotency-Key.
+	IdempotencyTTL time.Duration
+	// ArchiveAfter is how long after completion a task is archived; zero disables archiving.
+	ArchiveAfter time.Duration
+
+	// Args are the command-line arguments left after the flags, such as "migrate up".
+	Args []string
+}
+
+// DatabaseConfig is the Postgres connection and pool configuration. URL, when set, replaces the
+// individual connection fields.
+type DatabaseConfig struct {
+	URL      string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+
+	// Zero values leave pgx's defaults in place.
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+}
+
+// ConnString returns URL, or a connection URL built from the individual fields.
+func (db DatabaseConfig) ConnString() string {
+	if db.URL != "" {
+		return db.URL
+	}
+
+	u := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(db.User, db.Password),
+		Host:   net.JoinHostPort(db.Host, db.Port),
+		Path:   "/" + db.Name,
+	}
+	if db.Password == "" {
+		u.User = url.User(db.User)
+	}
+	return u.String()
+}
+
+// setting is one configuration value. name is its environment variable without envPrefix and
+// its flag is the same name in lower case with dashes, so DB_MAX_CONNS is set by -db-max-conns.
+type setting struct {
+	name     string
+	fallback string
+	usage    string
+	parse    func(value string) error
+}
+
+func (s setting) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(s.name), "_", "-")
+}
+
+// configSettings lists every setting LoadConfig reads, each parsing into its field of cfg.
+func configSettings(cfg *Config) []setting {
+	return []setting{
+		{"LISTEN_ADDR", ":1323", "address the HTTP server listens on", stringValue(&cfg.ListenAddr)},
+		{"SHUTDOWN_TIMEOUT", "30s", "how long in-flight requests get to finish on shutdown", durationValue(&cfg.ShutdownTimeout)},
+		{"METRICS_ADDR", "", "separate address serving /metrics", stringValue(&cfg.MetricsAddr)},
+		{"METRICS_TOKEN", "", "bearer token required by /metrics", stringValue(&cfg.MetricsToken)},
+		{"CORS_ORIGINS", "http://localhost:5173,https://yata-delta.vercel.app", "comma-separated origins allowed to make cross-origin requests", listValue(&cfg.CORSOrigins)},
+		{"SUPABASE_JWT_SIGNINGKEY", "", "key that verifies Supabase access tokens", func(value string) error {
+			cfg.JWTSigningKey = []byte(value)
+			return nil
+		}},
+
+		{"STORAGE_BACKEND", "postgres", "storage backend: postgres or sqlite", stringValue(&cfg.StorageBackend)},
+		{"SQLITE_PATH", "todo.db", "database file for the sqlite backend", stringValue(&cfg.SQLitePath)},
+		{"DATABASE_URL", "", "Postgres connection URL; replaces the DB_HOST, DB_PORT, DB_USER, DB_PASSWORD and DB_NAME settings", stringValue(&cfg.Database.URL)},
+		{"DB_HOST", "", "Postgres host", stringValue(&cfg.Database.Host)},
+		{"DB_PORT", "5432", "Postgres port", stringValue(&cfg.Database.Port)},
+		{"DB_USER", "", "Postgres user", stringValue(&cfg.Database.User)},
+		{"DB_PASSWORD", "", "Postgres password", stringValue(&cfg.Database.Password)},
+		{"DB_NAME", "", "Postgres database name", stringValue(&cfg.Database.Name)},
+		{"DB_MAX_CONNS", "0", "maximum connections in the pool; 0 keeps pgx's default", int32Value(&cfg.Database.MaxConns)},
+		{"DB_MIN_CONNS", "0", "connections the pool keeps open when idle", int32Value(&cfg.Database.MinConns)},
+		{"DB_MAX_CONN_LIFETIME", "0", "how long a connection is used before it is replaced; 0 keeps pgx's default", durationValue(&cfg.Database.MaxConnLifetime)},
+		{"DB_MAX_CONN_IDLE_TIME", "0", "how long an idle connection is kept; 0 keeps pgx's default", durationValue(&cfg.Database.MaxConnIdleTime)},
+		{"MIGRATE_ON_START", "false", "apply pending migrations when the server starts", boolValue(&cfg.MigrateOnStart)},
+
+		{"ENFORCE_TASK_BLOCKERS", "false", "refuse to complete tasks with open blockers", boolValue(&cfg.EnforceBlockers)},
+		{"IDEMPOTENCY_KEY_TTL", "24h", "how long responses are kept for their Idempotency-Key", durationValue(&cfg.IdempotencyTTL)},
+		{"ARCHIVE_COMPLETED_AFTER_DAYS", "30", "days after completion that tasks are archived; 0 disables archiving", func(value string) error {
+			days, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%q is not a whole number", value)
+			}
+			cfg.ArchiveAfter = time.Duration(days) * 24 * time.Hour
+			return nil
+		}},
+	}
+}
+
+func stringValue(field *string) func(string) error {
+	return func(value string) error {
+		*field = value
+		return nil
+	}
+}
+
+func listValue(field *[]string) func(string) error {
+	return func(value string) error {
+		*field = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*field = append(*field, item)
+			}
+		}
+		return nil
+	}
+}
+
+func boolValue(field *bool) func(string) error {
+	return func(value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		*field = b
+		return nil
+	}
+}
+
+func int32Value(field *int32) func(string) error {
+	return func(value string) error {
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		*field = int32(n)
+		return nil
+	}
+}
+
+func durationValue(field *time.Duration) func(string) error {
+	return func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 24h", value)
+		}
+		*field = d
+		return nil
+	}
+}
+
+// LoadConfig reads the configuration from args, the environment and an optional config file.
+// A flag beats an environment variable, which beats the config file, which beats the default.
+// The config file is named by -config or TODO_CONFIG_FILE and holds KEY=value lines using the
+// environment variable names. Every invalid or missing setting is reported in the one error.
+func LoadConfig(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	var cfg Config
+	settings := configSettings(&cfg)
+
+	fs := flag.NewFlagSet("todoapi", flag.ContinueOnError)
+	configFile := fs.String("config", "", "file of KEY=value settings (env "+envPrefix+"CONFIG_FILE)")
+	flags := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flags[s.name] = fs.String(s.flagName(), s.fallback, s.usage+" (env "+envPrefix+s.name+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	path := *configFile
+	if !given["config"] {
+		path, _ = lookupEnv(envPrefix + "CONFIG_FILE")
+	}
+	var file map[string]string
+	if path != "" {
+		var err error
+		if file, err = godotenv.Read(path); err != nil {
+			return Config{}, fmt.Errorf("unable to read config file: %w", err)
+		}
+	}
+
+	var problems []string
+	for _, s := range settings {
+		// Parsing the fallback first leaves a valid value behind if value turns out not to be,
+		// so validate doesn't report the same setting twice.
+		s.parse(s.fallback)
+
+		value := s.fallback
+		if v, ok := file[envPrefix+s.name]; ok {
+			value = v
+		}
+		if v, ok := lookupEnv(envPrefix + s.name); ok {
+			value = v
+		}
+		if given[s.flagName()] {
+			value = *flags[s.name]
+		}
+		if err := s.parse(value); err != nil {
+			problems = append(problems, envPrefix+s.name+": "+err.Error())
+		}
+	}
+	cfg.Args = fs.Args()
+
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return Config{}, fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return cfg, nil
+}
+
+// validate returns a description of every setting that is missing or out of range.
+func (cfg *Config) validate() []string {
+	var problems []string
+	missing := func(name string) {
+		problems = append(problems, envPrefix+name+" is not set")
+	}
+
+	// The migrate subcommand only needs the storage settings.
+	if len(cfg.Args) == 0 || cfg.Args[0] != "migrate" {
+		if cfg.ListenAddr == "" {
+			missing("LISTEN_ADDR")
+		}
+		if len(cfg.JWTSigningKey) == 0 {
+			missing("SUPABASE_JWT_SIGNINGKEY")
+		}
+		if cfg.MetricsAddr != "" && cfg.MetricsAddr == cfg.ListenAddr {
+			problems = append(problems, envPrefix+"METRICS_ADDR must differ from "+envPrefix+"LISTEN_ADDR")
+		}
+	}
+
+	switch cfg.StorageBackend {
+	case "postgres":
+		db := cfg.Database
+		if db.URL != "" {
+			if _, err := pgxpool.ParseConfig(db.URL); err != nil {
+				problems = append(problems, envPrefix+"DATABASE_URL: "+err.Error())
+			}
+			break
+		}
+		if db.Host == "" {
+			missing("DB_HOST")
+		}
+		if db.Port == "" {
+			missing("DB_PORT")
+		}
+		if db.User == "" {
+			missing("DB_USER")
+		}
+		if db.Name == "" {
+			missing("DB_NAME")
+		}
+	case "sqlite":
+		if cfg.SQLitePath == "" {
+			missing("SQLITE_PATH")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("%sSTORAGE_BACKEND: %q is not postgres or sqlite", envPrefix, cfg.StorageBackend))
+	}
+
+	if cfg.Database.MaxConns < 0 || cfg.Database.MinConns < 0 {
+		problems = append(problems, envPrefix+"DB_MAX_CONNS and "+envPrefix+"DB_MIN_CONNS must not be negative")
+	}
+	if cfg.Database.MaxConns > 0 && cfg.Database.MinConns > cfg.Database.MaxConns {
+		problems = append(problems, envPrefix+"DB_MIN_CONNS must not be more than "+envPrefix+"DB_MAX_CONNS")
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		problems = append(problems, envPrefix+"SHUTDOWN_TIMEOUT must be positive")
+	}
+	if cfg.IdempotencyTTL <= 0 {
+		problems = append(problems, envPrefix+"IDEMPOTENCY_KEY_TTL must be positive")
+	}
+	if cfg.ArchiveAfter < 0 {
+		problems = append(problems, envPrefix+"ARCHIVE_COMPLETED_AFTER_DAYS must not be negative")
+	}
+	return problems
+}
+
+func CreateDatabaseConnection(db DatabaseConfig) *pgxpool.Pool {
+	poolConfig, err := pgxpool.ParseConfig(db.ConnString())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
+		os.Exit(1)
+	}
+	if db.MaxConns > 0 {
+		poolConfig.MaxConns = db.MaxConns
+	}
+	if db.MinConns > 0 {
+		poolConfig.MinConns = db.MinConns
+	}
+	if db.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = db.MaxConnLifetime
+	}
+	if db.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = db.MaxConnIdleTime
+	}
+
+	conn, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
 		os.Exit(1)
 	}
 
 	return conn
-}
\ No newline at end of file
+}
@@ -1,13 +1,29 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/dmcleish91/go_todo_api/internal/models"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
+// maxTemplateFileSize caps the size of an imported template file.
+const maxTemplateFileSize = 1 << 20
+
+// Page size bounds for GET /v1/tasks/completed.
+const (
+	defaultCompletedTasksLimit = 100
+	maxCompletedTasksLimit     = 1000
+)
+
 func GetUserID(c echo.Context) string {
 	if userID, ok := c.Get("user_id").(string); ok {
 		return userID
@@ -70,13 +86,100 @@ func (app *application) GetProjectsByUserID(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
 	}
-	projects, err := app.projects.GetProjectsByUserID(uid)
+	includeArchived := false
+	if raw := c.QueryParam("include_archived"); raw != "" {
+		includeArchived, err = strconv.ParseBool(raw)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid include_archived"})
+		}
+	}
+	projects, err := app.projects.GetProjectsByUserID(uid, includeArchived)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 	return c.JSON(http.StatusOK, projects)
 }
 
+// ArchiveProject handles POST /v1/projects/:id/archive
+func (app *application) ArchiveProject(c echo.Context) error {
+	return app.setProjectArchived(c, true)
+}
+
+// UnarchiveProject handles POST /v1/projects/:id/unarchive
+func (app *application) UnarchiveProject(c echo.Context) error {
+	return app.setProjectArchived(c, false)
+}
+
+// setProjectArchived archives or unarchives a project and all of its sub-projects.
+func (app *application) setProjectArchived(c echo.Context, archived bool) error {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid project ID"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	projects, err := app.projects.ArchiveProject(projectID, uid, archived)
+	if errors.Is(err, models.ErrNoRecord) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Project not found or not owned by user"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	message := "Project archived successfully"
+	if !archived {
+		message = "Project unarchived successfully"
+	}
+	return c.JSON(http.StatusOK, map[string]any{"message": message, "data": projects})
+}
+
+// HandleReorderProjects handles PATCH /v1/projects/reorder
+// All projects must be siblings (same parent_project_id).
+func (app *application) HandleReorderProjects(c echo.Context) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	var updates []models.ProjectOrderUpdate
+	if err := c.Bind(&updates); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+	if len(updates) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "No projects to reorder"})
+	}
+	for _, upd := range updates {
+		if upd.ProjectID == uuid.Nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid project ID"})
+		}
+		if !models.ValidOrder(upd.Order) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Each order must be a 32-bit integer"})
+		}
+	}
+
+	err = app.projects.BulkUpdateProjectOrder(uid, updates)
+	switch {
+	case errors.Is(err, models.ErrInvalidReorder):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "Each project_id must be given exactly once"})
+	case errors.Is(err, models.ErrNoRecord):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Project not found or not owned by user"})
+	case errors.Is(err, models.ErrNotSiblings):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "All projects must have the same parent_project_id"})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]string{"message": "Project order updated successfully"})
+}
+
 func (app *application) DeleteProject(c echo.Context) error {
 	projectIDStr := c.QueryParam("project_id")
 	projectID, err := uuid.Parse(projectIDStr)
@@ -98,33 +201,113 @@ func (app *application) DeleteProject(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]any{"message": "Project deleted successfully", "rows_affected": rowsAffected})
 }
 
-// Task Handlers
-func (app *application) AddNewTask(c echo.Context) error {
-	var input models.NewTask
-	if err := c.Bind(&input); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input", "message": err.Error()})
+func (app *application) AddProjectMember(c echo.Context) error {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid project ID"})
+	}
+	var input struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	if err := c.Bind(&input); err != nil || input.UserID == uuid.Nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	member, err := app.projects.AddProjectMember(projectID, uid, input.UserID)
+	if errors.Is(err, models.ErrNoRecord) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Project not found or not owned by user"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
+	return c.JSON(http.StatusCreated, map[string]any{"message": "Project member added successfully", "data": member})
+}
 
-	// Create a new validator
-	v := models.NewValidator()
+func (app *application) GetProjectMembers(c echo.Context) error {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid project ID"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	members, err := app.projects.GetProjectMembers(projectID, uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, members)
+}
+
+func (app *application) RemoveProjectMember(c echo.Context) error {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid project ID"})
+	}
+	memberIDStr := c.QueryParam("user_id")
+	memberID, err := uuid.Parse(memberIDStr)
+	if err != nil || memberIDStr == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid member user ID"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	rowsAffected, err := app.projects.RemoveProjectMember(projectID, uid, memberID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"message": "Project member removed successfully", "rows_affected": rowsAffected})
+}
 
-	// Validate the input
-	if input.Content == "" {
-		v.AddError("content", "Content is required")
+// DuplicateProject handles POST /v1/projects/:id/duplicate
+func (app *application) DuplicateProject(c echo.Context) error {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid project ID"})
+	}
+	var opts models.DuplicateOptions
+	if err := c.Bind(&opts); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
 	}
-	
-	// NEW: Validate that task_id is provided
-	if input.TaskID == uuid.Nil {
-		v.AddError("task_id", "Task ID is required")
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	duplicate, err := app.projects.DuplicateProject(projectID, uid, opts)
+	if errors.Is(err, models.ErrNoRecord) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Project not found or not owned by user"})
 	}
-	
-	// Optionally validate order is non-negative
-	if input.Order != nil && *input.Order < 0 {
-		v.AddError("order", "Order must be non-negative")
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
+	return c.JSON(http.StatusCreated, map[string]any{"message": "Project duplicated successfully", "data": duplicate})
+}
 
-	if !v.Valid() {
-		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+// Task Handlers
+func (app *application) AddNewTask(c echo.Context) error {
+	var input models.NewTask
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input", "message": err.Error()})
 	}
 
 	userID := GetUserID(c)
@@ -137,10 +320,22 @@ func (app *application) AddNewTask(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
 	}
 
+	v := models.NewValidator()
+	if err := app.validateNewTask(v, input, uid); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+
 	created, err := app.tasks.AddTask(input, uid)
+	if errors.Is(err, models.ErrUnknownLabel) {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": map[string]string{"label_ids": "Labels must exist and belong to you"}})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
+	app.metrics.tasksCreated.Inc()
 
 	return c.JSON(http.StatusCreated, map[string]any{"message": "Task added successfully", "data": created})
 }
@@ -160,7 +355,21 @@ func (app *application) EditExistingTask(c echo.Context) error {
 	}
 	task.UserID = uid
 
+	v := models.NewValidator()
+	if err := app.validateTaskProject(v, task.ProjectID, task.AssigneeID, uid); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+
 	updated, err := app.tasks.EditTaskByID(task)
+	if errors.Is(err, models.ErrNoRecord) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found"})
+	}
+	if errors.Is(err, models.ErrUnknownLabel) {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": map[string]string{"label_ids": "Labels must exist and belong to you"}})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
@@ -176,7 +385,77 @@ func (app *application) GetTasksByUserID(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
 	}
-	tasks, err := app.tasks.GetTasksByUserID(uid)
+
+	var filter models.TaskFilter
+	switch assignee := c.QueryParam("assignee"); assignee {
+	case "":
+	case "me":
+		filter.AssigneeID = &uid
+	default:
+		assigneeID, err := uuid.Parse(assignee)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid assignee"})
+		}
+		filter.AssigneeID = &assigneeID
+	}
+
+	if include := c.QueryParam("include_completed"); include != "" {
+		filter.IncludeCompleted, err = strconv.ParseBool(include)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid include_completed"})
+		}
+	}
+
+	tasks, err := app.tasks.GetTasksByUserID(uid, filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, tasks)
+}
+
+// GetCompletedTasks handles GET /v1/tasks/completed?from=2025-01-01&to=2025-01-31&project_id=...&limit=100
+// from and to are inclusive dates; archived tasks are included.
+func (app *application) GetCompletedTasks(c echo.Context) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	filter := models.CompletedTaskFilter{Limit: defaultCompletedTasksLimit}
+	v := models.NewValidator()
+	if from := c.QueryParam("from"); from != "" {
+		day, err := time.Parse(time.DateOnly, from)
+		v.Check(err == nil, "from", "from must be a date like 2025-01-31")
+		filter.From = &day
+	}
+	if to := c.QueryParam("to"); to != "" {
+		day, err := time.Parse(time.DateOnly, to)
+		v.Check(err == nil, "to", "to must be a date like 2025-01-31")
+		end := day.AddDate(0, 0, 1)
+		filter.To = &end
+	}
+	if projectID := c.QueryParam("project_id"); projectID != "" {
+		id, err := uuid.Parse(projectID)
+		v.Check(err == nil, "project_id", "Invalid project ID")
+		filter.ProjectID = &id
+	}
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		v.Check(err == nil && n > 0 && n <= maxCompletedTasksLimit, "limit", fmt.Sprintf("limit must be between 1 and %d", maxCompletedTasksLimit))
+		filter.Limit = n
+	}
+	if v.Valid() && filter.From != nil && filter.To != nil {
+		v.Check(filter.From.Before(*filter.To), "to", "to must not be before from")
+	}
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+
+	tasks, err := app.tasks.GetCompletedTasks(uid, filter)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
@@ -221,18 +500,46 @@ func (app *application) ToggleTaskCompletion(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
 	}
 
-	updatedTask, err := app.tasks.ToggleTaskCompleted(taskID, uid)
+	rules, err := app.completionRules(uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	updatedTask, err := app.tasks.ToggleTaskCompleted(taskID, uid, rules)
+	if errors.Is(err, models.ErrNoRecord) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found"})
+	}
+	if errors.Is(err, models.ErrTaskBlocked) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Task is blocked by open tasks"})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
+	if updatedTask.IsCompleted {
+		app.metrics.tasksCompleted.Inc()
+	}
 
 	return c.JSON(http.StatusOK, map[string]any{"message": "Task updated successfully", "data": updatedTask})
 }
 
-// Label Handlers
-func (app *application) AddNewLabel(c echo.Context) error {
+// CompleteTask handles POST /v1/tasks/:id/complete
+// Completing an already completed task is a no-op, so retries are safe.
+func (app *application) CompleteTask(c echo.Context) error {
+	return app.setTaskCompletion(c, true)
+}
+
+// ReopenTask handles POST /v1/tasks/:id/reopen
+func (app *application) ReopenTask(c echo.Context) error {
+	return app.setTaskCompletion(c, false)
+}
+
+func (app *application) setTaskCompletion(c echo.Context, completed bool) error {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid task ID"})
+	}
 	var input struct {
-		Name string `json:"name"`
+		CompletedAt *time.Time `json:"completed_at"`
 	}
 	if err := c.Bind(&input); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
@@ -245,25 +552,45 @@ func (app *application) AddNewLabel(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
 	}
-	label := models.Label{UserID: uid, Name: input.Name}
+
 	v := models.NewValidator()
-	models.ValidateLabel(&label, v)
+	v.Check(completed || input.CompletedAt == nil, "completed_at", "completed_at can only be set when completing a task")
+	v.Check(input.CompletedAt == nil || !input.CompletedAt.After(time.Now()), "completed_at", "completed_at cannot be in the future")
 	if !v.Valid() {
 		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
 	}
-	created, err := app.labels.AddLabel(uid, input.Name)
+
+	rules, err := app.completionRules(uid)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-	return c.JSON(http.StatusCreated, map[string]any{"message": "Label added successfully", "data": created})
+
+	task, changed, err := app.tasks.SetTaskCompleted(taskID, uid, completed, input.CompletedAt, rules)
+	if errors.Is(err, models.ErrNoRecord) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found"})
+	}
+	if errors.Is(err, models.ErrTaskBlocked) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Task is blocked by open tasks"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	// Retrying on a task that is already completed isn't a new completion.
+	if completed && changed {
+		app.metrics.tasksCompleted.Inc()
+	}
+	return c.JSON(http.StatusOK, map[string]any{"message": "Task updated successfully", "data": task})
 }
 
-func (app *application) EditExistingLabel(c echo.Context) error {
-	var input struct {
-		LabelID string `json:"label_id"`
-		Name    string `json:"name"`
+// MoveTask handles POST /v1/tasks/:id/move
+// It relocates a task and its subtasks to another project and/or parent at the given position.
+func (app *application) MoveTask(c echo.Context) error {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid task ID"})
 	}
-	if err := c.Bind(&input); err != nil {
+	var move models.TaskMove
+	if err := c.Bind(&move); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
 	}
 	userID := GetUserID(c)
@@ -274,24 +601,37 @@ func (app *application) EditExistingLabel(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
 	}
-	labelID, err := uuid.Parse(input.LabelID)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid label ID"})
-	}
-	label := models.Label{LabelID: labelID, UserID: uid, Name: input.Name}
+
 	v := models.NewValidator()
-	models.ValidateLabel(&label, v)
+	validateTaskMove(v, move)
 	if !v.Valid() {
 		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
 	}
-	updated, err := app.labels.EditLabelByID(labelID, uid, input.Name)
+
+	moved, err := app.tasks.MoveTask(taskID, uid, move)
+	if errors.Is(err, models.ErrNoRecord) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task, parent task or project not found"})
+	}
+	if errors.Is(err, models.ErrMoveIntoSubtree) {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "Cannot move a task under itself or one of its subtasks"})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-	return c.JSON(http.StatusOK, map[string]any{"message": "Label updated successfully", "data": updated})
+	return c.JSON(http.StatusOK, map[string]any{"message": "Task moved successfully", "data": moved})
 }
 
-func (app *application) GetLabelsByUserID(c echo.Context) error {
+// PositionTask handles PATCH /v1/tasks/:id/position
+// The task is placed between the given siblings, usually by rewriting only its own order.
+func (app *application) PositionTask(c echo.Context) error {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid task ID"})
+	}
+	var pos models.TaskPosition
+	if err := c.Bind(&pos); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
 	userID := GetUserID(c)
 	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
@@ -300,18 +640,36 @@ func (app *application) GetLabelsByUserID(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
 	}
-	labels, err := app.labels.GetLabelsByUserID(uid)
-	if err != nil {
+
+	v := models.NewValidator()
+	v.Check(pos.BeforeID == nil || *pos.BeforeID != taskID, "before_id", "A task cannot be positioned relative to itself")
+	v.Check(pos.AfterID == nil || *pos.AfterID != taskID, "after_id", "A task cannot be positioned relative to itself")
+	v.Check(pos.BeforeID == nil || pos.AfterID == nil || *pos.BeforeID != *pos.AfterID, "before_id", "before_id and after_id must be different tasks")
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+
+	positioned, err := app.tasks.PositionTask(taskID, uid, pos)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found or not owned by user"})
+	case errors.Is(err, models.ErrNotSiblings):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "before_id and after_id must be siblings of the task"})
+	case errors.Is(err, models.ErrInvalidPosition):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "before_id and after_id must be neighbouring tasks"})
+	case err != nil:
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-	return c.JSON(http.StatusOK, labels)
+	return c.JSON(http.StatusOK, map[string]any{"message": "Task positioned successfully", "data": positioned})
 }
 
-func (app *application) DeleteLabel(c echo.Context) error {
-	labelIDStr := c.QueryParam("label_id")
-	labelID, err := uuid.Parse(labelIDStr)
-	if err != nil || labelIDStr == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid label ID"})
+// DropTask handles POST /v1/tasks/drop
+// It moves a task into another parent or project next to the given siblings and returns the
+// renumbered destination list, plus the source list when the task changed lists.
+func (app *application) DropTask(c echo.Context) error {
+	var drop models.TaskDrop
+	if err := c.Bind(&drop); err != nil || drop.TaskID == uuid.Nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
 	}
 	userID := GetUserID(c)
 	if userID == "" {
@@ -321,16 +679,41 @@ func (app *application) DeleteLabel(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
 	}
-	rowsAffected, err := app.labels.DeleteLabelByID(labelID, uid)
-	if err != nil {
+
+	v := models.NewValidator()
+	v.Check(drop.BeforeID == nil || *drop.BeforeID != drop.TaskID, "before_id", "A task cannot be positioned relative to itself")
+	v.Check(drop.AfterID == nil || *drop.AfterID != drop.TaskID, "after_id", "A task cannot be positioned relative to itself")
+	v.Check(drop.BeforeID == nil || drop.AfterID == nil || *drop.BeforeID != *drop.AfterID, "before_id", "before_id and after_id must be different tasks")
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+
+	result, err := app.tasks.DropTask(uid, drop)
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task, parent task, project or sibling not found"})
+	case errors.Is(err, models.ErrMoveIntoSubtree):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "Cannot move a task under itself or one of its subtasks"})
+	case errors.Is(err, models.ErrNotSiblings):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "before_id and after_id must be in the destination list"})
+	case errors.Is(err, models.ErrInvalidPosition):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "before_id and after_id must be neighbouring tasks"})
+	case err != nil:
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-	return c.JSON(http.StatusOK, map[string]any{"message": "Label deleted successfully", "rows_affected": rowsAffected})
+	return c.JSON(http.StatusOK, map[string]any{"message": "Task moved successfully", "data": result})
 }
 
-// HandleReorderTasks handles PATCH /v1/tasks/reorder
-// It expects a JSON array of {task_id, order} and reorders sibling tasks atomically.
-func (app *application) HandleReorderTasks(c echo.Context) error {
+// DuplicateTask handles POST /v1/tasks/:id/duplicate
+func (app *application) DuplicateTask(c echo.Context) error {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid task ID"})
+	}
+	var opts models.DuplicateOptions
+	if err := c.Bind(&opts); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
 	userID := GetUserID(c)
 	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
@@ -339,7 +722,440 @@ func (app *application) HandleReorderTasks(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
 	}
-
+	duplicate, err := app.tasks.DuplicateTask(taskID, uid, opts)
+	if errors.Is(err, models.ErrNoRecord) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found or not owned by user"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, map[string]any{"message": "Task duplicated successfully", "data": duplicate})
+}
+
+func (app *application) GetTaskHistory(c echo.Context) error {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid task ID"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	events, err := app.tasks.GetTaskHistory(taskID, uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, events)
+}
+
+func (app *application) AddTaskDependency(c echo.Context) error {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid task ID"})
+	}
+	var input struct {
+		BlockedByTaskID uuid.UUID `json:"blocked_by_task_id"`
+	}
+	if err := c.Bind(&input); err != nil || input.BlockedByTaskID == uuid.Nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	dep, err := app.tasks.AddDependency(taskID, input.BlockedByTaskID, uid)
+	if errors.Is(err, models.ErrNoRecord) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found or not owned by user"})
+	}
+	if errors.Is(err, models.ErrDependencyCycle) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Dependency would create a cycle"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, map[string]any{"message": "Dependency added successfully", "data": dep})
+}
+
+func (app *application) RemoveTaskDependency(c echo.Context) error {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid task ID"})
+	}
+	blockedByStr := c.QueryParam("blocked_by_task_id")
+	blockedByID, err := uuid.Parse(blockedByStr)
+	if err != nil || blockedByStr == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid blocked_by_task_id"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	rowsAffected, err := app.tasks.RemoveDependency(taskID, blockedByID, uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"message": "Dependency removed successfully", "rows_affected": rowsAffected})
+}
+
+func (app *application) GetTaskDependencies(c echo.Context) error {
+	taskID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid task ID"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	deps, err := app.tasks.GetTaskDependencies(taskID, uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, deps)
+}
+
+// GetProjectTaskOrder handles GET /v1/projects/:id/tasks/topological-order
+// It returns the project's tasks so that every task appears after the tasks blocking it.
+func (app *application) GetProjectTaskOrder(c echo.Context) error {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid project ID"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	tasks, err := app.tasks.GetProjectTasksInTopologicalOrder(projectID, uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, tasks)
+}
+
+// completionRules combines the server-wide blocker policy with the user's subtask settings.
+func (app *application) completionRules(userID uuid.UUID) (models.CompletionRules, error) {
+	settings, err := app.settings.GetSettings(userID)
+	if err != nil {
+		return models.CompletionRules{}, err
+	}
+	return models.CompletionRules{
+		RefuseIfBlocked:  app.enforceBlockers,
+		CompleteSubtasks: settings.CompleteSubtasksWithParent,
+		CompleteParent:   settings.CompleteParentWithLastSubtask,
+	}, nil
+}
+
+// validateNewTask checks a task about to be created. It is shared by AddNewTask and the batch endpoint.
+func (app *application) validateNewTask(v *models.Validator, input models.NewTask, userID uuid.UUID) error {
+	v.Check(input.Content != "", "content", "Content is required")
+	v.Check(input.TaskID != uuid.Nil, "task_id", "Task ID is required")
+	v.Check(input.Order == nil || *input.Order >= 0, "order", "Order must be non-negative")
+	return app.validateTaskProject(v, input.ProjectID, input.AssigneeID, userID)
+}
+
+// validateTaskMove checks the destination of a move. It is shared by MoveTask and the batch endpoint.
+func validateTaskMove(v *models.Validator, move models.TaskMove) {
+	v.Check(move.Position == nil || *move.Position >= 0, "position", "Position must be non-negative")
+}
+
+// validateTaskProject adds a validation error when userID may not put a task in projectID, or
+// when assigneeID may not own it there. Only project members can add tasks to a project, and
+// assign them to any member; tasks outside a project can only be assigned to their creator.
+func (app *application) validateTaskProject(v *models.Validator, projectID, assigneeID *uuid.UUID, userID uuid.UUID) error {
+	if projectID != nil {
+		ok, err := app.projects.IsProjectMember(*projectID, userID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			v.AddError("project_id", "Project must exist and you must be a member")
+			return nil
+		}
+	}
+	if assigneeID == nil {
+		return nil
+	}
+	if projectID == nil {
+		v.Check(*assigneeID == userID, "assignee_id", "Tasks outside a project can only be assigned to yourself")
+		return nil
+	}
+	ok, err := app.projects.IsProjectMember(*projectID, *assigneeID)
+	if err != nil {
+		return err
+	}
+	v.Check(ok, "assignee_id", "Assignee must be a member of the task's project")
+	return nil
+}
+
+// Label Handlers
+func (app *application) AddNewLabel(c echo.Context) error {
+	var input struct {
+		Name       string  `json:"name"`
+		Color      *string `json:"color"`
+		IsFavorite bool    `json:"is_favorite"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	existing, err := app.labels.GetLabelsByUserID(uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	label := models.Label{UserID: uid, Name: strings.TrimSpace(input.Name), Color: input.Color, IsFavorite: input.IsFavorite}
+	v := models.NewValidator()
+	models.ValidateLabel(&label, existing, v)
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+	created, err := app.labels.AddLabel(label)
+	if errors.Is(err, models.ErrDuplicateLabel) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "A label with this name already exists"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, map[string]any{"message": "Label added successfully", "data": created})
+}
+
+func (app *application) EditExistingLabel(c echo.Context) error {
+	var input struct {
+		LabelID    string  `json:"label_id"`
+		Name       string  `json:"name"`
+		Color      *string `json:"color"`
+		IsFavorite bool    `json:"is_favorite"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	labelID, err := uuid.Parse(input.LabelID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid label ID"})
+	}
+	existing, err := app.labels.GetLabelsByUserID(uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	label := models.Label{LabelID: labelID, UserID: uid, Name: strings.TrimSpace(input.Name), Color: input.Color, IsFavorite: input.IsFavorite}
+	v := models.NewValidator()
+	models.ValidateLabel(&label, existing, v)
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+	updated, err := app.labels.EditLabelByID(label)
+	if errors.Is(err, models.ErrNoRecord) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Label not found"})
+	}
+	if errors.Is(err, models.ErrDuplicateLabel) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "A label with this name already exists"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"message": "Label updated successfully", "data": updated})
+}
+
+// ReorderLabels puts the listed labels first, in the given order.
+func (app *application) ReorderLabels(c echo.Context) error {
+	var input struct {
+		LabelIDs []uuid.UUID `json:"label_ids"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	v := models.NewValidator()
+	v.Check(len(input.LabelIDs) > 0, "label_ids", "At least one label is required")
+	seen := map[uuid.UUID]bool{}
+	for _, id := range input.LabelIDs {
+		v.Check(!seen[id], "label_ids", "Labels must not be repeated")
+		seen[id] = true
+	}
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+
+	labels, err := app.labels.ReorderLabels(uid, input.LabelIDs)
+	if errors.Is(err, models.ErrUnknownLabel) {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": map[string]string{"label_ids": "Labels must exist and belong to you"}})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, labels)
+}
+
+// MergeLabels folds the source labels into the target label.
+func (app *application) MergeLabels(c echo.Context) error {
+	var input struct {
+		TargetLabelID  uuid.UUID   `json:"target_label_id"`
+		SourceLabelIDs []uuid.UUID `json:"source_label_ids"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	v := models.NewValidator()
+	v.Check(input.TargetLabelID != uuid.Nil, "target_label_id", "Target label is required")
+	v.Check(len(input.SourceLabelIDs) > 0, "source_label_ids", "At least one source label is required")
+	seen := map[uuid.UUID]bool{input.TargetLabelID: true}
+	for _, id := range input.SourceLabelIDs {
+		v.Check(!seen[id], "source_label_ids", "Source labels must be distinct and differ from the target")
+		seen[id] = true
+	}
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+
+	result, err := app.labels.MergeLabels(uid, input.TargetLabelID, input.SourceLabelIDs)
+	if errors.Is(err, models.ErrUnknownLabel) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Label not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"message": "Labels merged successfully", "data": result})
+}
+
+// BulkLabelTasks adds and removes labels on every task matching an ID list or filter.
+func (app *application) BulkLabelTasks(c echo.Context) error {
+	var input models.BulkLabel
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	v := models.NewValidator()
+	v.Check(input.HasSelector(), "task_ids", "Provide task IDs or at least one filter")
+	v.Check(len(input.AddLabelIDs)+len(input.RemoveLabelIDs) > 0, "add_label_ids", "Provide labels to add or remove")
+	adding := map[uuid.UUID]bool{}
+	for _, id := range input.AddLabelIDs {
+		adding[id] = true
+	}
+	for _, id := range input.RemoveLabelIDs {
+		v.Check(!adding[id], "remove_label_ids", "A label cannot be both added and removed")
+	}
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+
+	result, err := app.tasks.BulkLabelTasks(uid, input)
+	if errors.Is(err, models.ErrUnknownLabel) {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": map[string]string{"label_ids": "Labels must exist and belong to you"}})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (app *application) GetLabelsByUserID(c echo.Context) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	labels, err := app.labels.GetLabelsByUserID(uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, labels)
+}
+
+func (app *application) DeleteLabel(c echo.Context) error {
+	labelIDStr := c.QueryParam("label_id")
+	labelID, err := uuid.Parse(labelIDStr)
+	if err != nil || labelIDStr == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid label ID"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	rowsAffected, err := app.labels.DeleteLabelByID(labelID, uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"message": "Label deleted successfully", "rows_affected": rowsAffected})
+}
+
+// HandleReorderTasks handles PATCH /v1/tasks/reorder
+// It expects a JSON array of {task_id, order} covering one complete sibling list and reorders it
+// atomically. Rejected, missing and duplicate entries are reported back with a 422.
+func (app *application) HandleReorderTasks(c echo.Context) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
 	var updates []models.TaskOrderUpdate
 	if err := c.Bind(&updates); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
@@ -347,39 +1163,308 @@ func (app *application) HandleReorderTasks(c echo.Context) error {
 	if len(updates) == 0 {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "No tasks to reorder"})
 	}
+	for _, upd := range updates {
+		if !models.ValidOrder(upd.Order) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Each order must be a 32-bit integer"})
+		}
+	}
 
-	// Fetch the first task to get project_id and parent_task_id
-	firstTaskID, err := uuid.Parse(updates[0].TaskID)
+	rejection, err := app.tasks.BulkUpdateTaskOrder(uid, updates)
+	if errors.Is(err, models.ErrInvalidReorder) {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{
+			"error":   "Reorder must list every sibling task exactly once with distinct orders",
+			"details": rejection,
+		})
+	}
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid task_id in input"})
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]string{"message": "Task order updated successfully"})
+}
+
+// Template Handlers
+func (app *application) SaveTemplate(c echo.Context) error {
+	var input struct {
+		ProjectID   uuid.UUID `json:"project_id"`
+		Name        string    `json:"name"`
+		Description *string   `json:"description"`
+		AnchorDate  string    `json:"anchor_date"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
 	}
-	task, err := app.tasks.GetTaskByID(firstTaskID, uid)
+	uid, err := uuid.Parse(userID)
 	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found or not owned by user"})
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
 	}
-	projectID := task.ProjectID
-	parentTaskID := task.ParentTaskID
 
-	// Validate all tasks are siblings (same project_id and parent_task_id)
-	for _, upd := range updates {
-		id, err := uuid.Parse(upd.TaskID)
+	v := models.NewValidator()
+	v.Check(input.ProjectID != uuid.Nil, "project_id", "Project ID is required")
+	v.Check(input.Name != "", "name", "Template name is required")
+	var anchor *time.Time
+	if input.AnchorDate != "" {
+		date, err := time.Parse(time.DateOnly, input.AnchorDate)
+		v.Check(err == nil, "anchor_date", "Anchor date must be in YYYY-MM-DD format")
+		anchor = &date
+	}
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+
+	template, err := app.templates.SaveProjectAsTemplate(input.ProjectID, uid, input.Name, input.Description, anchor)
+	if errors.Is(err, models.ErrNoRecord) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Project not found or not owned by user"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, map[string]any{"message": "Template saved successfully", "data": template})
+}
+
+func (app *application) GetTemplatesByUserID(c echo.Context) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	templates, err := app.templates.GetTemplatesByUserID(uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, templates)
+}
+
+func (app *application) DeleteTemplate(c echo.Context) error {
+	templateIDStr := c.QueryParam("template_id")
+	templateID, err := uuid.Parse(templateIDStr)
+	if err != nil || templateIDStr == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid template ID"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	rowsAffected, err := app.templates.DeleteTemplateByID(templateID, uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"message": "Template deleted successfully", "rows_affected": rowsAffected})
+}
+
+// InstantiateTemplate handles POST /v1/templates/:id/instantiate
+// Due dates in the new project are the anchor date (default: today, UTC) plus each task's offset.
+func (app *application) InstantiateTemplate(c echo.Context) error {
+	templateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid template ID"})
+	}
+	var input struct {
+		AnchorDate  string  `json:"anchor_date"`
+		ProjectName *string `json:"project_name"`
+	}
+	if err := c.Bind(&input); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	anchor := time.Now().UTC().Truncate(24 * time.Hour)
+	if input.AnchorDate != "" {
+		anchor, err = time.Parse(time.DateOnly, input.AnchorDate)
 		if err != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid task_id in input"})
+			return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": map[string]string{"anchor_date": "Anchor date must be in YYYY-MM-DD format"}})
 		}
-		t, err := app.tasks.GetTaskByID(id, uid)
+	}
+
+	project, err := app.templates.InstantiateTemplate(templateID, uid, anchor, input.ProjectName)
+	if errors.Is(err, models.ErrNoRecord) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Template not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, map[string]any{"message": "Project created from template successfully", "data": project})
+}
+
+// ExportTemplate handles GET /v1/templates/:id/export
+// It downloads the template as a JSON file that can be shared and imported elsewhere.
+func (app *application) ExportTemplate(c echo.Context) error {
+	templateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid template ID"})
+	}
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	template, err := app.templates.GetTemplateByID(templateID, uid)
+	if errors.Is(err, models.ErrNoRecord) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Template not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	file := models.TemplateFile{
+		Version:     models.TemplateVersion,
+		Name:        template.Name,
+		Description: template.Description,
+		Project:     template.Project,
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", "template-"+template.TemplateID.String()+".json"))
+	return c.JSONPretty(http.StatusOK, file, "  ")
+}
+
+// ImportTemplate handles POST /v1/templates/import
+// It accepts an exported template either as a multipart upload in the "file" field or as the raw JSON body.
+func (app *application) ImportTemplate(c echo.Context) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	body := c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		header, err := c.FormFile("file")
 		if err != nil {
-			return c.JSON(http.StatusNotFound, map[string]string{"error": "Task not found or not owned by user"})
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Missing template file"})
 		}
-		if (t.ProjectID == nil && projectID != nil) || (t.ProjectID != nil && projectID == nil) || (t.ProjectID != nil && projectID != nil && *t.ProjectID != *projectID) {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "All tasks must have the same project_id"})
-		}
-		if (t.ParentTaskID == nil && parentTaskID != nil) || (t.ParentTaskID != nil && parentTaskID == nil) || (t.ParentTaskID != nil && parentTaskID != nil && *t.ParentTaskID != *parentTaskID) {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "All tasks must have the same parent_task_id"})
+		f, err := header.Open()
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unable to read template file"})
 		}
+		defer f.Close()
+		body = f
 	}
 
-	if err := app.tasks.BulkUpdateTaskOrder(uid, projectID, parentTaskID, updates); err != nil {
+	var file models.TemplateFile
+	if err := json.NewDecoder(io.LimitReader(body, maxTemplateFileSize)).Decode(&file); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid template file", "message": err.Error()})
+	}
+
+	v := models.NewValidator()
+	models.ValidateTemplateFile(&file, v)
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+
+	template, err := app.templates.ImportTemplate(uid, file)
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-	return c.JSON(http.StatusOK, map[string]string{"message": "Task order updated successfully"})
+	return c.JSON(http.StatusCreated, map[string]any{"message": "Template imported successfully", "data": template})
+}
+
+// Settings Handlers
+func (app *application) GetSettings(c echo.Context) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	settings, err := app.settings.GetSettings(uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings handles PUT /v1/settings
+// Fields missing from the body keep their current values.
+func (app *application) UpdateSettings(c echo.Context) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+	settings, err := app.settings.GetSettings(uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	if err := c.Bind(&settings); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+	settings.UserID = uid
+
+	v := models.NewValidator()
+	models.ValidateSettings(&settings, v)
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+
+	updated, err := app.settings.UpdateSettings(settings)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"message": "Settings updated successfully", "data": updated})
+}
+
+// Stats Handlers
+
+// GetStats handles GET /v1/stats?days=30&weeks=12
+// Days and weeks are bucketed in the timezone from the user's settings.
+func (app *application) GetStats(c echo.Context) error {
+	userID := GetUserID(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID"})
+	}
+
+	r := models.DefaultStatsRange
+	v := models.NewValidator()
+	if days := c.QueryParam("days"); days != "" {
+		r.Days, err = strconv.Atoi(days)
+		v.Check(err == nil && r.Days >= 1 && r.Days <= models.MaxStatsRange.Days, "days", fmt.Sprintf("days must be between 1 and %d", models.MaxStatsRange.Days))
+	}
+	if weeks := c.QueryParam("weeks"); weeks != "" {
+		r.Weeks, err = strconv.Atoi(weeks)
+		v.Check(err == nil && r.Weeks >= 1 && r.Weeks <= models.MaxStatsRange.Weeks, "weeks", fmt.Sprintf("weeks must be between 1 and %d", models.MaxStatsRange.Weeks))
+	}
+	if !v.Valid() {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]any{"errors": v.Errors})
+	}
+
+	settings, err := app.settings.GetSettings(uid)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	stats, err := app.tasks.GetStats(uid, settings, r)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, stats)
 }
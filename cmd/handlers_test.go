@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dmcleish91/go_todo_api/internal/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// newTestApp returns an application backed by a fresh in-memory store.
+func newTestApp() *application {
+	store := models.NewMemoryStore()
+	return &application{
+		projects: store.Projects(),
+		tasks:    store.Tasks(),
+		labels:   store.Labels(),
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics:  newMetrics(nil),
+	}
+}
+
+// call runs handler for a request with body sent by userID and returns the recorded response.
+func call(t *testing.T, handler echo.HandlerFunc, method string, body any, userID uuid.UUID) *httptest.ResponseRecorder {
+	t.Helper()
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, "/", strings.NewReader(string(encoded)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.Set("user_id", userID.String())
+	if err := handler(c); err != nil {
+		t.Fatalf("handler returned %v", err)
+	}
+	return rec
+}
+
+func TestTasksInForeignProject(t *testing.T) {
+	app := newTestApp()
+	owner, stranger := uuid.New(), uuid.New()
+	project, err := app.projects.AddProject(models.Project{UserID: owner, ProjectName: "private"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := call(t, app.AddNewTask, http.MethodPost, models.NewTask{TaskID: uuid.New(), Content: "sneak in", ProjectID: &project.ProjectID}, stranger)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "project_id") {
+		t.Fatalf("create in a foreign project: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = call(t, app.AddNewTask, http.MethodPost, models.NewTask{TaskID: uuid.New(), Content: "sneak in", ProjectID: &project.ProjectID, AssigneeID: &owner}, stranger)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "project_id") {
+		t.Fatalf("assign in a foreign project: %d %s", rec.Code, rec.Body)
+	}
+
+	task, err := app.tasks.AddTask(models.NewTask{TaskID: uuid.New(), Content: "mine"}, stranger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task.ProjectID = &project.ProjectID
+	rec = call(t, app.EditExistingTask, http.MethodPut, task, stranger)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "project_id") {
+		t.Fatalf("edit into a foreign project: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = call(t, app.AddNewTask, http.MethodPost, models.NewTask{TaskID: uuid.New(), Content: "allowed", ProjectID: &project.ProjectID}, owner)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create in own project: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestReorderProjectsRejectsDuplicates(t *testing.T) {
+	app := newTestApp()
+	user := uuid.New()
+	a, err := app.projects.AddProject(models.Project{UserID: user, ProjectName: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := app.projects.AddProject(models.Project{UserID: user, ProjectName: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates := []models.ProjectOrderUpdate{{ProjectID: a.ProjectID, Order: 1}, {ProjectID: b.ProjectID, Order: 2}, {ProjectID: a.ProjectID, Order: 3}}
+	rec := call(t, app.HandleReorderProjects, http.MethodPatch, updates, user)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("duplicate project_id: %d %s", rec.Code, rec.Body)
+	}
+}
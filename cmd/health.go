@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// version and commit identify the build. Release builds set them with
+//
+//	go build -ldflags "-X main.version=1.4.0 -X main.commit=$(git rev-parse HEAD)" ./cmd/
+//
+// Without -ldflags, commit falls back to the revision the Go toolchain stamps into the binary.
+var (
+	version = "dev"
+	commit  = ""
+)
+
+// readyTimeout bounds the checks behind /readyz so that a stuck database fails the probe.
+const readyTimeout = 2 * time.Second
+
+// buildCommit returns commit, or the VCS revision recorded in the binary, or "unknown".
+func buildCommit() string {
+	if commit != "" {
+		return commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				return s.Value
+			}
+		}
+	}
+	return "unknown"
+}
+
+// Healthz reports that the process is up. It doesn't touch the database.
+func (app *application) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz reports whether the server can handle requests: the database answers a ping and has no
+// pending migrations.
+func (app *application) Readyz(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), readyTimeout)
+	defer cancel()
+
+	if err := app.ping(ctx); err != nil {
+		app.logger.Warn("readiness check failed", "check", "database", "error", err)
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": "database unreachable"})
+	}
+
+	pending, err := app.migrator.Pending(ctx)
+	if err != nil {
+		app.logger.Warn("readiness check failed", "check", "migrations", "error", err)
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": "unable to check migrations"})
+	}
+	if pending > 0 {
+		return c.JSON(http.StatusServiceUnavailable, map[string]any{"status": "unavailable", "error": "migrations pending", "pending_migrations": pending})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// Version reports the build and how long the process has been running.
+func (app *application) Version(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{
+		"version":    version,
+		"commit":     buildCommit(),
+		"go_version": runtime.Version(),
+		"uptime":     time.Since(app.startedAt).Round(time.Second).String(),
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/dmcleish91/go_todo_api/internal/models"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerIdempotencyKey      = "Idempotency-Key"
+	headerIdempotentReplayed  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+
+	// idempotencyLease is how long a key is held for the request that claimed it before a
+	// retry may take it over. It keeps a key usable when its request never finishes, for
+	// example because the process was killed.
+	idempotencyLease = time.Minute
+)
+
+// replayedHeaders are the response headers stored alongside Content-Type and sent again on a
+// replay. Other headers are dropped.
+var replayedHeaders = []string{echo.HeaderLocation, echo.HeaderContentDisposition}
+
+// Idempotency makes mutating requests that carry an Idempotency-Key header safe to retry.
+// The first request with a key runs normally and its response is stored for app.idempotencyTTL;
+// retries with the same body replay that response, and reusing the key with a different body
+// is rejected with 422. Server errors are not stored, so the client can retry them. While the
+// first request runs, the key is only held for idempotencyLease.
+// It must run after the JWT middleware, because keys are scoped per user.
+func (app *application) Idempotency() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			key := req.Header.Get(headerIdempotencyKey)
+			if key == "" || req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodOptions {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Idempotency-Key is too long"})
+			}
+
+			uid, err := uuid.Parse(GetUserID(c))
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+			}
+
+			body, err := io.ReadAll(io.LimitReader(req.Body, maxIdempotentRequestBytes+1))
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+			}
+			if len(body) > maxIdempotentRequestBytes {
+				return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "Request body is too large for an idempotent request"})
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := idempotencyHash(req, body)
+			claimed, stored, err := app.idempotency.Reserve(uid, key, hash, idempotencyLease)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			}
+			if !claimed {
+				if stored.RequestHash != hash {
+					return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "Idempotency-Key was already used with a different request"})
+				}
+				if stored.StatusCode == nil {
+					return c.JSON(http.StatusConflict, map[string]string{"error": "A request with this Idempotency-Key is still being processed"})
+				}
+				header := c.Response().Header()
+				for name, value := range stored.Headers {
+					header.Set(name, value)
+				}
+				header.Set(headerIdempotentReplayed, "true")
+				return c.Blob(*stored.StatusCode, stored.ContentType, stored.Body)
+			}
+
+			res := c.Response()
+			recorder := &responseRecorder{ResponseWriter: res.Writer}
+			res.Writer = recorder
+
+			err = next(c)
+
+			if err != nil || res.Status >= http.StatusInternalServerError {
+				app.releaseIdempotencyKey(uid, key)
+				return err
+			}
+
+			status := res.Status
+			response := models.IdempotentResponse{
+				StatusCode:  &status,
+				ContentType: res.Header().Get(echo.HeaderContentType),
+				Body:        recorder.body.Bytes(),
+			}
+			for _, name := range replayedHeaders {
+				if value := res.Header().Get(name); value != "" {
+					if response.Headers == nil {
+						response.Headers = map[string]string{}
+					}
+					response.Headers[name] = value
+				}
+			}
+			if saveErr := app.idempotency.Save(uid, key, response, app.idempotencyTTL); saveErr != nil {
+				// Without a stored response, retries would get 409 until the lease ran out.
+				app.logger.Error("unable to save idempotent response", "error", saveErr)
+				app.releaseIdempotencyKey(uid, key)
+			}
+			return nil
+		}
+	}
+}
+
+// releaseIdempotencyKey releases a claimed key so that the client can retry the request.
+func (app *application) releaseIdempotencyKey(uid uuid.UUID, key string) {
+	if err := app.idempotency.Release(uid, key); err != nil {
+		app.logger.Error("unable to release idempotency key", "error", err)
+	}
+}
+
+// idempotencyHash fingerprints a request by method, path, query string and body.
+func idempotencyHash(req *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies everything written to the response so it can be stored.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
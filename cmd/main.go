@@ -1,45 +1,116 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"flag"
 	"log/slog"
 	"os"
+	"time"
+	_ "time/tzdata" // timezone validation must not depend on the host's zoneinfo
 
 	"github.com/dmcleish91/go_todo_api/internal/models"
 	"github.com/joho/godotenv"
 )
 
 type application struct {
-	projects *models.ProjectModel
-	tasks    *models.TaskModel
-	labels   *models.LabelModel
-	logger   *slog.Logger
+	projects    models.ProjectStore
+	tasks       models.TaskStore
+	labels      models.LabelStore
+	settings    models.SettingsStore
+	templates   models.TemplateStore
+	idempotency models.IdempotencyStore
+	logger      *slog.Logger
+
+	// migrator and ping back the /readyz checks.
+	migrator schemaMigrator
+	ping     func(context.Context) error
+	// startedAt is when the process started, for the uptime reported by /version.
+	startedAt time.Time
+
+	metrics *metrics
+	// metricsAddr is the separate listener for /metrics; when empty, /metrics is served on the
+	// main listener, but only if metricsToken is set.
+	metricsAddr string
+	// metricsToken is the bearer token /metrics requires, if any.
+	metricsToken string
+
+	// jwtSigningKey verifies the Supabase access tokens sent with /v1 requests.
+	jwtSigningKey []byte
+	// corsOrigins are the origins allowed to make cross-origin requests.
+	corsOrigins []string
+	// enforceBlockers refuses to complete tasks that still have open blockers.
+	enforceBlockers bool
+	// idempotencyTTL is how long a stored response can be replayed for its Idempotency-Key.
+	idempotencyTTL time.Duration
+	// archiveAfter is how long after completion a task is archived; zero disables archiving.
+	archiveAfter time.Duration
 }
 
 func main() {
+	startedAt := time.Now()
 	godotenv.Load()
-	user := os.Getenv("user")
-	password := os.Getenv("password")
-	host := os.Getenv("host")
-	port := os.Getenv("port")
-	dbname := os.Getenv("dbname")
 
 	logger := NewStructuredLogger()
 
-	DATABASE_URL := fmt.Sprintf("postgresql://%s:%s@%s:%s/%s", user, password, host, port, dbname)
+	cfg, err := LoadConfig(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		logger.Error("unable to load configuration", "error", err)
+		os.Exit(2)
+	}
 
-	conn := CreateDatabaseConnection(DATABASE_URL)
-	defer conn.Close()
+	store, err := openStorage(cfg)
+	if err != nil {
+		logger.Error("unable to open storage", "error", err)
+		os.Exit(1)
+	}
+
+	if len(cfg.Args) > 0 && cfg.Args[0] == "migrate" {
+		code := runMigrateCommand(context.Background(), store.migrator, logger, cfg.Args[1:])
+		store.close()
+		os.Exit(code)
+	}
+	if cfg.MigrateOnStart {
+		if err := migrateOnStart(context.Background(), store.migrator, logger); err != nil {
+			logger.Error("unable to apply migrations", "error", err)
+			store.close()
+			os.Exit(1)
+		}
+	}
 
 	app := &application{
-		projects: &models.ProjectModel{DB: conn},
-		tasks:    &models.TaskModel{DB: conn},
-		labels:   &models.LabelModel{DB: conn},
-		logger:   logger,
+		projects:    store.projects,
+		tasks:       store.tasks,
+		labels:      store.labels,
+		settings:    store.settings,
+		templates:   store.templates,
+		idempotency: store.idempotency,
+		logger:      logger,
+		migrator:    store.migrator,
+		ping:        store.ping,
+		startedAt:   startedAt,
+
+		metrics:      newMetrics(store.collector),
+		metricsAddr:  cfg.MetricsAddr,
+		metricsToken: cfg.MetricsToken,
+
+		jwtSigningKey:   cfg.JWTSigningKey,
+		corsOrigins:     cfg.CORSOrigins,
+		enforceBlockers: cfg.EnforceBlockers,
+		idempotencyTTL:  cfg.IdempotencyTTL,
+		archiveAfter:    cfg.ArchiveAfter,
 	}
 
-	e := app.Routes()
+	err = app.serve(cfg.ListenAddr, cfg.ShutdownTimeout)
+
+	logger.Info("closing storage")
+	store.close()
+	logger.Info("shutdown complete")
 
-	logger.Info("starting server on :1323")
-	e.Logger.Fatal(e.Start(":1323"))
+	if err != nil {
+		os.Exit(1)
+	}
 }
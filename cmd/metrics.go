@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics holds the Prometheus collectors the server updates and the registry /metrics serves.
+type metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	tasksCreated    prometheus.Counter
+	tasksCompleted  prometheus.Counter
+}
+
+// newMetrics registers the HTTP, task, Go runtime and process metrics, plus storage's own
+// collector when it has one.
+func newMetrics(storage prometheus.Collector) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "todoapi_http_requests_total",
+			Help: "HTTP requests handled, by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "todoapi_http_request_duration_seconds",
+			Help:    "Time taken to handle HTTP requests, by method and route template.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		tasksCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "todoapi_tasks_created_total",
+			Help: "Tasks created through POST /v1/tasks and batch create operations.",
+		}),
+		tasksCompleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "todoapi_tasks_completed_total",
+			Help: "Open tasks completed through the completion endpoints and batch complete operations.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.tasksCreated,
+		m.tasksCompleted,
+	)
+	if storage != nil {
+		m.registry.MustRegister(storage)
+	}
+	return m
+}
+
+// Middleware records every request under its route template, such as /v1/tasks/:id/move, so
+// that IDs in the URI don't create a series per task. It must run outside StructuredLogger,
+// which turns handler errors into responses, so that the recorded status is the one sent.
+func (m *metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			route := c.Path()
+			if route == "" || route == "/*" {
+				route = "unmatched"
+			}
+			method := c.Request().Method
+			m.requests.WithLabelValues(method, route, strconv.Itoa(c.Response().Status)).Inc()
+			m.requestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+
+			return err
+		}
+	}
+}
+
+// Handler serves the registry in the Prometheus text format. When token is set, requests must
+// send it as a bearer token.
+func (m *metrics) Handler(token string) echo.HandlerFunc {
+	h := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	return func(c echo.Context) error {
+		if token != "" {
+			got := c.Request().Header.Get(echo.HeaderAuthorization)
+			if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+token)) != 1 {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+			}
+		}
+		h.ServeHTTP(c.Response(), c.Request())
+		return nil
+	}
+}
+
+// pgxPoolCollector exports a pgxpool.Pool's statistics. They are read from the pool on each
+// scrape.
+type pgxPoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns    *prometheus.Desc
+	idleConns        *prometheus.Desc
+	totalConns       *prometheus.Desc
+	maxConns         *prometheus.Desc
+	acquires         *prometheus.Desc
+	acquireDuration  *prometheus.Desc
+	emptyAcquires    *prometheus.Desc
+	canceledAcquires *prometheus.Desc
+}
+
+func newPgxPoolCollector(pool *pgxpool.Pool) *pgxPoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("todoapi_db_pool_"+name, help, nil, nil)
+	}
+	return &pgxPoolCollector{
+		pool:             pool,
+		acquiredConns:    desc("acquired_conns", "Connections currently checked out of the pool."),
+		idleConns:        desc("idle_conns", "Idle connections in the pool."),
+		totalConns:       desc("total_conns", "Connections in the pool, including ones being opened."),
+		maxConns:         desc("max_conns", "Maximum size of the pool."),
+		acquires:         desc("acquires_total", "Connections acquired from the pool."),
+		acquireDuration:  desc("acquire_duration_seconds_total", "Time spent acquiring connections, including waiting for one to free up."),
+		emptyAcquires:    desc("empty_acquires_total", "Acquires that had to wait because the pool had no idle connection."),
+		canceledAcquires: desc("canceled_acquires_total", "Acquires cancelled by their context while waiting."),
+	}
+}
+
+func (p *pgxPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.acquiredConns
+	ch <- p.idleConns
+	ch <- p.totalConns
+	ch <- p.maxConns
+	ch <- p.acquires
+	ch <- p.acquireDuration
+	ch <- p.emptyAcquires
+	ch <- p.canceledAcquires
+}
+
+func (p *pgxPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := p.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(p.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(p.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(p.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(p.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(p.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(p.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(p.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(p.canceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+}
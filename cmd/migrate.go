@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/dmcleish91/go_todo_api/internal/migrations"
+)
+
+// schemaMigrator is implemented by migrations.Migrator for Postgres and migrations.SQLiteMigrator.
+type schemaMigrator interface {
+	Up(ctx context.Context) ([]migrations.Migration, error)
+	Down(ctx context.Context, steps int) ([]migrations.Migration, error)
+	Status(ctx context.Context) ([]migrations.Status, error)
+	Pending(ctx context.Context) (int, error)
+}
+
+const migrateUsage = "usage: migrate up | down [steps] | status"
+
+// runMigrateCommand implements the `migrate up|down [steps]|status` subcommand and returns the
+// process exit code. down reverts one migration unless a step count is given.
+func runMigrateCommand(ctx context.Context, migrator schemaMigrator, logger *slog.Logger, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			logger.Info("applied migration", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			logger.Error("migrate up failed", "error", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			logger.Info("database is up to date")
+		}
+		return 0
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+			steps = n
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			logger.Info("reverted migration", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			logger.Error("migrate down failed", "error", err)
+			return 1
+		}
+		return 0
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			logger.Error("migrate status failed", "error", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		w.Flush()
+		return 0
+	}
+
+	fmt.Fprintln(os.Stderr, migrateUsage)
+	return 2
+}
+
+// migrateOnStart applies pending migrations before the server starts serving.
+func migrateOnStart(ctx context.Context, migrator schemaMigrator, logger *slog.Logger) error {
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		logger.Info("applied migration", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
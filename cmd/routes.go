@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -23,38 +22,100 @@ func (app *application) Routes() *echo.Echo {
 
 	e.Use(ServerHeader)
 
+	e.Use(app.metrics.Middleware())
+
 	e.Use(StructuredLogger(app.logger))
 
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins:     []string{"http://localhost:5173", "https://yata-delta.vercel.app"},
+		AllowOrigins:     app.corsOrigins,
 		AllowMethods:     []string{echo.GET, echo.PUT, echo.POST, echo.DELETE, echo.PATCH},
-		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
+		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, headerIdempotencyKey},
+		ExposeHeaders:    []string{headerIdempotentReplayed},
 		AllowCredentials: true,
 	}))
 
+	// Probe and build info endpoints, outside /v1 and unauthenticated
+	e.GET("/healthz", app.Healthz)
+	e.GET("/readyz", app.Readyz)
+	e.GET("/version", app.Version)
+
+	// Without a separate metrics listener, metrics are served here behind their token.
+	if app.metricsAddr == "" && app.metricsToken != "" {
+		e.GET("/metrics", app.metrics.Handler(app.metricsToken))
+	}
+
 	secured := e.Group("/v1")
 
 	secured.Use(app.SupabaseJWTMiddleware())
+	secured.Use(app.Idempotency())
 
 	// Project endpoints
 	secured.POST("/projects", app.AddNewProject)
 	secured.PUT("/projects", app.EditExistingProject)
 	secured.GET("/projects", app.GetProjectsByUserID)
 	secured.DELETE("/projects", app.DeleteProject)
+	secured.PATCH("/projects/reorder", app.HandleReorderProjects)
+	secured.POST("/projects/:id/archive", app.ArchiveProject)
+	secured.POST("/projects/:id/unarchive", app.UnarchiveProject)
+	secured.POST("/projects/:id/duplicate", app.DuplicateProject)
+	secured.POST("/projects/:id/members", app.AddProjectMember)
+	secured.GET("/projects/:id/members", app.GetProjectMembers)
+	secured.DELETE("/projects/:id/members", app.RemoveProjectMember)
+	secured.GET("/projects/:id/tasks/topological-order", app.GetProjectTaskOrder)
 
 	// Task endpoints
 	secured.POST("/tasks", app.AddNewTask)
 	secured.PUT("/tasks", app.EditExistingTask)
 	secured.GET("/tasks", app.GetTasksByUserID)
+	secured.GET("/tasks/completed", app.GetCompletedTasks)
 	secured.DELETE("/tasks", app.DeleteTask)
 	secured.PUT("/tasks/:id/toggle-completion", app.ToggleTaskCompletion)
+	secured.POST("/tasks/:id/complete", app.CompleteTask)
+	secured.POST("/tasks/:id/reopen", app.ReopenTask)
+	secured.POST("/tasks/:id/move", app.MoveTask)
+	secured.PATCH("/tasks/:id/position", app.PositionTask)
+	secured.POST("/tasks/:id/duplicate", app.DuplicateTask)
+	secured.GET("/tasks/:id/history", app.GetTaskHistory)
+	secured.POST("/tasks/:id/dependencies", app.AddTaskDependency)
+	secured.GET("/tasks/:id/dependencies", app.GetTaskDependencies)
+	secured.DELETE("/tasks/:id/dependencies", app.RemoveTaskDependency)
 	secured.PATCH("/tasks/reorder", app.HandleReorderTasks)
+	secured.POST("/tasks/drop", app.DropTask)
+	secured.POST("/tasks/bulk-label", app.BulkLabelTasks)
+	secured.POST("/tasks/batch", app.BatchTasks)
 
 	// Label endpoints
 	secured.POST("/labels", app.AddNewLabel)
 	secured.PUT("/labels", app.EditExistingLabel)
 	secured.GET("/labels", app.GetLabelsByUserID)
 	secured.DELETE("/labels", app.DeleteLabel)
+	secured.PATCH("/labels/reorder", app.ReorderLabels)
+	secured.POST("/labels/merge", app.MergeLabels)
+
+	// Template endpoints
+	secured.POST("/templates", app.SaveTemplate)
+	secured.GET("/templates", app.GetTemplatesByUserID)
+	secured.DELETE("/templates", app.DeleteTemplate)
+	secured.POST("/templates/import", app.ImportTemplate)
+	secured.GET("/templates/:id/export", app.ExportTemplate)
+	secured.POST("/templates/:id/instantiate", app.InstantiateTemplate)
+
+	// Settings endpoints
+	secured.GET("/settings", app.GetSettings)
+	secured.PUT("/settings", app.UpdateSettings)
+
+	// Stats endpoints
+	secured.GET("/stats", app.GetStats)
+
+	return e
+}
+
+// MetricsRoutes is the server for app.metricsAddr. It serves only /metrics, which still checks
+// app.metricsToken when one is set.
+func (app *application) MetricsRoutes() *echo.Echo {
+	e := echo.New()
+
+	e.GET("/metrics", app.metrics.Handler(app.metricsToken))
 
 	return e
 }
@@ -72,11 +133,6 @@ func (app *application) SupabaseJWTMiddleware() echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusUnauthorized, "invalid authorization header format")
 			}
 
-			signingKey := os.Getenv("SUPABASE_JWT_SIGNINGKEY")
-			if signingKey == "" {
-				return echo.NewHTTPError(http.StatusInternalServerError, "SUPABASE_JWT_SIGNINGKEY not set")
-			}
-
 			// Parse and validate the token
 			claims := &SupabaseJWTClaims{}
 			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
@@ -84,7 +140,7 @@ func (app *application) SupabaseJWTMiddleware() echo.MiddlewareFunc {
 				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 				}
-				return []byte(signingKey), nil
+				return app.jwtSigningKey, nil
 			})
 
 			if err != nil {
@@ -107,7 +163,7 @@ func (app *application) SupabaseJWTMiddleware() echo.MiddlewareFunc {
 
 func ServerHeader(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		c.Response().Header().Set(echo.HeaderServer, "TodoApi/0.1")
+		c.Response().Header().Set(echo.HeaderServer, "TodoApi/"+version)
 
 		return next(c)
 	}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// serve runs the HTTP server, the metrics server when app.metricsAddr is set, and the background
+// workers until a server fails or the process receives SIGINT or SIGTERM. It then stops
+// accepting connections, gives in-flight requests until shutdownTimeout to finish, and stops the
+// workers. Closing storage is left to the caller.
+func (app *application) serve(addr string, shutdownTimeout time.Duration) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	workers := app.startWorkers(workerCtx)
+
+	servers := []*echo.Echo{app.Routes()}
+	addrs := []string{addr}
+	if app.metricsAddr != "" {
+		servers = append(servers, app.MetricsRoutes())
+		addrs = append(addrs, app.metricsAddr)
+	}
+
+	serverErr := make(chan error, len(servers))
+	for i, e := range servers {
+		e.HideBanner = true
+		e.HidePort = true
+		go func() {
+			serverErr <- e.Start(addrs[i])
+		}()
+	}
+	app.logger.Info("starting server", "addr", addr)
+	if app.metricsAddr != "" {
+		app.logger.Info("starting metrics server", "addr", app.metricsAddr)
+	}
+
+	var runErr error
+	select {
+	case sig := <-signals:
+		app.logger.Info("shutting down", "signal", sig.String())
+	case err := <-serverErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			app.logger.Info("server closed")
+		} else {
+			runErr = err
+			app.logger.Error("server stopped unexpectedly", "error", err)
+		}
+	}
+
+	// A second signal during shutdown falls through to the default handler and exits at once.
+	signal.Stop(signals)
+
+	app.logger.Info("draining in-flight requests", "timeout", shutdownTimeout.String())
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	drained := true
+	for _, e := range servers {
+		if err := e.Shutdown(ctx); err != nil {
+			app.logger.Warn("drain deadline exceeded; closing remaining connections", "error", err)
+			e.Close()
+			drained = false
+		}
+	}
+	if drained {
+		app.logger.Info("http server stopped")
+	}
+
+	app.logger.Info("stopping background workers")
+	stopWorkers()
+	workers.Wait()
+	app.logger.Info("background workers stopped")
+
+	return runErr
+}
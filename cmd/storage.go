@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dmcleish91/go_todo_api/internal/migrations"
+	"github.com/dmcleish91/go_todo_api/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// storage is the set of stores the server runs on, with the migrator for their schema and a
+// ping that checks the database is reachable, and a collector exporting its connection stats.
+type storage struct {
+	projects    models.ProjectStore
+	tasks       models.TaskStore
+	labels      models.LabelStore
+	settings    models.SettingsStore
+	templates   models.TemplateStore
+	idempotency models.IdempotencyStore
+	migrator    schemaMigrator
+	ping        func(context.Context) error
+	collector   prometheus.Collector
+	close       func()
+}
+
+// openStorage opens the backend named by cfg.StorageBackend: "postgres" connects to
+// cfg.Database and "sqlite" opens the file at cfg.SQLitePath.
+func openStorage(cfg Config) (*storage, error) {
+	switch cfg.StorageBackend {
+	case "postgres":
+		conn := CreateDatabaseConnection(cfg.Database)
+		return &storage{
+			projects:    &models.ProjectModel{DB: conn},
+			tasks:       &models.TaskModel{DB: conn},
+			labels:      &models.LabelModel{DB: conn},
+			settings:    &models.SettingsModel{DB: conn},
+			templates:   &models.TemplateModel{DB: conn},
+			idempotency: &models.IdempotencyModel{DB: conn},
+			migrator:    &migrations.Migrator{DB: conn},
+			ping:        conn.Ping,
+			collector:   newPgxPoolCollector(conn),
+			close:       conn.Close,
+		}, nil
+
+	case "sqlite":
+		store, err := models.OpenSQLite(cfg.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		return &storage{
+			projects:    store.Projects(),
+			tasks:       store.Tasks(),
+			labels:      store.Labels(),
+			settings:    store.Settings(),
+			templates:   store.Templates(),
+			idempotency: store.Idempotency(),
+			migrator:    &migrations.SQLiteMigrator{DB: store.DB()},
+			ping:        store.DB().PingContext,
+			collector:   collectors.NewDBStatsCollector(store.DB(), "sqlite"),
+			close:       func() { store.Close() },
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+}
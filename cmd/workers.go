@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// runEvery calls fn every interval until ctx is cancelled, logging failures under name.
+func (app *application) runEvery(ctx context.Context, name string, interval time.Duration, fn func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := fn(ctx); err != nil {
+				app.logger.Error("background job failed", "job", name, "error", err)
+			}
+		}
+	}
+}
+
+// purgeIdempotencyKeys deletes idempotency keys whose TTL has passed.
+func (app *application) purgeIdempotencyKeys(ctx context.Context) error {
+	deleted, err := app.idempotency.DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		app.logger.Info("purged expired idempotency keys", "count", deleted)
+	}
+	return nil
+}
+
+// archiveCompletedTasks moves tasks completed more than app.archiveAfter ago into the archive.
+func (app *application) archiveCompletedTasks(ctx context.Context) error {
+	archived, err := app.tasks.ArchiveCompletedTasks(ctx, time.Now().Add(-app.archiveAfter))
+	if err != nil {
+		return err
+	}
+	if archived > 0 {
+		app.logger.Info("archived completed tasks", "count", archived)
+	}
+	return nil
+}
+
+// rebalanceTaskOrders respreads sibling lists whose orders have run out of room.
+func (app *application) rebalanceTaskOrders(ctx context.Context) error {
+	renumbered, err := app.tasks.RebalanceTaskOrders(ctx)
+	if err != nil {
+		return err
+	}
+	if renumbered > 0 {
+		app.logger.Info("rebalanced task orders", "count", renumbered)
+	}
+	return nil
+}
+
+// startWorkers launches the background jobs. They stop when ctx is cancelled, and the returned
+// WaitGroup is done once every job has returned.
+func (app *application) startWorkers(ctx context.Context) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	start := func(name string, interval time.Duration, fn func(context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			app.runEvery(ctx, name, interval, fn)
+		}()
+	}
+
+	start("purge-idempotency-keys", time.Hour, app.purgeIdempotencyKeys)
+	start("rebalance-task-orders", 10*time.Minute, app.rebalanceTaskOrders)
+	if app.archiveAfter > 0 {
+		start("archive-completed-tasks", time.Hour, app.archiveCompletedTasks)
+	}
+	return &wg
+}
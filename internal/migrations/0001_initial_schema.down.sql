@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.tasks;
+DROP TABLE IF EXISTS public.labels;
+DROP TABLE IF EXISTS public.projects;
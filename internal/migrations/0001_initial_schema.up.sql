@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS public.projects (
+    project_id uuid NOT NULL DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    project_name character varying NOT NULL,
+    color character varying,
+    is_inbox boolean DEFAULT false,
+    parent_project_id uuid,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT projects_pkey PRIMARY KEY (project_id),
+    CONSTRAINT projects_parent_project_id_fkey FOREIGN KEY (parent_project_id) REFERENCES public.projects(project_id),
+    CONSTRAINT projects_user_id_fkey FOREIGN KEY (user_id) REFERENCES auth.users(id)
+);
+
+CREATE TABLE IF NOT EXISTS public.labels (
+    label_id uuid NOT NULL DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    name character varying NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT labels_pkey PRIMARY KEY (label_id),
+    CONSTRAINT labels_user_id_fkey FOREIGN KEY (user_id) REFERENCES auth.users(id),
+    CONSTRAINT labels_user_name_unique UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS public.tasks (
+    task_id uuid NOT NULL,
+    project_id uuid,
+    user_id uuid NOT NULL,
+    content text NOT NULL,
+    description text,
+    due_date date,
+    due_datetime time without time zone,
+    priority smallint,
+    is_completed boolean DEFAULT false,
+    completed_at timestamp with time zone,
+    parent_task_id uuid,
+    "order" integer NOT NULL DEFAULT 0,
+    labels jsonb DEFAULT '[]'::jsonb,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT tasks_pkey PRIMARY KEY (task_id),
+    CONSTRAINT tasks_parent_task_id_fkey FOREIGN KEY (parent_task_id) REFERENCES public.tasks(task_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT tasks_project_id_fkey FOREIGN KEY (project_id) REFERENCES public.projects(project_id) ON DELETE CASCADE,
+    CONSTRAINT tasks_user_id_fkey FOREIGN KEY (user_id) REFERENCES auth.users(id)
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS public.task_history;
+DROP TABLE IF EXISTS public.project_members;
+DROP INDEX IF EXISTS public.tasks_assignee_id_idx;
+ALTER TABLE public.tasks DROP COLUMN IF EXISTS created_by;
+ALTER TABLE public.tasks DROP COLUMN IF EXISTS assignee_id;
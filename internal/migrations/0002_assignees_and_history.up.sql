@@ -0,0 +1,30 @@
+-- Existing tasks were created by their owner.
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS assignee_id uuid CONSTRAINT tasks_assignee_id_fkey REFERENCES auth.users(id);
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS created_by uuid CONSTRAINT tasks_created_by_fkey REFERENCES auth.users(id);
+UPDATE public.tasks SET created_by = user_id WHERE created_by IS NULL;
+ALTER TABLE public.tasks ALTER COLUMN created_by SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS tasks_assignee_id_idx ON public.tasks (assignee_id);
+
+CREATE TABLE IF NOT EXISTS public.project_members (
+    project_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT project_members_pkey PRIMARY KEY (project_id, user_id),
+    CONSTRAINT project_members_project_id_fkey FOREIGN KEY (project_id) REFERENCES public.projects(project_id) ON DELETE CASCADE,
+    CONSTRAINT project_members_user_id_fkey FOREIGN KEY (user_id) REFERENCES auth.users(id)
+);
+
+-- task_history is an append-only audit log; rows outlive the task they describe.
+CREATE TABLE IF NOT EXISTS public.task_history (
+    event_id uuid NOT NULL DEFAULT gen_random_uuid(),
+    task_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    event_type character varying NOT NULL,
+    old_value text,
+    new_value text,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT task_history_pkey PRIMARY KEY (event_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_history_task_id_idx ON public.task_history (task_id, created_at);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS public.task_dependencies (
+    task_id uuid NOT NULL,
+    blocked_by_task_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT task_dependencies_pkey PRIMARY KEY (task_id, blocked_by_task_id),
+    CONSTRAINT task_dependencies_not_self CHECK (task_id <> blocked_by_task_id),
+    CONSTRAINT task_dependencies_task_id_fkey FOREIGN KEY (task_id) REFERENCES public.tasks(task_id) ON DELETE CASCADE,
+    CONSTRAINT task_dependencies_blocked_by_task_id_fkey FOREIGN KEY (blocked_by_task_id) REFERENCES public.tasks(task_id) ON DELETE CASCADE,
+    CONSTRAINT task_dependencies_user_id_fkey FOREIGN KEY (user_id) REFERENCES auth.users(id)
+);
+
+CREATE INDEX IF NOT EXISTS task_dependencies_blocked_by_idx ON public.task_dependencies (blocked_by_task_id);
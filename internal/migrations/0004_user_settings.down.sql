@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS public.tasks_parent_task_id_idx;
+DROP TABLE IF EXISTS public.user_settings;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS public.user_settings (
+    user_id uuid NOT NULL,
+    complete_subtasks_with_parent boolean NOT NULL DEFAULT false,
+    complete_parent_with_last_subtask boolean NOT NULL DEFAULT false,
+    updated_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT user_settings_pkey PRIMARY KEY (user_id),
+    CONSTRAINT user_settings_user_id_fkey FOREIGN KEY (user_id) REFERENCES auth.users(id)
+);
+
+CREATE INDEX IF NOT EXISTS tasks_parent_task_id_idx ON public.tasks (parent_task_id);
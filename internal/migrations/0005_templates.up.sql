@@ -0,0 +1,11 @@
+-- templates.content holds the project tree (sub-projects, tasks, subtasks, labels and due offsets) as JSON.
+CREATE TABLE IF NOT EXISTS public.templates (
+    template_id uuid NOT NULL DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    name character varying NOT NULL,
+    description text,
+    content jsonb NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT templates_pkey PRIMARY KEY (template_id),
+    CONSTRAINT templates_user_id_fkey FOREIGN KEY (user_id) REFERENCES auth.users(id)
+);
@@ -0,0 +1,10 @@
+ALTER TABLE public.tasks ADD COLUMN IF NOT EXISTS labels jsonb DEFAULT '[]'::jsonb;
+
+UPDATE public.tasks t SET labels = COALESCE((
+    SELECT jsonb_agg(l.name ORDER BY l.name)
+    FROM public.task_labels tl
+    JOIN public.labels l ON l.label_id = tl.label_id
+    WHERE tl.task_id = t.task_id
+), '[]'::jsonb);
+
+DROP TABLE IF EXISTS public.task_labels;
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS public.task_labels (
+    task_id uuid NOT NULL,
+    label_id uuid NOT NULL,
+    CONSTRAINT task_labels_pkey PRIMARY KEY (task_id, label_id),
+    CONSTRAINT task_labels_task_id_fkey FOREIGN KEY (task_id) REFERENCES public.tasks(task_id) ON DELETE CASCADE,
+    CONSTRAINT task_labels_label_id_fkey FOREIGN KEY (label_id) REFERENCES public.labels(label_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS task_labels_label_id_idx ON public.task_labels (label_id);
+
+-- Creates a label for every name found in tasks.labels that the owner doesn't have yet, links
+-- tasks to labels by ID, then drops the old jsonb column. Skipped once the column is gone.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'public' AND table_name = 'tasks' AND column_name = 'labels'
+    ) THEN
+        INSERT INTO public.labels (user_id, name)
+        SELECT DISTINCT t.user_id, l.name
+        FROM public.tasks t
+        CROSS JOIN LATERAL jsonb_array_elements_text(t.labels) AS l(name)
+        WHERE jsonb_typeof(t.labels) = 'array' AND l.name <> ''
+        ON CONFLICT (user_id, name) DO NOTHING;
+
+        INSERT INTO public.task_labels (task_id, label_id)
+        SELECT t.task_id, lb.label_id
+        FROM public.tasks t
+        CROSS JOIN LATERAL jsonb_array_elements_text(t.labels) AS l(name)
+        JOIN public.labels lb ON lb.user_id = t.user_id AND lb.name = l.name
+        WHERE jsonb_typeof(t.labels) = 'array'
+        ON CONFLICT (task_id, label_id) DO NOTHING;
+
+        ALTER TABLE public.tasks DROP COLUMN labels;
+    END IF;
+END $$;
@@ -0,0 +1,5 @@
+-- Merged labels are not restored.
+DROP INDEX IF EXISTS public.labels_user_lower_name_unique;
+ALTER TABLE public.labels DROP COLUMN IF EXISTS "order";
+ALTER TABLE public.labels DROP COLUMN IF EXISTS is_favorite;
+ALTER TABLE public.labels DROP COLUMN IF EXISTS color;
@@ -0,0 +1,39 @@
+-- Labels whose names differ only by case are merged into the oldest one before the
+-- case-insensitive unique index is created. Orders are only assigned when the column is new,
+-- so running this on a database that already has it keeps the manual order.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'public' AND table_name = 'labels' AND column_name = 'order'
+    ) THEN
+        ALTER TABLE public.labels ADD COLUMN "order" integer NOT NULL DEFAULT 0;
+
+        UPDATE public.labels l SET "order" = s.position
+        FROM (
+            SELECT label_id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY name) - 1 AS position FROM public.labels
+        ) s
+        WHERE l.label_id = s.label_id;
+    END IF;
+END $$;
+
+ALTER TABLE public.labels ADD COLUMN IF NOT EXISTS color character varying;
+ALTER TABLE public.labels ADD COLUMN IF NOT EXISTS is_favorite boolean NOT NULL DEFAULT false;
+
+CREATE TEMPORARY TABLE label_merges ON COMMIT DROP AS
+SELECT label_id, first_value(label_id) OVER (PARTITION BY user_id, lower(name) ORDER BY created_at, label_id) AS keep_id
+FROM public.labels;
+
+INSERT INTO public.task_labels (task_id, label_id)
+SELECT tl.task_id, m.keep_id
+FROM public.task_labels tl
+JOIN label_merges m ON m.label_id = tl.label_id
+WHERE m.label_id <> m.keep_id
+ON CONFLICT (task_id, label_id) DO NOTHING;
+
+DELETE FROM public.labels l
+USING label_merges m
+WHERE l.label_id = m.label_id AND m.label_id <> m.keep_id;
+
+-- Label names are unique per user ignoring case.
+CREATE UNIQUE INDEX IF NOT EXISTS labels_user_lower_name_unique ON public.labels (user_id, lower(name));
@@ -0,0 +1,15 @@
+-- idempotency_keys stores the response to a request sent with an Idempotency-Key header so retries
+-- can be replayed. status_code is NULL while the first request is still being processed.
+CREATE TABLE IF NOT EXISTS public.idempotency_keys (
+    user_id uuid NOT NULL,
+    idempotency_key character varying(255) NOT NULL,
+    request_hash character varying NOT NULL,
+    status_code integer,
+    content_type character varying,
+    response_body bytea,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    expires_at timestamp with time zone NOT NULL,
+    CONSTRAINT idempotency_keys_pkey PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON public.idempotency_keys (expires_at);
@@ -0,0 +1,25 @@
+-- archived_tasks holds completed task trees moved out of tasks by the auto-archive job.
+-- Rows are read-only snapshots: labels keeps the label names at archive time, and the project
+-- and parent references are kept as plain IDs.
+CREATE TABLE IF NOT EXISTS public.archived_tasks (
+    task_id uuid NOT NULL,
+    project_id uuid,
+    user_id uuid NOT NULL,
+    content text NOT NULL,
+    description text,
+    due_date date,
+    due_datetime time without time zone,
+    priority smallint,
+    completed_at timestamp with time zone NOT NULL,
+    parent_task_id uuid,
+    "order" integer NOT NULL DEFAULT 0,
+    labels text[] NOT NULL DEFAULT '{}',
+    assignee_id uuid,
+    created_by uuid NOT NULL,
+    created_at timestamp with time zone NOT NULL,
+    archived_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT archived_tasks_pkey PRIMARY KEY (task_id)
+);
+
+CREATE INDEX IF NOT EXISTS archived_tasks_user_id_completed_at_idx ON public.archived_tasks (user_id, completed_at);
+CREATE INDEX IF NOT EXISTS tasks_user_id_completed_at_idx ON public.tasks (user_id, completed_at) WHERE is_completed;
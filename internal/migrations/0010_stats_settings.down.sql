@@ -0,0 +1,2 @@
+ALTER TABLE public.user_settings DROP COLUMN IF EXISTS timezone;
+ALTER TABLE public.user_settings DROP COLUMN IF EXISTS daily_goal;
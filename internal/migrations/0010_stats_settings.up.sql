@@ -0,0 +1,2 @@
+ALTER TABLE public.user_settings ADD COLUMN IF NOT EXISTS daily_goal integer NOT NULL DEFAULT 5;
+ALTER TABLE public.user_settings ADD COLUMN IF NOT EXISTS timezone character varying NOT NULL DEFAULT 'UTC';
@@ -0,0 +1,3 @@
+ALTER TABLE public.projects DROP COLUMN IF EXISTS "order";
+ALTER TABLE public.projects DROP COLUMN IF EXISTS is_favorite;
+ALTER TABLE public.projects DROP COLUMN IF EXISTS is_archived;
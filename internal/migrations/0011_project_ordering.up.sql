@@ -0,0 +1,21 @@
+-- Existing projects keep their creation order among their siblings. Orders are only assigned
+-- when the column is new, so running this on a database that already has it keeps the manual order.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'public' AND table_name = 'projects' AND column_name = 'order'
+    ) THEN
+        ALTER TABLE public.projects ADD COLUMN "order" integer NOT NULL DEFAULT 0;
+
+        UPDATE public.projects p SET "order" = o.rn
+        FROM (
+            SELECT project_id, ROW_NUMBER() OVER (PARTITION BY user_id, parent_project_id ORDER BY created_at, project_id) - 1 AS rn
+            FROM public.projects
+        ) o
+        WHERE o.project_id = p.project_id;
+    END IF;
+END $$;
+
+ALTER TABLE public.projects ADD COLUMN IF NOT EXISTS is_archived boolean NOT NULL DEFAULT false;
+ALTER TABLE public.projects ADD COLUMN IF NOT EXISTS is_favorite boolean NOT NULL DEFAULT false;
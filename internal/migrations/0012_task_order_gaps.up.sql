@@ -0,0 +1,8 @@
+-- Sibling lists are spread out to multiples of 1024 so a task can be placed between two
+-- neighbours by updating only its own order. The relative order is unchanged.
+UPDATE public.tasks t SET "order" = s.position
+FROM (
+    SELECT task_id, (ROW_NUMBER() OVER (PARTITION BY user_id, project_id, parent_task_id ORDER BY "order" ASC, created_at ASC) - 1) * 1024 AS position
+    FROM public.tasks
+) s
+WHERE t.task_id = s.task_id AND t."order" <> s.position;
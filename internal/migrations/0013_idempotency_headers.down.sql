@@ -0,0 +1 @@
+ALTER TABLE public.idempotency_keys DROP COLUMN IF EXISTS response_headers;
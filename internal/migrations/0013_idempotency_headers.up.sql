@@ -0,0 +1,3 @@
+-- response_headers keeps the headers, other than Content-Type, that a replayed response needs,
+-- such as Location.
+ALTER TABLE public.idempotency_keys ADD COLUMN IF NOT EXISTS response_headers jsonb;
@@ -0,0 +1,169 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLiteMigrator applies the scripts in sqlite/ to a database opened with models.OpenSQLite.
+// They mirror the Postgres scripts version for version, so both backends report the same
+// status. Transactions start with BEGIN IMMEDIATE, which takes SQLite's write lock up front
+// and plays the part of the Postgres advisory lock.
+type SQLiteMigrator struct {
+	DB *sql.DB
+}
+
+// SQLiteAll returns the embedded SQLite migrations in version order.
+func SQLiteAll() ([]Migration, error) {
+	return load(sqliteScripts, "sqlite")
+}
+
+// Up applies every pending migration in order and returns the ones it applied.
+func (m *SQLiteMigrator) Up(ctx context.Context) ([]Migration, error) {
+	all, err := SQLiteAll()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, mig := range all {
+		ran, err := m.run(ctx, mig.Version, true, mig.Up, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			mig.Version, mig.Name, time.Now().UTC().Format(time.RFC3339Nano))
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s failed: %w", mig.Version, mig.Name, err)
+		}
+		if ran {
+			applied = append(applied, mig)
+		}
+	}
+	return applied, nil
+}
+
+// Down reverts the latest steps applied migrations, newest first, and returns the ones it reverted.
+func (m *SQLiteMigrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	all, err := SQLiteAll()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(all) - 1; i >= 0 && len(reverted) < steps; i-- {
+		mig := all[i]
+		ran, err := m.run(ctx, mig.Version, false, mig.Down, `DELETE FROM schema_migrations WHERE version = ?`, mig.Version)
+		if err != nil {
+			return reverted, fmt.Errorf("reverting migration %d_%s failed: %w", mig.Version, mig.Name, err)
+		}
+		if ran {
+			reverted = append(reverted, mig)
+		}
+	}
+	return reverted, nil
+}
+
+// Status lists every embedded migration with the time it was applied, or nil if it is pending.
+func (m *SQLiteMigrator) Status(ctx context.Context) ([]Status, error) {
+	all, err := SQLiteAll()
+	if err != nil {
+		return nil, err
+	}
+
+	done := map[int64]time.Time{}
+	var exists bool
+	err = m.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("unable to check schema_migrations: %w", err)
+	}
+	if exists {
+		rows, err := m.DB.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read schema_migrations: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var version int64
+			var at string
+			if err := rows.Scan(&version, &at); err != nil {
+				return nil, fmt.Errorf("unable to scan schema_migrations: %w", err)
+			}
+			done[version], err = time.Parse(time.RFC3339Nano, at)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse applied_at of version %d: %w", version, err)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("unable to read schema_migrations: %w", err)
+		}
+	}
+
+	statuses := make([]Status, len(all))
+	for i, mig := range all {
+		statuses[i] = Status{Version: mig.Version, Name: mig.Name}
+		if at, ok := done[mig.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
+
+// Pending returns how many embedded migrations have not been applied yet.
+func (m *SQLiteMigrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (m *SQLiteMigrator) ensureTable(ctx context.Context) error {
+	_, err := m.DB.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			applied_at TEXT NOT NULL,
+			CONSTRAINT schema_migrations_pkey PRIMARY KEY (version)
+		)`)
+	if err != nil {
+		return fmt.Errorf("unable to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// run executes script and the bookkeeping statement in one transaction if version's applied
+// state is not already want, and reports whether it did. The check runs inside the transaction,
+// so an instance that waited for the lock skips what another one just did.
+func (m *SQLiteMigrator) run(ctx context.Context, version int64, want bool, script, record string, args ...any) (bool, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var applied bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)`, version).Scan(&applied); err != nil {
+		return false, err
+	}
+	if applied == want {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
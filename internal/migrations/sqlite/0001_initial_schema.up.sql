@@ -0,0 +1,43 @@
+-- SQLite has no auth schema; user_id columns hold the JWT subject without a foreign key.
+-- UUIDs are stored as text, timestamps as fixed-width UTC RFC 3339 text with microseconds,
+-- dates as YYYY-MM-DD and times of day as HH:MM:SS.ffffff, so they sort and compare as text.
+CREATE TABLE IF NOT EXISTS projects (
+    project_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    project_name TEXT NOT NULL,
+    color TEXT,
+    is_inbox INTEGER DEFAULT 0,
+    parent_project_id TEXT,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f000Z', 'now')),
+    CONSTRAINT projects_pkey PRIMARY KEY (project_id),
+    CONSTRAINT projects_parent_project_id_fkey FOREIGN KEY (parent_project_id) REFERENCES projects(project_id)
+);
+
+CREATE TABLE IF NOT EXISTS labels (
+    label_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f000Z', 'now')),
+    CONSTRAINT labels_pkey PRIMARY KEY (label_id),
+    CONSTRAINT labels_user_name_unique UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS tasks (
+    task_id TEXT NOT NULL,
+    project_id TEXT,
+    user_id TEXT NOT NULL,
+    content TEXT NOT NULL,
+    description TEXT,
+    due_date TEXT,
+    due_datetime TEXT,
+    priority INTEGER,
+    is_completed INTEGER DEFAULT 0,
+    completed_at TEXT,
+    parent_task_id TEXT,
+    "order" INTEGER NOT NULL DEFAULT 0,
+    labels TEXT DEFAULT '[]' CHECK (json_valid(labels)),
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f000Z', 'now')),
+    CONSTRAINT tasks_pkey PRIMARY KEY (task_id),
+    CONSTRAINT tasks_parent_task_id_fkey FOREIGN KEY (parent_task_id) REFERENCES tasks(task_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT tasks_project_id_fkey FOREIGN KEY (project_id) REFERENCES projects(project_id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS task_history;
+DROP TABLE IF EXISTS project_members;
+DROP INDEX IF EXISTS tasks_assignee_id_idx;
+ALTER TABLE tasks DROP COLUMN created_by;
+ALTER TABLE tasks DROP COLUMN assignee_id;
@@ -0,0 +1,29 @@
+-- Existing tasks were created by their owner. SQLite can't add a NOT NULL column without a
+-- default, so created_by defaults to the empty string and is filled in straight away.
+ALTER TABLE tasks ADD COLUMN assignee_id TEXT;
+ALTER TABLE tasks ADD COLUMN created_by TEXT NOT NULL DEFAULT '';
+UPDATE tasks SET created_by = user_id WHERE created_by = '';
+
+CREATE INDEX IF NOT EXISTS tasks_assignee_id_idx ON tasks (assignee_id);
+
+CREATE TABLE IF NOT EXISTS project_members (
+    project_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f000Z', 'now')),
+    CONSTRAINT project_members_pkey PRIMARY KEY (project_id, user_id),
+    CONSTRAINT project_members_project_id_fkey FOREIGN KEY (project_id) REFERENCES projects(project_id) ON DELETE CASCADE
+);
+
+-- task_history is an append-only audit log; rows outlive the task they describe.
+CREATE TABLE IF NOT EXISTS task_history (
+    event_id TEXT NOT NULL,
+    task_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f000Z', 'now')),
+    CONSTRAINT task_history_pkey PRIMARY KEY (event_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_history_task_id_idx ON task_history (task_id, created_at);
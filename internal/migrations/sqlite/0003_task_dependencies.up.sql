@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS task_dependencies (
+    task_id TEXT NOT NULL,
+    blocked_by_task_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f000Z', 'now')),
+    CONSTRAINT task_dependencies_pkey PRIMARY KEY (task_id, blocked_by_task_id),
+    CONSTRAINT task_dependencies_not_self CHECK (task_id <> blocked_by_task_id),
+    CONSTRAINT task_dependencies_task_id_fkey FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE,
+    CONSTRAINT task_dependencies_blocked_by_task_id_fkey FOREIGN KEY (blocked_by_task_id) REFERENCES tasks(task_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS task_dependencies_blocked_by_idx ON task_dependencies (blocked_by_task_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS tasks_parent_task_id_idx;
+DROP TABLE IF EXISTS user_settings;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id TEXT NOT NULL,
+    complete_subtasks_with_parent INTEGER NOT NULL DEFAULT 0,
+    complete_parent_with_last_subtask INTEGER NOT NULL DEFAULT 0,
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f000Z', 'now')),
+    CONSTRAINT user_settings_pkey PRIMARY KEY (user_id)
+);
+
+CREATE INDEX IF NOT EXISTS tasks_parent_task_id_idx ON tasks (parent_task_id);
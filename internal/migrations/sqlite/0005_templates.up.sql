@@ -0,0 +1,10 @@
+-- templates.content holds the project tree (sub-projects, tasks, subtasks, labels and due offsets) as JSON.
+CREATE TABLE IF NOT EXISTS templates (
+    template_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT,
+    content TEXT NOT NULL CHECK (json_valid(content)),
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f000Z', 'now')),
+    CONSTRAINT templates_pkey PRIMARY KEY (template_id)
+);
@@ -0,0 +1,10 @@
+ALTER TABLE tasks ADD COLUMN labels TEXT DEFAULT '[]' CHECK (json_valid(labels));
+
+UPDATE tasks SET labels = (
+    SELECT json_group_array(l.name ORDER BY l.name)
+    FROM task_labels tl
+    JOIN labels l ON l.label_id = tl.label_id
+    WHERE tl.task_id = tasks.task_id
+);
+
+DROP TABLE IF EXISTS task_labels;
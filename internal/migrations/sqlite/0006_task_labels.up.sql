@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS task_labels (
+    task_id TEXT NOT NULL,
+    label_id TEXT NOT NULL,
+    CONSTRAINT task_labels_pkey PRIMARY KEY (task_id, label_id),
+    CONSTRAINT task_labels_task_id_fkey FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE,
+    CONSTRAINT task_labels_label_id_fkey FOREIGN KEY (label_id) REFERENCES labels(label_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS task_labels_label_id_idx ON task_labels (label_id);
+
+-- Creates a label for every name found in tasks.labels that the owner doesn't have yet, links
+-- tasks to labels by ID, then drops the old JSON column. New label IDs are random version 4 UUIDs.
+INSERT INTO labels (label_id, user_id, name)
+SELECT
+    lower(substr(h, 1, 8) || '-' || substr(h, 9, 4) || '-4' || substr(h, 14, 3) || '-' ||
+        substr('89ab', 1 + (abs(random()) % 4), 1) || substr(h, 18, 3) || '-' || substr(h, 21, 12)),
+    user_id, name
+FROM (
+    SELECT t.user_id, l.value AS name, hex(randomblob(16)) AS h
+    FROM tasks t, json_each(t.labels) AS l
+    WHERE json_type(t.labels) = 'array' AND l.type = 'text' AND l.value <> ''
+    GROUP BY t.user_id, l.value
+)
+WHERE true
+ON CONFLICT (user_id, name) DO NOTHING;
+
+INSERT INTO task_labels (task_id, label_id)
+SELECT t.task_id, lb.label_id
+FROM tasks t, json_each(t.labels) AS l
+JOIN labels lb ON lb.user_id = t.user_id AND lb.name = l.value
+WHERE json_type(t.labels) = 'array'
+ON CONFLICT (task_id, label_id) DO NOTHING;
+
+ALTER TABLE tasks DROP COLUMN labels;
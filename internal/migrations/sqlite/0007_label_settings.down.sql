@@ -0,0 +1,5 @@
+-- Merged labels are not restored.
+DROP INDEX IF EXISTS labels_user_lower_name_unique;
+ALTER TABLE labels DROP COLUMN "order";
+ALTER TABLE labels DROP COLUMN is_favorite;
+ALTER TABLE labels DROP COLUMN color;
@@ -0,0 +1,32 @@
+-- Orders follow the label names, as for a Postgres database that gets the column here.
+ALTER TABLE labels ADD COLUMN "order" INTEGER NOT NULL DEFAULT 0;
+
+UPDATE labels SET "order" = s.position
+FROM (
+    SELECT label_id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY name) - 1 AS position FROM labels
+) s
+WHERE labels.label_id = s.label_id;
+
+ALTER TABLE labels ADD COLUMN color TEXT;
+ALTER TABLE labels ADD COLUMN is_favorite INTEGER NOT NULL DEFAULT 0;
+
+-- Labels whose names differ only by case are merged into the oldest one before the
+-- case-insensitive unique index is created.
+CREATE TEMPORARY TABLE label_merges AS
+SELECT label_id, first_value(label_id) OVER (PARTITION BY user_id, lower(name) ORDER BY created_at, label_id) AS keep_id
+FROM labels;
+
+INSERT INTO task_labels (task_id, label_id)
+SELECT tl.task_id, m.keep_id
+FROM task_labels tl
+JOIN label_merges m ON m.label_id = tl.label_id
+WHERE m.label_id <> m.keep_id
+ON CONFLICT (task_id, label_id) DO NOTHING;
+
+DELETE FROM labels
+WHERE label_id IN (SELECT label_id FROM label_merges WHERE label_id <> keep_id);
+
+DROP TABLE temp.label_merges;
+
+-- Label names are unique per user ignoring case. SQLite's lower() only folds ASCII letters.
+CREATE UNIQUE INDEX IF NOT EXISTS labels_user_lower_name_unique ON labels (user_id, lower(name));
@@ -0,0 +1,15 @@
+-- idempotency_keys stores the response to a request sent with an Idempotency-Key header so retries
+-- can be replayed. status_code is NULL while the first request is still being processed.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id TEXT NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code INTEGER,
+    content_type TEXT,
+    response_body BLOB,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f000Z', 'now')),
+    expires_at TEXT NOT NULL,
+    CONSTRAINT idempotency_keys_pkey PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
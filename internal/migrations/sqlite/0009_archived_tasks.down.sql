@@ -0,0 +1,3 @@
+-- Archived tasks are dropped, not restored into tasks.
+DROP INDEX IF EXISTS tasks_user_id_completed_at_idx;
+DROP TABLE IF EXISTS archived_tasks;
@@ -0,0 +1,25 @@
+-- archived_tasks holds completed task trees moved out of tasks by the auto-archive job.
+-- Rows are read-only snapshots: labels keeps the label names at archive time as a JSON array,
+-- and the project and parent references are kept as plain IDs.
+CREATE TABLE IF NOT EXISTS archived_tasks (
+    task_id TEXT NOT NULL,
+    project_id TEXT,
+    user_id TEXT NOT NULL,
+    content TEXT NOT NULL,
+    description TEXT,
+    due_date TEXT,
+    due_datetime TEXT,
+    priority INTEGER,
+    completed_at TEXT NOT NULL,
+    parent_task_id TEXT,
+    "order" INTEGER NOT NULL DEFAULT 0,
+    labels TEXT NOT NULL DEFAULT '[]' CHECK (json_valid(labels)),
+    assignee_id TEXT,
+    created_by TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    archived_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f000Z', 'now')),
+    CONSTRAINT archived_tasks_pkey PRIMARY KEY (task_id)
+);
+
+CREATE INDEX IF NOT EXISTS archived_tasks_user_id_completed_at_idx ON archived_tasks (user_id, completed_at);
+CREATE INDEX IF NOT EXISTS tasks_user_id_completed_at_idx ON tasks (user_id, completed_at) WHERE is_completed;
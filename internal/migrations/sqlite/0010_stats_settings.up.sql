@@ -0,0 +1,2 @@
+ALTER TABLE user_settings ADD COLUMN daily_goal INTEGER NOT NULL DEFAULT 5;
+ALTER TABLE user_settings ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
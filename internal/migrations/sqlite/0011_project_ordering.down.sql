@@ -0,0 +1,3 @@
+ALTER TABLE projects DROP COLUMN "order";
+ALTER TABLE projects DROP COLUMN is_favorite;
+ALTER TABLE projects DROP COLUMN is_archived;
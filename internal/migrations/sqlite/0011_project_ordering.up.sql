@@ -0,0 +1,12 @@
+-- Existing projects keep their creation order among their siblings.
+ALTER TABLE projects ADD COLUMN "order" INTEGER NOT NULL DEFAULT 0;
+
+UPDATE projects SET "order" = o.rn
+FROM (
+    SELECT project_id, ROW_NUMBER() OVER (PARTITION BY user_id, parent_project_id ORDER BY created_at, project_id) - 1 AS rn
+    FROM projects
+) o
+WHERE o.project_id = projects.project_id;
+
+ALTER TABLE projects ADD COLUMN is_archived INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE projects ADD COLUMN is_favorite INTEGER NOT NULL DEFAULT 0;
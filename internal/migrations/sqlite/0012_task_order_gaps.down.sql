@@ -0,0 +1,6 @@
+UPDATE tasks SET "order" = s.position
+FROM (
+    SELECT task_id, ROW_NUMBER() OVER (PARTITION BY user_id, project_id, parent_task_id ORDER BY "order" ASC, created_at ASC) - 1 AS position
+    FROM tasks
+) s
+WHERE tasks.task_id = s.task_id AND tasks."order" <> s.position;
@@ -0,0 +1,3 @@
+-- response_headers keeps the headers, other than Content-Type, that a replayed response needs,
+-- such as Location. It holds a JSON object.
+ALTER TABLE idempotency_keys ADD COLUMN response_headers TEXT;
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CompletedTask is a completed task as returned by GetCompletedTasks. Tasks moved to the
+// archive keep a snapshot of their label names and record when they were archived.
+type CompletedTask struct {
+	TaskID       uuid.UUID  `json:"task_id"`
+	ProjectID    *uuid.UUID `json:"project_id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	Content      string     `json:"content"`
+	Description  *string    `json:"description"`
+	DueDate      *time.Time `json:"due_date"`
+	DueDatetime  *time.Time `json:"due_datetime"`
+	Priority     int16      `json:"priority"`
+	CompletedAt  time.Time  `json:"completed_at"`
+	ParentTaskID *uuid.UUID `json:"parent_task_id"`
+	Order        int        `json:"order"`
+	Labels       []string   `json:"labels"`
+	AssigneeID   *uuid.UUID `json:"assignee_id"`
+	CreatedBy    uuid.UUID  `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Archived     bool       `json:"archived"`
+	ArchivedAt   *time.Time `json:"archived_at"`
+}
+
+// CompletedTaskFilter narrows GetCompletedTasks. Tasks completed in [From, To) are returned;
+// nil bounds are open.
+type CompletedTaskFilter struct {
+	From      *time.Time
+	To        *time.Time
+	ProjectID *uuid.UUID
+	Limit     int
+}
+
+// completedTaskColumns is shared by the live and archived halves of GetCompletedTasks.
+// The live half must select from tasks without an alias so the label subquery resolves.
+const completedTaskColumns = `task_id, project_id, user_id, content, description, due_date, due_datetime, priority, completed_at, parent_task_id, "order"`
+
+// GetCompletedTasks returns the completed tasks a user owns or is assigned to, archived or not,
+// most recently completed first.
+func (m *TaskModel) GetCompletedTasks(userID uuid.UUID, filter CompletedTaskFilter) ([]CompletedTask, error) {
+	query := `
+		SELECT ` + completedTaskColumns + `, ` + taskLabelNamesColumn + `, assignee_id, created_by, created_at, NULL::timestamptz
+		FROM tasks
+		WHERE (user_id = $1 OR assignee_id = $1) AND is_completed
+			AND ($2::timestamptz IS NULL OR completed_at >= $2)
+			AND ($3::timestamptz IS NULL OR completed_at < $3)
+			AND ($4::uuid IS NULL OR project_id = $4)
+		UNION ALL
+		SELECT ` + completedTaskColumns + `, labels, assignee_id, created_by, created_at, archived_at
+		FROM archived_tasks
+		WHERE (user_id = $1 OR assignee_id = $1)
+			AND ($2::timestamptz IS NULL OR completed_at >= $2)
+			AND ($3::timestamptz IS NULL OR completed_at < $3)
+			AND ($4::uuid IS NULL OR project_id = $4)
+		ORDER BY completed_at DESC, task_id
+		LIMIT $5`
+
+	rows, err := m.DB.Query(context.Background(), query, userID, filter.From, filter.To, filter.ProjectID, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query completed tasks: %w", err)
+	}
+	tasks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (CompletedTask, error) {
+		var task CompletedTask
+		err := row.Scan(
+			&task.TaskID,
+			&task.ProjectID,
+			&task.UserID,
+			&task.Content,
+			&task.Description,
+			&task.DueDate,
+			&task.DueDatetime,
+			&task.Priority,
+			&task.CompletedAt,
+			&task.ParentTaskID,
+			&task.Order,
+			&task.Labels,
+			&task.AssigneeID,
+			&task.CreatedBy,
+			&task.CreatedAt,
+			&task.ArchivedAt,
+		)
+		task.Archived = task.ArchivedAt != nil
+		return task, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan row: %w", err)
+	}
+	return tasks, nil
+}
+
+// ArchiveCompletedTasks moves top-level tasks completed before cutoff, together with their
+// subtasks, into archived_tasks. A tree is only archived once every task in it was completed
+// before cutoff. It returns the number of tasks archived.
+func (m *TaskModel) ArchiveCompletedTasks(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `
+		WITH RECURSIVE tree(root_id, task_id) AS (
+			SELECT task_id, task_id FROM tasks
+			WHERE parent_task_id IS NULL AND is_completed AND completed_at < $1
+			UNION
+			SELECT tr.root_id, t.task_id FROM tasks t JOIN tree tr ON t.parent_task_id = tr.task_id
+		), eligible AS (
+			SELECT tr.root_id
+			FROM tree tr
+			JOIN tasks t ON t.task_id = tr.task_id
+			GROUP BY tr.root_id
+			HAVING bool_and(t.is_completed AND t.completed_at < $1)
+		), archived AS (
+			INSERT INTO archived_tasks (` + completedTaskColumns + `, labels, assignee_id, created_by, created_at)
+			SELECT ` + completedTaskColumns + `, ` + taskLabelNamesColumn + `, assignee_id, created_by, created_at
+			FROM tasks
+			WHERE task_id IN (SELECT task_id FROM tree WHERE root_id IN (SELECT root_id FROM eligible))
+			RETURNING task_id
+		)
+		DELETE FROM tasks WHERE task_id IN (SELECT task_id FROM archived)`
+
+	result, err := m.DB.Exec(ctx, query, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("unable to archive completed tasks: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,228 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// TaskDependency records that TaskID cannot start until BlockedByTaskID is done.
+type TaskDependency struct {
+	TaskID          uuid.UUID `json:"task_id"`
+	BlockedByTaskID uuid.UUID `json:"blocked_by_task_id"`
+	UserID          uuid.UUID `json:"user_id"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// TaskDependencies lists both directions of a task's dependency edges.
+type TaskDependencies struct {
+	BlockedBy []uuid.UUID `json:"blocked_by"`
+	Blocks    []uuid.UUID `json:"blocks"`
+}
+
+// AddDependency marks taskID as blocked by blockedByID. Both tasks must belong to userID.
+// It returns ErrDependencyCycle if blockedByID already (transitively) depends on taskID.
+func (m *TaskModel) AddDependency(taskID, blockedByID, userID uuid.UUID) (TaskDependency, error) {
+	if taskID == blockedByID {
+		return TaskDependency{}, ErrDependencyCycle
+	}
+
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return TaskDependency{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize graph changes per user so two concurrent inserts can't close a cycle between them.
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1::text, 0))`, userID); err != nil {
+		return TaskDependency{}, fmt.Errorf("unable to lock dependency graph: %w", err)
+	}
+
+	var owned int
+	err = tx.QueryRow(ctx, `SELECT count(*) FROM tasks WHERE task_id IN ($1, $2) AND user_id = $3`, taskID, blockedByID, userID).Scan(&owned)
+	if err != nil {
+		return TaskDependency{}, fmt.Errorf("unable to fetch tasks: %w", err)
+	}
+	if owned != 2 {
+		return TaskDependency{}, ErrNoRecord
+	}
+
+	cycleQuery := `
+		WITH RECURSIVE upstream(task_id) AS (
+			SELECT blocked_by_task_id FROM task_dependencies WHERE task_id = $1
+			UNION
+			SELECT d.blocked_by_task_id
+			FROM task_dependencies d
+			JOIN upstream u ON d.task_id = u.task_id
+		)
+		SELECT EXISTS (SELECT 1 FROM upstream WHERE task_id = $2)`
+
+	var cycle bool
+	if err := tx.QueryRow(ctx, cycleQuery, blockedByID, taskID).Scan(&cycle); err != nil {
+		return TaskDependency{}, fmt.Errorf("unable to check for dependency cycle: %w", err)
+	}
+	if cycle {
+		return TaskDependency{}, ErrDependencyCycle
+	}
+
+	query := `
+		INSERT INTO task_dependencies (task_id, blocked_by_task_id, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (task_id, blocked_by_task_id) DO UPDATE SET created_at = task_dependencies.created_at
+		RETURNING task_id, blocked_by_task_id, user_id, created_at`
+
+	var dep TaskDependency
+	err = tx.QueryRow(ctx, query, taskID, blockedByID, userID).Scan(
+		&dep.TaskID,
+		&dep.BlockedByTaskID,
+		&dep.UserID,
+		&dep.CreatedAt,
+	)
+	if err != nil {
+		return TaskDependency{}, fmt.Errorf("unable to add dependency: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return TaskDependency{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return dep, nil
+}
+
+// RemoveDependency deletes the edge that makes taskID blocked by blockedByID.
+func (m *TaskModel) RemoveDependency(taskID, blockedByID, userID uuid.UUID) (int64, error) {
+	query := `DELETE FROM task_dependencies WHERE task_id = $1 AND blocked_by_task_id = $2 AND user_id = $3`
+
+	result, err := m.DB.Exec(context.Background(), query, taskID, blockedByID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to remove dependency: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// GetTaskDependencies returns the tasks blocking taskID and the tasks taskID blocks.
+func (m *TaskModel) GetTaskDependencies(taskID, userID uuid.UUID) (TaskDependencies, error) {
+	query := `
+		SELECT blocked_by_task_id, task_id
+		FROM task_dependencies
+		WHERE user_id = $2 AND (task_id = $1 OR blocked_by_task_id = $1)
+		ORDER BY created_at ASC`
+
+	rows, err := m.DB.Query(context.Background(), query, taskID, userID)
+	if err != nil {
+		return TaskDependencies{}, fmt.Errorf("unable to query dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	deps := TaskDependencies{BlockedBy: []uuid.UUID{}, Blocks: []uuid.UUID{}}
+	for rows.Next() {
+		var blocker, blocked uuid.UUID
+		if err := rows.Scan(&blocker, &blocked); err != nil {
+			return TaskDependencies{}, fmt.Errorf("unable to scan dependency: %w", err)
+		}
+		if blocked == taskID {
+			deps.BlockedBy = append(deps.BlockedBy, blocker)
+		} else {
+			deps.Blocks = append(deps.Blocks, blocked)
+		}
+	}
+	return deps, nil
+}
+
+// GetProjectTasksInTopologicalOrder returns a project's tasks ordered so that every task comes
+// after the tasks blocking it. Dependencies on tasks outside the project are ignored.
+func (m *TaskModel) GetProjectTasksInTopologicalOrder(projectID, userID uuid.UUID) ([]Task, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE project_id = $1 AND user_id = $2
+		ORDER BY "order" ASC, created_at ASC`
+
+	tasks, err := queryTasks(ctx, m.DB, query, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	depQuery := `
+		SELECT d.task_id, d.blocked_by_task_id, d.user_id, d.created_at
+		FROM task_dependencies d
+		JOIN tasks a ON a.task_id = d.task_id
+		JOIN tasks b ON b.task_id = d.blocked_by_task_id
+		WHERE a.project_id = $1 AND b.project_id = $1 AND d.user_id = $2`
+
+	rows, err := m.DB.Query(ctx, depQuery, projectID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query dependencies: %w", err)
+	}
+	deps, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (TaskDependency, error) {
+		var dep TaskDependency
+		err := row.Scan(&dep.TaskID, &dep.BlockedByTaskID, &dep.UserID, &dep.CreatedAt)
+		return dep, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan dependency: %w", err)
+	}
+
+	return TopologicalSort(tasks, deps)
+}
+
+// TopologicalSort orders tasks so that each task follows all of its blockers (Kahn's algorithm).
+// Among tasks that are ready at the same time, the input order is preserved.
+// Dependencies referring to tasks not in the slice are ignored.
+func TopologicalSort(tasks []Task, deps []TaskDependency) ([]Task, error) {
+	index := make(map[uuid.UUID]int, len(tasks))
+	for i, task := range tasks {
+		index[task.TaskID] = i
+	}
+
+	indegree := make([]int, len(tasks))
+	blocks := make([][]int, len(tasks))
+	for _, dep := range deps {
+		from, ok := index[dep.BlockedByTaskID]
+		if !ok {
+			continue
+		}
+		to, ok := index[dep.TaskID]
+		if !ok {
+			continue
+		}
+		blocks[from] = append(blocks[from], to)
+		indegree[to]++
+	}
+
+	// ready is kept sorted by input position so ties resolve deterministically.
+	var ready []int
+	for i, n := range indegree {
+		if n == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	sorted := make([]Task, 0, len(tasks))
+	for len(ready) > 0 {
+		next := ready[0]
+		ready = ready[1:]
+		sorted = append(sorted, tasks[next])
+		for _, to := range blocks[next] {
+			indegree[to]--
+			if indegree[to] == 0 {
+				pos := sort.SearchInts(ready, to)
+				ready = append(ready, 0)
+				copy(ready[pos+1:], ready[pos:])
+				ready[pos] = to
+			}
+		}
+	}
+
+	if len(sorted) != len(tasks) {
+		return nil, ErrDependencyCycle
+	}
+	return sorted, nil
+}
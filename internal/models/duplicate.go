@@ -0,0 +1,281 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// DuplicateOptions tune how tasks and projects are copied.
+type DuplicateOptions struct {
+	// DueDateOffsetDays shifts every copied due date by this many days (may be negative).
+	DueDateOffsetDays int `json:"due_date_offset_days"`
+	// ProjectName names the copy of the root project; it defaults to "Copy of <name>".
+	// It is ignored when duplicating a task.
+	ProjectName *string `json:"project_name"`
+}
+
+// DuplicateTask deep-copies a task and its subtasks with fresh IDs in a single transaction.
+// The copy is appended after the original's siblings; subtasks keep their relative order.
+// Copies start out open, whatever the completion state of the originals.
+func (m *TaskModel) DuplicateTask(taskID, userID uuid.UUID, opts DuplicateOptions) (Task, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return Task{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		WITH RECURSIVE subtree(task_id) AS (
+			SELECT task_id FROM tasks WHERE task_id = $1 AND user_id = $2
+			UNION
+			SELECT t.task_id FROM tasks t JOIN subtree s ON t.parent_task_id = s.task_id
+		)
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE task_id IN (SELECT task_id FROM subtree)`
+
+	tasks, err := queryTasks(ctx, tx, query, taskID, userID)
+	if err != nil {
+		return Task{}, err
+	}
+	if len(tasks) == 0 {
+		return Task{}, ErrNoRecord
+	}
+
+	list := siblingList{userID: userID, projectID: tasks[0].ProjectID, parentTaskID: tasks[0].ParentTaskID}
+	nextOrder, err := list.nextOrder(ctx, tx)
+	if err != nil {
+		return Task{}, err
+	}
+
+	idMap := map[uuid.UUID]uuid.UUID{}
+	for _, task := range orderParentsFirst(tasks) {
+		copied := task
+		if task.TaskID == taskID {
+			copied.Order = nextOrder
+		}
+		if err := insertTaskCopy(ctx, tx, copied, userID, idMap, nil, opts); err != nil {
+			return Task{}, err
+		}
+	}
+
+	duplicate, err := getTask(ctx, tx, idMap[taskID])
+	if err != nil {
+		return Task{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Task{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return duplicate, nil
+}
+
+// DuplicateProject deep-copies a project, its sub-projects and all of their tasks with fresh IDs
+// in a single transaction. The copy is private to userID: members are not copied and assignees are cleared.
+func (m *ProjectModel) DuplicateProject(projectID, userID uuid.UUID, opts DuplicateOptions) (Project, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return Project{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	projectQuery := `
+		WITH RECURSIVE subtree(project_id, depth) AS (
+			SELECT project_id, 0 FROM projects WHERE project_id = $1 AND user_id = $2
+			UNION
+			SELECT p.project_id, s.depth + 1 FROM projects p JOIN subtree s ON p.parent_project_id = s.project_id
+		)
+		SELECT ` + projectColumns + `
+		FROM projects
+		JOIN subtree USING (project_id)
+		ORDER BY depth ASC, created_at ASC`
+
+	rows, err := tx.Query(ctx, projectQuery, projectID, userID)
+	if err != nil {
+		return Project{}, fmt.Errorf("unable to query projects: %w", err)
+	}
+	projects, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Project, error) {
+		var p Project
+		err := scanProject(row, &p)
+		return p, err
+	})
+	if err != nil {
+		return Project{}, fmt.Errorf("unable to scan row: %w", err)
+	}
+	if len(projects) == 0 {
+		return Project{}, ErrNoRecord
+	}
+
+	projectMap := map[uuid.UUID]uuid.UUID{}
+	projectIDs := make([]uuid.UUID, 0, len(projects))
+	for _, p := range projects {
+		newID := uuid.New()
+		projectMap[p.ProjectID] = newID
+		projectIDs = append(projectIDs, p.ProjectID)
+
+		name := p.ProjectName
+		parentID := p.ParentProjectID
+		isFavorite := p.IsFavorite
+		order := p.Order
+		if p.ProjectID == projectID {
+			name = "Copy of " + p.ProjectName
+			if opts.ProjectName != nil {
+				name = *opts.ProjectName
+			}
+			// The copy starts out as an ordinary project after the original's siblings.
+			isFavorite = false
+			err := tx.QueryRow(ctx, `
+				SELECT COALESCE(max("order") + 1, 0) FROM projects
+				WHERE user_id = $1 AND parent_project_id IS NOT DISTINCT FROM $2::uuid`,
+				userID, parentID).Scan(&order)
+			if err != nil {
+				return Project{}, fmt.Errorf("unable to find next project order: %w", err)
+			}
+		} else if parentID != nil {
+			mapped := projectMap[*parentID]
+			parentID = &mapped
+		}
+
+		// Copies are never the inbox, so is_inbox is left to its default.
+		_, err := tx.Exec(ctx, `
+			INSERT INTO projects (project_id, user_id, project_name, color, parent_project_id, is_favorite, "order")
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			newID, userID, name, p.Color, parentID, isFavorite, order)
+		if err != nil {
+			return Project{}, fmt.Errorf("unable to copy project: %w", err)
+		}
+	}
+
+	taskQuery := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE project_id = ANY($1) AND user_id = $2`
+
+	tasks, err := queryTasks(ctx, tx, taskQuery, projectIDs, userID)
+	if err != nil {
+		return Project{}, err
+	}
+
+	idMap := map[uuid.UUID]uuid.UUID{}
+	for _, task := range orderParentsFirst(tasks) {
+		task.AssigneeID = nil
+		if err := insertTaskCopy(ctx, tx, task, userID, idMap, projectMap, opts); err != nil {
+			return Project{}, err
+		}
+	}
+
+	var duplicate Project
+	err = scanProject(tx.QueryRow(ctx, `SELECT `+projectColumns+` FROM projects WHERE project_id = $1`, projectMap[projectID]), &duplicate)
+	if err != nil {
+		return Project{}, fmt.Errorf("unable to fetch project: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Project{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return duplicate, nil
+}
+
+// insertTaskCopy inserts a fresh copy of task and records its new ID in idMap.
+// Parent tasks must be copied before their subtasks. A parent that was not copied is dropped,
+// making the copy a top-level task. When projectMap is non-nil the project is remapped as well.
+func insertTaskCopy(ctx context.Context, tx pgx.Tx, task Task, userID uuid.UUID, idMap, projectMap map[uuid.UUID]uuid.UUID, opts DuplicateOptions) error {
+	newID := uuid.New()
+	idMap[task.TaskID] = newID
+
+	var parentID *uuid.UUID
+	if task.ParentTaskID != nil {
+		if mapped, ok := idMap[*task.ParentTaskID]; ok {
+			parentID = &mapped
+		}
+	}
+
+	projectID := task.ProjectID
+	if projectMap != nil && projectID != nil {
+		mapped := projectMap[*projectID]
+		projectID = &mapped
+	}
+
+	dueDate := task.DueDate
+	if dueDate != nil && opts.DueDateOffsetDays != 0 {
+		shifted := dueDate.AddDate(0, 0, opts.DueDateOffsetDays)
+		dueDate = &shifted
+	}
+
+	query := `
+		INSERT INTO tasks (
+			task_id, project_id, user_id, content, description, due_date, due_datetime, priority, parent_task_id, "order", assignee_id, created_by
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $3
+		)`
+
+	_, err := tx.Exec(ctx, query,
+		newID,
+		projectID,
+		userID,
+		task.Content,
+		task.Description,
+		dueDate,
+		task.DueDatetime,
+		task.Priority,
+		parentID,
+		task.Order,
+		task.AssigneeID,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to copy task: %w", err)
+	}
+	return setTaskLabels(ctx, tx, newID, userID, task.LabelIDs)
+}
+
+// orderParentsFirst returns tasks reordered so that every parent precedes its subtasks.
+// Tasks whose parent is not in the slice are treated as roots.
+func orderParentsFirst(tasks []Task) []Task {
+	present := make(map[uuid.UUID]bool, len(tasks))
+	for _, task := range tasks {
+		present[task.TaskID] = true
+	}
+
+	children := map[uuid.UUID][]Task{}
+	var ordered []Task
+	for _, task := range tasks {
+		if task.ParentTaskID != nil && present[*task.ParentTaskID] {
+			children[*task.ParentTaskID] = append(children[*task.ParentTaskID], task)
+		} else {
+			ordered = append(ordered, task)
+		}
+	}
+	for i := 0; i < len(ordered); i++ {
+		ordered = append(ordered, children[ordered[i].TaskID]...)
+	}
+	return ordered
+}
+
+// queryer is satisfied by both *pgxpool.Pool and pgx.Tx.
+type queryer interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// queryTasks runs a query selecting taskColumns and collects the rows.
+func queryTasks(ctx context.Context, db queryer, query string, args ...any) ([]Task, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query tasks: %w", err)
+	}
+	tasks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Task, error) {
+		var task Task
+		err := scanTask(row, &task)
+		return task, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan row: %w", err)
+	}
+	return tasks, nil
+}
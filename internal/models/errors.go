@@ -0,0 +1,38 @@
+package models
+
+import "errors"
+
+var (
+	// ErrNoRecord is returned when a lookup matches no row the user is allowed to see.
+	ErrNoRecord = errors.New("models: no matching record found")
+
+	// ErrTaskBlocked is returned when completing a task that still has open blockers.
+	ErrTaskBlocked = errors.New("models: task is blocked by open tasks")
+
+	// ErrDependencyCycle is returned when a new dependency would make a task (transitively) block itself.
+	ErrDependencyCycle = errors.New("models: dependency would create a cycle")
+
+	// ErrUnknownLabel is returned when a task references a label that doesn't exist or belongs to another user.
+	ErrUnknownLabel = errors.New("models: unknown label")
+
+	// ErrDuplicateLabel is returned when a label name is already used by another of the user's labels, ignoring case.
+	ErrDuplicateLabel = errors.New("models: duplicate label name")
+
+	// ErrNotSiblings is returned when a reorder mixes items that don't share the same parent.
+	ErrNotSiblings = errors.New("models: items are not siblings")
+
+	// ErrInvalidPosition is returned when a task is positioned next to itself or between
+	// two siblings that aren't neighbours.
+	ErrInvalidPosition = errors.New("models: before and after tasks are not adjacent")
+
+	// ErrInvalidReorder is returned when a bulk reorder doesn't cover exactly one complete
+	// sibling list, or lists the same project more than once.
+	ErrInvalidReorder = errors.New("models: reorder must list every sibling exactly once")
+
+	// ErrMoveIntoSubtree is returned when a task would be moved under itself or one of its subtasks.
+	ErrMoveIntoSubtree = errors.New("models: cannot move a task under its own subtree")
+
+	// ErrConflict is returned by MemoryStore when a transaction is abandoned because another
+	// write committed while it ran. The caller may retry it.
+	ErrConflict = errors.New("models: concurrent update, retry the transaction")
+)
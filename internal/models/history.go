@@ -0,0 +1,93 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Task event types recorded in task_history.
+const (
+	TaskEventReassigned = "reassigned"
+)
+
+// TaskEvent is a single entry in a task's history.
+// OldValue and NewValue hold the textual value before and after the change;
+// either may be nil (e.g. when a task is assigned for the first time).
+type TaskEvent struct {
+	EventID   uuid.UUID `json:"event_id"`
+	TaskID    uuid.UUID `json:"task_id"`
+	UserID    uuid.UUID `json:"user_id"` // the user who made the change
+	EventType string    `json:"event_type"`
+	OldValue  *string   `json:"old_value"`
+	NewValue  *string   `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// insertTaskEvent records event as part of an enclosing transaction.
+func insertTaskEvent(ctx context.Context, tx pgx.Tx, event TaskEvent) error {
+	query := `
+		INSERT INTO task_history (task_id, user_id, event_type, old_value, new_value)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := tx.Exec(ctx, query, event.TaskID, event.UserID, event.EventType, event.OldValue, event.NewValue)
+	if err != nil {
+		return fmt.Errorf("unable to record task event: %w", err)
+	}
+	return nil
+}
+
+// GetTaskHistory returns the events of a task owned by or assigned to userID, oldest first.
+func (m *TaskModel) GetTaskHistory(taskID uuid.UUID, userID uuid.UUID) ([]TaskEvent, error) {
+	query := `
+		SELECT h.event_id, h.task_id, h.user_id, h.event_type, h.old_value, h.new_value, h.created_at
+		FROM task_history h
+		JOIN tasks t ON t.task_id = h.task_id
+		WHERE h.task_id = $1 AND (t.user_id = $2 OR t.assignee_id = $2)
+		ORDER BY h.created_at ASC`
+
+	rows, err := m.DB.Query(context.Background(), query, taskID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query task history: %w", err)
+	}
+	defer rows.Close()
+
+	var events []TaskEvent
+	for rows.Next() {
+		var event TaskEvent
+		err := rows.Scan(
+			&event.EventID,
+			&event.TaskID,
+			&event.UserID,
+			&event.EventType,
+			&event.OldValue,
+			&event.NewValue,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan task event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// sameUUID reports whether two nullable UUIDs hold the same value.
+func sameUUID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// uuidString converts a nullable UUID into a nullable string.
+func uuidString(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+	s := id.String()
+	return &s
+}
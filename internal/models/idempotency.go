@@ -0,0 +1,119 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IdempotentResponse is a stored response for an Idempotency-Key. A nil StatusCode means the
+// first request with the key is still being processed. Headers holds the response headers,
+// other than Content-Type, that are replayed with it.
+type IdempotentResponse struct {
+	RequestHash string
+	StatusCode  *int
+	ContentType string
+	Headers     map[string]string
+	Body        []byte
+}
+
+// reserveAttempts bounds how often Reserve retries after the key vanished between its claim and
+// its read, which happens when a concurrent request releases or purges it.
+const reserveAttempts = 3
+
+type IdempotencyModel struct {
+	DB *pgxpool.Pool
+}
+
+// Reserve claims key for userID for a lease. It returns claimed=true when the caller should
+// process the request and later call Save or Release. Otherwise it returns the response stored
+// by the earlier request with the same key.
+func (m *IdempotencyModel) Reserve(userID uuid.UUID, key, requestHash string, lease time.Duration) (bool, IdempotentResponse, error) {
+	ctx := context.Background()
+
+	// An expired key is treated as unused and taken over. That includes a key whose request
+	// never saved a response before its lease ran out, for example because the process died.
+	query := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, expires_at)
+		VALUES ($1, $2, $3, now() + $4::interval)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			content_type = NULL,
+			response_headers = NULL,
+			response_body = NULL,
+			created_at = now(),
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= now()
+		RETURNING true`
+
+	storedQuery := `
+		SELECT request_hash, status_code, COALESCE(content_type, ''), response_headers, response_body
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2`
+
+	for attempt := 1; ; attempt++ {
+		var claimed bool
+		err := m.DB.QueryRow(ctx, query, userID, key, requestHash, lease).Scan(&claimed)
+		if err == nil {
+			return true, IdempotentResponse{}, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return false, IdempotentResponse{}, fmt.Errorf("unable to reserve idempotency key: %w", err)
+		}
+
+		var stored IdempotentResponse
+		err = m.DB.QueryRow(ctx, storedQuery, userID, key).Scan(&stored.RequestHash, &stored.StatusCode, &stored.ContentType, &stored.Headers, &stored.Body)
+		if errors.Is(err, pgx.ErrNoRows) && attempt < reserveAttempts {
+			// The key was released after the claim failed, so it is free to claim again.
+			continue
+		}
+		if err != nil {
+			return false, IdempotentResponse{}, fmt.Errorf("unable to get idempotency key: %w", err)
+		}
+		return false, stored, nil
+	}
+}
+
+// Save stores the response for a key claimed with Reserve so retries can replay it until ttl
+// elapses. The response's RequestHash is ignored.
+func (m *IdempotencyModel) Save(userID uuid.UUID, key string, response IdempotentResponse, ttl time.Duration) error {
+	query := `
+		UPDATE idempotency_keys SET
+			status_code = $3,
+			content_type = $4,
+			response_headers = $5,
+			response_body = $6,
+			expires_at = now() + $7::interval
+		WHERE user_id = $1 AND idempotency_key = $2`
+
+	_, err := m.DB.Exec(context.Background(), query, userID, key, response.StatusCode, response.ContentType, response.Headers, response.Body, ttl)
+	if err != nil {
+		return fmt.Errorf("unable to save idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release forgets a key claimed with Reserve, letting the client retry the request.
+func (m *IdempotencyModel) Release(userID uuid.UUID, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2`
+
+	if _, err := m.DB.Exec(context.Background(), query, userID, key); err != nil {
+		return fmt.Errorf("unable to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes keys whose TTL has passed and returns how many were removed.
+func (m *IdempotencyModel) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := m.DB.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete expired idempotency keys: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
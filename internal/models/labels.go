@@ -2,72 +2,162 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Label struct {
-	LabelID   uuid.UUID `json:"label_id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	LabelID       uuid.UUID `json:"label_id"`
+	UserID        uuid.UUID `json:"user_id"`
+	Name          string    `json:"name"`
+	Color         *string   `json:"color"`
+	IsFavorite    bool      `json:"is_favorite"`
+	Order         int       `json:"order"`
+	TaskCount     int       `json:"task_count"`
+	OpenTaskCount int       `json:"open_task_count"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 type LabelModel struct {
 	DB *pgxpool.Pool
 }
 
-func (m *LabelModel) AddLabel(userID uuid.UUID, name string) (Label, error) {
-	query := `INSERT INTO labels (user_id, name) VALUES ($1, $2) RETURNING label_id, user_id, name, created_at`
-	var label Label
-	err := m.DB.QueryRow(context.Background(), query, userID, name).Scan(
+// MaxLabelNameLength is the longest label name, in characters, that ValidateLabel accepts.
+const MaxLabelNameLength = 64
+
+// labelColumns is the column list scanned by scanLabel. The usage counts are computed, so
+// queries selecting it must not alias the labels table.
+const labelColumns = `label_id, user_id, name, color, is_favorite, "order", ` +
+	`(SELECT count(*) FROM task_labels tl WHERE tl.label_id = labels.label_id) AS task_count, ` +
+	`(SELECT count(*) FROM task_labels tl JOIN tasks t ON t.task_id = tl.task_id WHERE tl.label_id = labels.label_id AND NOT t.is_completed) AS open_task_count, ` +
+	`created_at`
+
+func scanLabel(row pgx.Row, label *Label) error {
+	return row.Scan(
 		&label.LabelID,
 		&label.UserID,
 		&label.Name,
+		&label.Color,
+		&label.IsFavorite,
+		&label.Order,
+		&label.TaskCount,
+		&label.OpenTaskCount,
 		&label.CreatedAt,
 	)
+}
+
+// AddLabel creates a label after the user's existing labels.
+// It returns ErrDuplicateLabel if the user already has a label with the same name, ignoring case.
+func (m *LabelModel) AddLabel(label Label) (Label, error) {
+	query := `
+		INSERT INTO labels (user_id, name, color, is_favorite, "order")
+		VALUES ($1, $2, $3, $4, (SELECT COALESCE(max("order") + 1, 0) FROM labels WHERE user_id = $1))
+		RETURNING ` + labelColumns
+
+	var created Label
+	err := scanLabel(m.DB.QueryRow(context.Background(), query, label.UserID, label.Name, label.Color, label.IsFavorite), &created)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return Label{}, ErrDuplicateLabel
+		}
 		return Label{}, fmt.Errorf("unable to add label: %w", err)
 	}
-	return label, nil
+	return created, nil
 }
 
-func (m *LabelModel) EditLabelByID(labelID, userID uuid.UUID, name string) (Label, error) {
-	query := `UPDATE labels SET name = $3 WHERE label_id = $1 AND user_id = $2 RETURNING label_id, user_id, name, created_at`
-	var label Label
-	err := m.DB.QueryRow(context.Background(), query, labelID, userID, name).Scan(
-		&label.LabelID,
-		&label.UserID,
-		&label.Name,
-		&label.CreatedAt,
-	)
+// EditLabelByID updates a label's name, color and favorite flag. Its order is changed with ReorderLabels.
+func (m *LabelModel) EditLabelByID(label Label) (Label, error) {
+	query := `
+		UPDATE labels SET name = $3, color = $4, is_favorite = $5
+		WHERE label_id = $1 AND user_id = $2
+		RETURNING ` + labelColumns
+
+	var updated Label
+	err := scanLabel(m.DB.QueryRow(context.Background(), query, label.LabelID, label.UserID, label.Name, label.Color, label.IsFavorite), &updated)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Label{}, ErrNoRecord
+		}
+		if isUniqueViolation(err) {
+			return Label{}, ErrDuplicateLabel
+		}
 		return Label{}, fmt.Errorf("unable to edit label: %w", err)
 	}
-	return label, nil
+	return updated, nil
 }
 
+// GetLabelsByUserID returns the user's labels in their user-defined order, with usage counts.
 func (m *LabelModel) GetLabelsByUserID(userID uuid.UUID) ([]Label, error) {
-	query := `SELECT label_id, user_id, name, created_at FROM labels WHERE user_id = $1 ORDER BY name ASC`
+	query := `SELECT ` + labelColumns + ` FROM labels WHERE user_id = $1 ORDER BY "order" ASC, name ASC`
 	rows, err := m.DB.Query(context.Background(), query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get labels: %w", err)
 	}
 	defer rows.Close()
 
-	var labels []Label
+	labels := []Label{}
 	for rows.Next() {
 		var label Label
-		err := rows.Scan(&label.LabelID, &label.UserID, &label.Name, &label.CreatedAt)
-		if err != nil {
+		if err := scanLabel(rows, &label); err != nil {
 			return nil, fmt.Errorf("unable to scan label: %w", err)
 		}
 		labels = append(labels, label)
 	}
-	return labels, nil
+	return labels, rows.Err()
+}
+
+// ReorderLabels moves the given labels to the front of the user's list, in the given order.
+// Labels not listed keep their relative order after them. It returns ErrUnknownLabel if any
+// label doesn't exist or belongs to another user.
+func (m *LabelModel) ReorderLabels(userID uuid.UUID, labelIDs []uuid.UUID) ([]Label, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var owned int
+	err = tx.QueryRow(ctx, `SELECT count(*) FROM labels WHERE label_id = ANY($1) AND user_id = $2`, labelIDs, userID).Scan(&owned)
+	if err != nil {
+		return nil, fmt.Errorf("unable to check labels: %w", err)
+	}
+	if owned != len(labelIDs) {
+		return nil, ErrUnknownLabel
+	}
+
+	query := `
+		WITH input AS (
+			SELECT label_id, position FROM unnest($2::uuid[]) WITH ORDINALITY AS i(label_id, position)
+		), ranked AS (
+			SELECT l.label_id, ROW_NUMBER() OVER (ORDER BY i.position ASC NULLS LAST, l."order" ASC, l.name ASC) - 1 AS position
+			FROM labels l
+			LEFT JOIN input i ON i.label_id = l.label_id
+			WHERE l.user_id = $1
+		)
+		UPDATE labels SET "order" = ranked.position
+		FROM ranked
+		WHERE labels.label_id = ranked.label_id AND labels."order" <> ranked.position`
+
+	if _, err := tx.Exec(ctx, query, userID, labelIDs); err != nil {
+		return nil, fmt.Errorf("unable to reorder labels: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return m.GetLabelsByUserID(userID)
 }
 
 func (m *LabelModel) DeleteLabelByID(labelID, userID uuid.UUID) (int64, error) {
@@ -79,9 +169,287 @@ func (m *LabelModel) DeleteLabelByID(labelID, userID uuid.UUID) (int64, error) {
 	return cmdTag.RowsAffected(), nil
 }
 
-func ValidateLabel(label *Label, v *Validator) {
-	if label.Name == "" {
-		v.AddError("name", "Label name is required")
+// LabelMergeResult reports what MergeLabels changed.
+type LabelMergeResult struct {
+	Label        Label `json:"label"`
+	TasksUpdated int64 `json:"tasks_updated"`
+	LabelsMerged int64 `json:"labels_merged"`
+}
+
+// MergeLabels moves every task labelled with one of sourceIDs onto targetID and deletes the
+// source labels, in a single transaction. It returns ErrUnknownLabel if any label doesn't exist
+// or belongs to another user.
+func (m *LabelModel) MergeLabels(userID, targetID uuid.UUID, sourceIDs []uuid.UUID) (LabelMergeResult, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return LabelMergeResult{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ids := append([]uuid.UUID{targetID}, sourceIDs...)
+	var owned int
+	err = tx.QueryRow(ctx, `SELECT count(*) FROM labels WHERE label_id = ANY($1) AND user_id = $2`, ids, userID).Scan(&owned)
+	if err != nil {
+		return LabelMergeResult{}, fmt.Errorf("unable to check labels: %w", err)
+	}
+	if owned != len(ids) {
+		return LabelMergeResult{}, ErrUnknownLabel
+	}
+
+	// Every task carrying a source label is updated, including those that already had the target.
+	var result LabelMergeResult
+	err = tx.QueryRow(ctx, `SELECT count(DISTINCT task_id) FROM task_labels WHERE label_id = ANY($1)`, sourceIDs).Scan(&result.TasksUpdated)
+	if err != nil {
+		return LabelMergeResult{}, fmt.Errorf("unable to count labelled tasks: %w", err)
+	}
+
+	query := `
+		INSERT INTO task_labels (task_id, label_id)
+		SELECT DISTINCT task_id, $1::uuid FROM task_labels WHERE label_id = ANY($2)
+		ON CONFLICT (task_id, label_id) DO NOTHING`
+
+	tag, err := tx.Exec(ctx, query, targetID, sourceIDs)
+	if err != nil {
+		return LabelMergeResult{}, fmt.Errorf("unable to relabel tasks: %w", err)
+	}
+
+	// Deleting the source labels cascades to their task_labels rows.
+	tag, err = tx.Exec(ctx, `DELETE FROM labels WHERE label_id = ANY($1) AND user_id = $2`, sourceIDs, userID)
+	if err != nil {
+		return LabelMergeResult{}, fmt.Errorf("unable to delete merged labels: %w", err)
+	}
+	result.LabelsMerged = tag.RowsAffected()
+
+	err = scanLabel(tx.QueryRow(ctx, `SELECT `+labelColumns+` FROM labels WHERE label_id = $1`, targetID), &result.Label)
+	if err != nil {
+		return LabelMergeResult{}, fmt.Errorf("unable to fetch label: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return LabelMergeResult{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// BulkLabel selects tasks for BulkLabelTasks and the labels to add to and remove from them.
+// Tasks are matched by TaskIDs and/or the filter fields; unset fields don't filter.
+type BulkLabel struct {
+	TaskIDs        []uuid.UUID `json:"task_ids"`
+	ProjectID      *uuid.UUID  `json:"project_id"`
+	LabelID        *uuid.UUID  `json:"label_id"`
+	IsCompleted    *bool       `json:"is_completed"`
+	AddLabelIDs    []uuid.UUID `json:"add_label_ids"`
+	RemoveLabelIDs []uuid.UUID `json:"remove_label_ids"`
+}
+
+// HasSelector reports whether the request narrows the tasks by ID list or by at least one filter.
+func (b BulkLabel) HasSelector() bool {
+	return len(b.TaskIDs) > 0 || b.ProjectID != nil || b.LabelID != nil || b.IsCompleted != nil
+}
+
+// BulkLabelResult reports, per label, how many tasks gained or lost it.
+type BulkLabelResult struct {
+	Matched int                 `json:"matched"`
+	Added   map[uuid.UUID]int64 `json:"added"`
+	Removed map[uuid.UUID]int64 `json:"removed"`
+}
+
+// BulkLabelTasks adds and removes labels on every one of the user's tasks matching the request,
+// in a single transaction. It returns ErrUnknownLabel if any label doesn't exist or belongs to another user.
+func (m *TaskModel) BulkLabelTasks(userID uuid.UUID, req BulkLabel) (BulkLabelResult, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return BulkLabelResult{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	labelIDs := append(append([]uuid.UUID{}, req.AddLabelIDs...), req.RemoveLabelIDs...)
+	var owned int
+	err = tx.QueryRow(ctx, `SELECT count(DISTINCT label_id) FROM labels WHERE label_id = ANY($1) AND user_id = $2`, labelIDs, userID).Scan(&owned)
+	if err != nil {
+		return BulkLabelResult{}, fmt.Errorf("unable to check labels: %w", err)
 	}
-	// Add more validation as needed (e.g., length, allowed characters)
+	if owned != len(uniqueUUIDs(labelIDs)) {
+		return BulkLabelResult{}, ErrUnknownLabel
+	}
+
+	query := `
+		SELECT task_id FROM tasks
+		WHERE user_id = $1
+			AND ($2::uuid[] IS NULL OR task_id = ANY($2))
+			AND ($3::uuid IS NULL OR project_id = $3)
+			AND ($4::uuid IS NULL OR EXISTS (SELECT 1 FROM task_labels tl WHERE tl.task_id = tasks.task_id AND tl.label_id = $4))
+			AND ($5::boolean IS NULL OR is_completed = $5)
+		FOR UPDATE`
+
+	// An empty ID list means "no ID filter", not "no tasks".
+	var filterIDs []uuid.UUID
+	if len(req.TaskIDs) > 0 {
+		filterIDs = req.TaskIDs
+	}
+
+	rows, err := tx.Query(ctx, query, userID, filterIDs, req.ProjectID, req.LabelID, req.IsCompleted)
+	if err != nil {
+		return BulkLabelResult{}, fmt.Errorf("unable to query tasks: %w", err)
+	}
+	taskIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return BulkLabelResult{}, fmt.Errorf("unable to scan task: %w", err)
+	}
+
+	result := BulkLabelResult{Matched: len(taskIDs), Added: map[uuid.UUID]int64{}, Removed: map[uuid.UUID]int64{}}
+	for _, labelID := range uniqueUUIDs(req.AddLabelIDs) {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO task_labels (task_id, label_id)
+			SELECT unnest($1::uuid[]), $2
+			ON CONFLICT (task_id, label_id) DO NOTHING`, taskIDs, labelID)
+		if err != nil {
+			return BulkLabelResult{}, fmt.Errorf("unable to add label: %w", err)
+		}
+		result.Added[labelID] = tag.RowsAffected()
+	}
+	for _, labelID := range uniqueUUIDs(req.RemoveLabelIDs) {
+		tag, err := tx.Exec(ctx, `DELETE FROM task_labels WHERE task_id = ANY($1) AND label_id = $2`, taskIDs, labelID)
+		if err != nil {
+			return BulkLabelResult{}, fmt.Errorf("unable to remove label: %w", err)
+		}
+		result.Removed[labelID] = tag.RowsAffected()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return BulkLabelResult{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// uniqueUUIDs returns ids without repeats, keeping the first occurrence of each.
+func uniqueUUIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := map[uuid.UUID]bool{}
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// setTaskLabels replaces the labels attached to a task with labelIDs as part of an enclosing
+// transaction. It returns ErrUnknownLabel if any label doesn't exist or belongs to another user.
+func setTaskLabels(ctx context.Context, tx pgx.Tx, taskID, userID uuid.UUID, labelIDs []uuid.UUID) error {
+	ids := uniqueUUIDs(labelIDs)
+
+	var owned int
+	err := tx.QueryRow(ctx, `SELECT count(*) FROM labels WHERE label_id = ANY($1) AND user_id = $2`, ids, userID).Scan(&owned)
+	if err != nil {
+		return fmt.Errorf("unable to check labels: %w", err)
+	}
+	if owned != len(ids) {
+		return ErrUnknownLabel
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM task_labels WHERE task_id = $1 AND label_id <> ALL($2)`, taskID, ids); err != nil {
+		return fmt.Errorf("unable to remove task labels: %w", err)
+	}
+
+	query := `
+		INSERT INTO task_labels (task_id, label_id)
+		SELECT $1, unnest($2::uuid[])
+		ON CONFLICT (task_id, label_id) DO NOTHING`
+
+	if _, err := tx.Exec(ctx, query, taskID, ids); err != nil {
+		return fmt.Errorf("unable to add task labels: %w", err)
+	}
+	return nil
+}
+
+// ensureLabels returns the IDs of the user's labels with the given names, creating any that are missing.
+// Names match existing labels ignoring case, so the returned map is keyed by the lower-cased name.
+func ensureLabels(ctx context.Context, tx pgx.Tx, userID uuid.UUID, names []string) (map[string]uuid.UUID, error) {
+	ids := map[string]uuid.UUID{}
+	if len(names) == 0 {
+		return ids, nil
+	}
+
+	// New labels are appended after the user's existing ones, in the order the names are given.
+	query := `
+		INSERT INTO labels (user_id, name, "order")
+		SELECT $1::uuid, n.name,
+			(SELECT COALESCE(max("order") + 1, 0) FROM labels WHERE user_id = $1) + row_number() OVER (ORDER BY n.idx) - 1
+		FROM (
+			SELECT DISTINCT ON (lower(name)) name, idx
+			FROM unnest($2::text[]) WITH ORDINALITY AS u(name, idx)
+			WHERE NOT EXISTS (SELECT 1 FROM labels l WHERE l.user_id = $1 AND lower(l.name) = lower(u.name))
+			ORDER BY lower(name), idx
+		) n
+		ON CONFLICT DO NOTHING`
+
+	if _, err := tx.Exec(ctx, query, userID, names); err != nil {
+		return nil, fmt.Errorf("unable to create labels: %w", err)
+	}
+
+	query = `SELECT label_id, lower(name) FROM labels WHERE user_id = $1 AND lower(name) IN (SELECT lower(n) FROM unnest($2::text[]) AS n)`
+	rows, err := tx.Query(ctx, query, userID, names)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get labels: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("unable to scan label: %w", err)
+		}
+		ids[name] = id
+	}
+	return ids, rows.Err()
+}
+
+// ValidateLabel checks a label's name and color. existing holds the user's current labels and
+// is used to reject names that differ from another label's only by case.
+func ValidateLabel(label *Label, existing []Label, v *Validator) {
+	name := label.Name
+	validateLabelName(name, "name", v)
+
+	for _, other := range existing {
+		if other.LabelID != label.LabelID && strings.EqualFold(other.Name, name) {
+			v.AddError("name", "A label with this name already exists")
+			break
+		}
+	}
+
+	if label.Color != nil {
+		v.Check(labelColorRX.MatchString(*label.Color), "color", "Color must be a hex value like #1a2b3c")
+	}
+}
+
+var labelColorRX = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// validateLabelName checks the rules every label name follows, reporting failures under field.
+func validateLabelName(name, field string, v *Validator) {
+	v.Check(name != "", field, "Label name is required")
+	v.Check(utf8.RuneCountInString(name) <= MaxLabelNameLength, field, fmt.Sprintf("Label name must be at most %d characters", MaxLabelNameLength))
+	v.Check(strings.TrimSpace(name) == name, field, "Label name must not start or end with spaces")
+	v.Check(validLabelName(name), field, "Label name may only contain letters, digits, spaces, '-', '_' and '.'")
+}
+
+func validLabelName(name string) bool {
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && r != '-' && r != '_' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
@@ -0,0 +1,256 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore keeps tasks, projects and labels in memory with the semantics of the Postgres
+// models: ownership scoping, cascading deletes and sibling ordering. It is safe for concurrent
+// use. Nothing is persisted, so it is meant for tests and local development.
+type MemoryStore struct {
+	mu    sync.Mutex
+	state *memState
+	// version counts committed writes so Atomic can tell whether another write got in first.
+	version uint64
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{state: newMemState()}
+}
+
+// Tasks returns the store's TaskStore.
+func (s *MemoryStore) Tasks() TaskStore { return &memoryTasks{db: s} }
+
+// Projects returns the store's ProjectStore.
+func (s *MemoryStore) Projects() ProjectStore { return &memoryProjects{db: s} }
+
+// Labels returns the store's LabelStore.
+func (s *MemoryStore) Labels() LabelStore { return &memoryLabels{db: s} }
+
+var (
+	_ TaskStore    = (*memoryTasks)(nil)
+	_ ProjectStore = (*memoryProjects)(nil)
+	_ LabelStore   = (*memoryLabels)(nil)
+)
+
+// memDB runs reads and writes against a memState. A write only takes effect if fn returns nil,
+// which gives every method the all-or-nothing behaviour of its Postgres transaction.
+type memDB interface {
+	read(fn func(st *memState) error) error
+	write(fn func(st *memState) error) error
+	atomic(fn func(tx *memTx) error) error
+}
+
+func (s *MemoryStore) read(fn func(st *memState) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s.state)
+}
+
+func (s *MemoryStore) write(fn func(st *memState) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.state.clone()
+	if err := fn(next); err != nil {
+		return err
+	}
+	s.state = next
+	s.version++
+	return nil
+}
+
+// atomic runs fn on a private copy of the state without holding the lock, so fn may use the
+// store's other views. The copy replaces the state only if no other write committed meanwhile;
+// otherwise ErrConflict is returned and the changes are dropped.
+func (s *MemoryStore) atomic(fn func(tx *memTx) error) error {
+	s.mu.Lock()
+	tx := &memTx{state: s.state.clone()}
+	version := s.version
+	s.mu.Unlock()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.version != version {
+		return ErrConflict
+	}
+	s.state = tx.state
+	s.version++
+	return nil
+}
+
+// memTx is the state of one Atomic call. It is used by a single goroutine and needs no lock;
+// nested calls work on a further copy that replaces the parent's only on success.
+type memTx struct {
+	state *memState
+}
+
+func (tx *memTx) read(fn func(st *memState) error) error {
+	return fn(tx.state)
+}
+
+func (tx *memTx) write(fn func(st *memState) error) error {
+	next := tx.state.clone()
+	if err := fn(next); err != nil {
+		return err
+	}
+	tx.state = next
+	return nil
+}
+
+func (tx *memTx) atomic(fn func(tx *memTx) error) error {
+	return tx.write(func(st *memState) error {
+		inner := &memTx{state: st}
+		if err := fn(inner); err != nil {
+			return err
+		}
+		*st = *inner.state
+		return nil
+	})
+}
+
+// memState holds the rows of every table the memory stores cover. Rows are stored by value and
+// replaced rather than modified in place, so clone only has to copy the maps.
+type memState struct {
+	// seq numbers rows in creation order; it breaks created_at ties deterministically.
+	seq int64
+
+	tasks      map[uuid.UUID]memTask
+	taskLabels map[uuid.UUID]map[uuid.UUID]bool // task_id -> label_ids
+	deps       map[memDepKey]TaskDependency
+	history    []TaskEvent
+	archived   map[uuid.UUID]CompletedTask
+
+	projects map[uuid.UUID]memProject
+	members  map[memMemberKey]ProjectMember
+
+	labels map[uuid.UUID]memLabel
+}
+
+// memTask is a stored task. Only the stored columns are set; Labels, LabelIDs, Blocked and the
+// subtask counts are computed by taskView.
+type memTask struct {
+	Task
+	seq int64
+}
+
+type memProject struct {
+	Project
+	seq int64
+}
+
+// memLabel is a stored label. The usage counts are computed by labelView.
+type memLabel struct {
+	Label
+	seq int64
+}
+
+type memDepKey struct {
+	taskID, blockedByID uuid.UUID
+}
+
+type memMemberKey struct {
+	projectID, userID uuid.UUID
+}
+
+func newMemState() *memState {
+	return &memState{
+		tasks:      map[uuid.UUID]memTask{},
+		taskLabels: map[uuid.UUID]map[uuid.UUID]bool{},
+		deps:       map[memDepKey]TaskDependency{},
+		archived:   map[uuid.UUID]CompletedTask{},
+		projects:   map[uuid.UUID]memProject{},
+		members:    map[memMemberKey]ProjectMember{},
+		labels:     map[uuid.UUID]memLabel{},
+	}
+}
+
+func (st *memState) clone() *memState {
+	c := &memState{
+		seq:        st.seq,
+		tasks:      make(map[uuid.UUID]memTask, len(st.tasks)),
+		taskLabels: make(map[uuid.UUID]map[uuid.UUID]bool, len(st.taskLabels)),
+		deps:       make(map[memDepKey]TaskDependency, len(st.deps)),
+		history:    append([]TaskEvent(nil), st.history...),
+		archived:   make(map[uuid.UUID]CompletedTask, len(st.archived)),
+		projects:   make(map[uuid.UUID]memProject, len(st.projects)),
+		members:    make(map[memMemberKey]ProjectMember, len(st.members)),
+		labels:     make(map[uuid.UUID]memLabel, len(st.labels)),
+	}
+	for k, v := range st.tasks {
+		c.tasks[k] = v
+	}
+	for k, v := range st.taskLabels {
+		labels := make(map[uuid.UUID]bool, len(v))
+		for id := range v {
+			labels[id] = true
+		}
+		c.taskLabels[k] = labels
+	}
+	for k, v := range st.deps {
+		c.deps[k] = v
+	}
+	for k, v := range st.archived {
+		c.archived[k] = v
+	}
+	for k, v := range st.projects {
+		c.projects[k] = v
+	}
+	for k, v := range st.members {
+		c.members[k] = v
+	}
+	for k, v := range st.labels {
+		c.labels[k] = v
+	}
+	return c
+}
+
+// nextSeq returns the next creation sequence number.
+func (st *memState) nextSeq() int64 {
+	st.seq++
+	return st.seq
+}
+
+// memNow returns the current time at the precision Postgres stores timestamps with.
+func memNow() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// memRowMissing is the error the memory stores return where Postgres would fail a foreign key
+// or find no row without the model mapping it to a sentinel error.
+func memRowMissing(table string, id uuid.UUID) error {
+	return fmt.Errorf("%s %s does not exist", table, id)
+}
+
+// lessUUID orders UUIDs the way Postgres does, byte by byte.
+func lessUUID(a, b uuid.UUID) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+// sortTasks sorts tasks in display order: by order, then creation.
+func sortTasks(tasks []memTask) {
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].Order != tasks[j].Order {
+			return tasks[i].Order < tasks[j].Order
+		}
+		return createdBefore(tasks[i].CreatedAt, tasks[i].seq, tasks[j].CreatedAt, tasks[j].seq)
+	})
+}
+
+// createdBefore compares two rows by created_at, falling back to creation sequence.
+func createdBefore(a time.Time, aSeq int64, b time.Time, bSeq int64) bool {
+	if !a.Equal(b) {
+		return a.Before(b)
+	}
+	return aSeq < bSeq
+}
@@ -0,0 +1,255 @@
+package models
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// memoryLabels is MemoryStore's LabelStore.
+type memoryLabels struct {
+	db memDB
+}
+
+func (m *memoryLabels) AddLabel(label Label) (Label, error) {
+	var created Label
+	err := m.db.write(func(st *memState) error {
+		if st.labelNameTaken(label.UserID, label.Name, uuid.Nil) {
+			return ErrDuplicateLabel
+		}
+
+		order := 0
+		for _, l := range st.labels {
+			if l.UserID == label.UserID && l.Order+1 > order {
+				order = l.Order + 1
+			}
+		}
+		stored := Label{
+			LabelID:    uuid.New(),
+			UserID:     label.UserID,
+			Name:       label.Name,
+			Color:      label.Color,
+			IsFavorite: label.IsFavorite,
+			Order:      order,
+			CreatedAt:  memNow(),
+		}
+		st.labels[stored.LabelID] = memLabel{Label: stored, seq: st.nextSeq()}
+		created = st.labelView(stored.LabelID)
+		return nil
+	})
+	if err != nil {
+		return Label{}, err
+	}
+	return created, nil
+}
+
+func (m *memoryLabels) EditLabelByID(label Label) (Label, error) {
+	var updated Label
+	err := m.db.write(func(st *memState) error {
+		stored, ok := st.labels[label.LabelID]
+		if !ok || stored.UserID != label.UserID {
+			return ErrNoRecord
+		}
+		if st.labelNameTaken(label.UserID, label.Name, label.LabelID) {
+			return ErrDuplicateLabel
+		}
+		stored.Name = label.Name
+		stored.Color = label.Color
+		stored.IsFavorite = label.IsFavorite
+		st.labels[label.LabelID] = stored
+		updated = st.labelView(label.LabelID)
+		return nil
+	})
+	if err != nil {
+		return Label{}, err
+	}
+	return updated, nil
+}
+
+func (m *memoryLabels) GetLabelsByUserID(userID uuid.UUID) ([]Label, error) {
+	labels := []Label{}
+	err := m.db.read(func(st *memState) error {
+		for _, l := range st.userLabels(userID) {
+			labels = append(labels, st.labelView(l.LabelID))
+		}
+		return nil
+	})
+	return labels, err
+}
+
+func (m *memoryLabels) ReorderLabels(userID uuid.UUID, labelIDs []uuid.UUID) ([]Label, error) {
+	err := m.db.write(func(st *memState) error {
+		// Repeated IDs fail the check, as in LabelModel.ReorderLabels.
+		owned := 0
+		for _, id := range uniqueUUIDs(labelIDs) {
+			if l, ok := st.labels[id]; ok && l.UserID == userID {
+				owned++
+			}
+		}
+		if owned != len(labelIDs) {
+			return ErrUnknownLabel
+		}
+
+		position := map[uuid.UUID]int{}
+		for i, id := range labelIDs {
+			position[id] = i
+		}
+		labels := st.userLabels(userID)
+		sort.SliceStable(labels, func(i, j int) bool {
+			pi, iListed := position[labels[i].LabelID]
+			pj, jListed := position[labels[j].LabelID]
+			if iListed != jListed {
+				return iListed
+			}
+			return iListed && pi < pj
+		})
+		for i, l := range labels {
+			l.Order = i
+			st.labels[l.LabelID] = l
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.GetLabelsByUserID(userID)
+}
+
+func (m *memoryLabels) DeleteLabelByID(labelID, userID uuid.UUID) (int64, error) {
+	var deleted int64
+	err := m.db.write(func(st *memState) error {
+		if l, ok := st.labels[labelID]; ok && l.UserID == userID {
+			st.deleteLabel(labelID)
+			deleted = 1
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+func (m *memoryLabels) MergeLabels(userID, targetID uuid.UUID, sourceIDs []uuid.UUID) (LabelMergeResult, error) {
+	var result LabelMergeResult
+	err := m.db.write(func(st *memState) error {
+		ids := append([]uuid.UUID{targetID}, sourceIDs...)
+		owned := 0
+		for _, id := range uniqueUUIDs(ids) {
+			if l, ok := st.labels[id]; ok && l.UserID == userID {
+				owned++
+			}
+		}
+		if owned != len(ids) {
+			return ErrUnknownLabel
+		}
+
+		sources := map[uuid.UUID]bool{}
+		for _, id := range sourceIDs {
+			sources[id] = true
+		}
+		for taskID, labels := range st.taskLabels {
+			for labelID := range labels {
+				if sources[labelID] {
+					st.attachLabel(taskID, targetID)
+					result.TasksUpdated++
+					break
+				}
+			}
+		}
+
+		// Deleting the source labels cascades to their task links.
+		for _, id := range sourceIDs {
+			st.deleteLabel(id)
+			result.LabelsMerged++
+		}
+
+		result.Label = st.labelView(targetID)
+		return nil
+	})
+	if err != nil {
+		return LabelMergeResult{}, err
+	}
+	return result, nil
+}
+
+// labelView returns a stored label with its usage counts filled in.
+func (st *memState) labelView(labelID uuid.UUID) Label {
+	label := st.labels[labelID].Label
+	for taskID, labels := range st.taskLabels {
+		if labels[labelID] {
+			label.TaskCount++
+			if !st.tasks[taskID].IsCompleted {
+				label.OpenTaskCount++
+			}
+		}
+	}
+	return label
+}
+
+// userLabels returns the user's stored labels in their user-defined order.
+func (st *memState) userLabels(userID uuid.UUID) []memLabel {
+	var labels []memLabel
+	for _, l := range st.labels {
+		if l.UserID == userID {
+			labels = append(labels, l)
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].Order != labels[j].Order {
+			return labels[i].Order < labels[j].Order
+		}
+		return labels[i].Name < labels[j].Name
+	})
+	return labels
+}
+
+// labelsOf returns the labels attached to a task, sorted by name.
+func (st *memState) labelsOf(taskID uuid.UUID) []Label {
+	var labels []Label
+	for id := range st.taskLabels[taskID] {
+		labels = append(labels, st.labels[id].Label)
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels
+}
+
+// labelNameTaken enforces the case-insensitive unique index on label names. except is the
+// label being renamed.
+func (st *memState) labelNameTaken(userID uuid.UUID, name string, except uuid.UUID) bool {
+	for _, l := range st.labels {
+		if l.UserID == userID && l.LabelID != except && strings.EqualFold(l.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// setTaskLabels mirrors the package function of the same name.
+func (st *memState) setTaskLabels(taskID, userID uuid.UUID, labelIDs []uuid.UUID) error {
+	ids := uniqueUUIDs(labelIDs)
+	for _, id := range ids {
+		if l, ok := st.labels[id]; !ok || l.UserID != userID {
+			return ErrUnknownLabel
+		}
+	}
+	delete(st.taskLabels, taskID)
+	for _, id := range ids {
+		st.attachLabel(taskID, id)
+	}
+	return nil
+}
+
+// attachLabel links a label to a task.
+func (st *memState) attachLabel(taskID, labelID uuid.UUID) {
+	if st.taskLabels[taskID] == nil {
+		st.taskLabels[taskID] = map[uuid.UUID]bool{}
+	}
+	st.taskLabels[taskID][labelID] = true
+}
+
+// deleteLabel deletes a label together with its task links.
+func (st *memState) deleteLabel(labelID uuid.UUID) {
+	delete(st.labels, labelID)
+	for _, labels := range st.taskLabels {
+		delete(labels, labelID)
+	}
+}
@@ -0,0 +1,378 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// memoryProjects is MemoryStore's ProjectStore.
+type memoryProjects struct {
+	db memDB
+}
+
+func (m *memoryProjects) AddProject(project Project) (Project, error) {
+	var created Project
+	err := m.db.write(func(st *memState) error {
+		if project.ParentProjectID != nil {
+			if _, ok := st.projects[*project.ParentProjectID]; !ok {
+				return fmt.Errorf("unable to execute query: %v", memRowMissing("project", *project.ParentProjectID))
+			}
+		}
+		created = Project{
+			ProjectID:       uuid.New(),
+			UserID:          project.UserID,
+			ProjectName:     project.ProjectName,
+			Color:           project.Color,
+			IsInbox:         project.IsInbox,
+			ParentProjectID: project.ParentProjectID,
+			IsFavorite:      project.IsFavorite,
+			Order:           st.nextProjectOrder(project.UserID, project.ParentProjectID),
+			CreatedAt:       memNow(),
+		}
+		st.projects[created.ProjectID] = memProject{Project: created, seq: st.nextSeq()}
+		return nil
+	})
+	if err != nil {
+		return Project{}, err
+	}
+	return created, nil
+}
+
+func (m *memoryProjects) EditProjectByID(project Project) (Project, error) {
+	var updated Project
+	err := m.db.write(func(st *memState) error {
+		stored, ok := st.projects[project.ProjectID]
+		if !ok || stored.UserID != project.UserID {
+			// ProjectModel reports a missing project as a plain error, not ErrNoRecord.
+			return fmt.Errorf("unable to execute query: %v", memRowMissing("project", project.ProjectID))
+		}
+		if project.ParentProjectID != nil {
+			if _, ok := st.projects[*project.ParentProjectID]; !ok {
+				return fmt.Errorf("unable to execute query: %v", memRowMissing("project", *project.ParentProjectID))
+			}
+		}
+		stored.ProjectName = project.ProjectName
+		stored.Color = project.Color
+		stored.IsInbox = project.IsInbox
+		stored.ParentProjectID = project.ParentProjectID
+		stored.IsFavorite = project.IsFavorite
+		st.projects[project.ProjectID] = stored
+		updated = stored.Project
+		return nil
+	})
+	if err != nil {
+		return Project{}, err
+	}
+	return updated, nil
+}
+
+func (m *memoryProjects) GetProjectsByUserID(userID uuid.UUID, includeArchived bool) ([]Project, error) {
+	var projects []Project
+	err := m.db.read(func(st *memState) error {
+		var stored []memProject
+		for _, p := range st.projects {
+			if p.UserID == userID && (includeArchived || !p.IsArchived) {
+				stored = append(stored, p)
+			}
+		}
+		sortProjects(stored)
+		for _, p := range stored {
+			projects = append(projects, p.Project)
+		}
+		return nil
+	})
+	return projects, err
+}
+
+func (m *memoryProjects) ArchiveProject(projectID, userID uuid.UUID, archived bool) ([]Project, error) {
+	var projects []Project
+	err := m.db.write(func(st *memState) error {
+		root, ok := st.projects[projectID]
+		if !ok || root.UserID != userID {
+			return ErrNoRecord
+		}
+		for _, id := range st.projectSubtree(projectID) {
+			p := st.projects[id]
+			p.IsArchived = archived
+			st.projects[id] = p
+			projects = append(projects, p.Project)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (m *memoryProjects) BulkUpdateProjectOrder(userID uuid.UUID, updates []ProjectOrderUpdate) error {
+	return m.db.write(func(st *memState) error {
+		ids := make([]uuid.UUID, len(updates))
+		for i, u := range updates {
+			ids[i] = u.ProjectID
+		}
+		if len(uniqueUUIDs(ids)) != len(ids) {
+			return ErrInvalidReorder
+		}
+
+		parents := map[uuid.UUID]bool{}
+		for _, id := range ids {
+			p, ok := st.projects[id]
+			if !ok || p.UserID != userID {
+				return ErrNoRecord
+			}
+			parent := uuid.Nil
+			if p.ParentProjectID != nil {
+				parent = *p.ParentProjectID
+			}
+			parents[parent] = true
+		}
+		if len(parents) > 1 {
+			return ErrNotSiblings
+		}
+
+		for _, u := range updates {
+			p := st.projects[u.ProjectID]
+			p.Order = u.Order
+			st.projects[u.ProjectID] = p
+		}
+		return nil
+	})
+}
+
+func (m *memoryProjects) DeleteProjectByID(projectID, userID uuid.UUID) (int64, error) {
+	var deleted int64
+	err := m.db.write(func(st *memState) error {
+		p, ok := st.projects[projectID]
+		if !ok || p.UserID != userID {
+			return nil
+		}
+		// Sub-projects are not deleted with their parent; the foreign key refuses the delete.
+		for _, child := range st.projects {
+			if child.ParentProjectID != nil && *child.ParentProjectID == projectID {
+				return fmt.Errorf("unable to delete project: project %s has sub-projects", projectID)
+			}
+		}
+
+		delete(st.projects, projectID)
+		for k := range st.members {
+			if k.projectID == projectID {
+				delete(st.members, k)
+			}
+		}
+		var tasks []uuid.UUID
+		for _, t := range st.tasks {
+			if t.ProjectID != nil && *t.ProjectID == projectID {
+				tasks = append(tasks, t.TaskID)
+			}
+		}
+		st.deleteTasks(tasks)
+		deleted = 1
+		return nil
+	})
+	return deleted, err
+}
+
+func (m *memoryProjects) DuplicateProject(projectID, userID uuid.UUID, opts DuplicateOptions) (Project, error) {
+	var duplicate Project
+	err := m.db.write(func(st *memState) error {
+		root, ok := st.projects[projectID]
+		if !ok || root.UserID != userID {
+			return ErrNoRecord
+		}
+
+		projectMap := map[uuid.UUID]uuid.UUID{}
+		for _, id := range st.projectSubtree(projectID) {
+			p := st.projects[id].Project
+			newID := uuid.New()
+			projectMap[p.ProjectID] = newID
+
+			isInbox := false
+			copied := Project{
+				ProjectID:       newID,
+				UserID:          userID,
+				ProjectName:     p.ProjectName,
+				Color:           p.Color,
+				IsInbox:         &isInbox,
+				ParentProjectID: p.ParentProjectID,
+				IsFavorite:      p.IsFavorite,
+				Order:           p.Order,
+				CreatedAt:       memNow(),
+			}
+			if p.ProjectID == projectID {
+				copied.ProjectName = "Copy of " + p.ProjectName
+				if opts.ProjectName != nil {
+					copied.ProjectName = *opts.ProjectName
+				}
+				// The copy starts out as an ordinary project after the original's siblings.
+				copied.IsFavorite = false
+				copied.Order = st.nextProjectOrder(userID, p.ParentProjectID)
+			} else if p.ParentProjectID != nil {
+				mapped := projectMap[*p.ParentProjectID]
+				copied.ParentProjectID = &mapped
+			}
+			st.projects[newID] = memProject{Project: copied, seq: st.nextSeq()}
+		}
+
+		var taskIDs []uuid.UUID
+		for _, t := range st.tasks {
+			if t.ProjectID == nil || t.UserID != userID {
+				continue
+			}
+			if _, ok := projectMap[*t.ProjectID]; ok {
+				taskIDs = append(taskIDs, t.TaskID)
+			}
+		}
+
+		idMap := map[uuid.UUID]uuid.UUID{}
+		for _, task := range orderParentsFirst(st.taskViews(taskIDs)) {
+			task.AssigneeID = nil
+			if err := st.insertTaskCopy(task, userID, idMap, projectMap, opts); err != nil {
+				return err
+			}
+		}
+
+		duplicate = st.projects[projectMap[projectID]].Project
+		return nil
+	})
+	if err != nil {
+		return Project{}, err
+	}
+	return duplicate, nil
+}
+
+func (m *memoryProjects) AddProjectMember(projectID, ownerID, memberID uuid.UUID) (ProjectMember, error) {
+	var member ProjectMember
+	err := m.db.write(func(st *memState) error {
+		if p, ok := st.projects[projectID]; !ok || p.UserID != ownerID {
+			return ErrNoRecord
+		}
+		key := memMemberKey{projectID: projectID, userID: memberID}
+		existing, ok := st.members[key]
+		if ok {
+			member = existing
+			return nil
+		}
+		member = ProjectMember{ProjectID: projectID, UserID: memberID, CreatedAt: memNow()}
+		st.members[key] = member
+		return nil
+	})
+	if err != nil {
+		return ProjectMember{}, err
+	}
+	return member, nil
+}
+
+func (m *memoryProjects) GetProjectMembers(projectID, userID uuid.UUID) ([]ProjectMember, error) {
+	var members []ProjectMember
+	err := m.db.read(func(st *memState) error {
+		if !st.isProjectMember(projectID, userID) {
+			return nil
+		}
+		for k, member := range st.members {
+			if k.projectID == projectID {
+				members = append(members, member)
+			}
+		}
+		sort.Slice(members, func(i, j int) bool {
+			if !members[i].CreatedAt.Equal(members[j].CreatedAt) {
+				return members[i].CreatedAt.Before(members[j].CreatedAt)
+			}
+			return lessUUID(members[i].UserID, members[j].UserID)
+		})
+		return nil
+	})
+	return members, err
+}
+
+func (m *memoryProjects) RemoveProjectMember(projectID, ownerID, memberID uuid.UUID) (int64, error) {
+	var removed int64
+	err := m.db.write(func(st *memState) error {
+		if p, ok := st.projects[projectID]; !ok || p.UserID != ownerID {
+			return nil
+		}
+		key := memMemberKey{projectID: projectID, userID: memberID}
+		if _, ok := st.members[key]; ok {
+			delete(st.members, key)
+			removed = 1
+		}
+		return nil
+	})
+	return removed, err
+}
+
+func (m *memoryProjects) IsProjectMember(projectID, userID uuid.UUID) (bool, error) {
+	var ok bool
+	err := m.db.read(func(st *memState) error {
+		ok = st.isProjectMember(projectID, userID)
+		return nil
+	})
+	return ok, err
+}
+
+// isProjectMember reports whether userID owns the project or has been added as a member.
+func (st *memState) isProjectMember(projectID, userID uuid.UUID) bool {
+	if p, ok := st.projects[projectID]; ok && p.UserID == userID {
+		return true
+	}
+	_, ok := st.members[memMemberKey{projectID: projectID, userID: userID}]
+	return ok
+}
+
+// nextProjectOrder returns the order that appends a project after its siblings.
+func (st *memState) nextProjectOrder(userID uuid.UUID, parentID *uuid.UUID) int {
+	order := 0
+	for _, p := range st.projects {
+		if p.UserID == userID && sameUUID(p.ParentProjectID, parentID) && p.Order+1 > order {
+			order = p.Order + 1
+		}
+	}
+	return order
+}
+
+// projectSubtree returns projectID and every project below it, shallowest first and in
+// creation order within a level.
+func (st *memState) projectSubtree(projectID uuid.UUID) []uuid.UUID {
+	children := map[uuid.UUID][]memProject{}
+	for _, p := range st.projects {
+		if p.ParentProjectID != nil {
+			children[*p.ParentProjectID] = append(children[*p.ParentProjectID], p)
+		}
+	}
+
+	seen := map[uuid.UUID]bool{projectID: true}
+	level := []uuid.UUID{projectID}
+	var ids []uuid.UUID
+	for len(level) > 0 {
+		ids = append(ids, level...)
+		var next []memProject
+		for _, id := range level {
+			for _, child := range children[id] {
+				if !seen[child.ProjectID] {
+					seen[child.ProjectID] = true
+					next = append(next, child)
+				}
+			}
+		}
+		sort.Slice(next, func(i, j int) bool {
+			return createdBefore(next[i].CreatedAt, next[i].seq, next[j].CreatedAt, next[j].seq)
+		})
+		level = nil
+		for _, p := range next {
+			level = append(level, p.ProjectID)
+		}
+	}
+	return ids
+}
+
+// sortProjects sorts projects by their manual order, then creation.
+func sortProjects(projects []memProject) {
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Order != projects[j].Order {
+			return projects[i].Order < projects[j].Order
+		}
+		return createdBefore(projects[i].CreatedAt, projects[i].seq, projects[j].CreatedAt, projects[j].seq)
+	})
+}
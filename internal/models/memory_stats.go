@@ -0,0 +1,64 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// GetStats mirrors TaskModel.GetStats, bucketing days in settings' timezone.
+func (m *memoryTasks) GetStats(userID uuid.UUID, settings UserSettings, r StatsRange) (Stats, error) {
+	var tasks []statsTask
+	var labelled []statsLabelUse
+	err := m.db.read(func(st *memState) error {
+		userLabels := map[string]memLabel{} // lower-cased name -> label
+		for _, l := range st.labels {
+			if l.UserID == userID {
+				userLabels[strings.ToLower(l.Name)] = l
+			}
+		}
+
+		for _, t := range st.tasks {
+			if !t.visibleTo(userID) {
+				continue
+			}
+			tasks = append(tasks, statsTask{t.ProjectID, st.projectName(t.ProjectID), t.IsCompleted, t.CompletedAt, t.CreatedAt, t.DueDate, t.DueDatetime})
+			for labelID := range st.taskLabels[t.TaskID] {
+				if l := st.labels[labelID]; l.UserID == userID {
+					labelled = append(labelled, statsLabelUse{labelID, l.Name, t.IsCompleted})
+				}
+			}
+		}
+		// Archived tasks only keep label names, so they are matched to the user's labels by name.
+		for _, a := range st.archived {
+			if a.UserID != userID && !sameUUID(a.AssigneeID, &userID) {
+				continue
+			}
+			completedAt := a.CompletedAt
+			tasks = append(tasks, statsTask{a.ProjectID, st.projectName(a.ProjectID), true, &completedAt, a.CreatedAt, a.DueDate, a.DueDatetime})
+			for _, name := range a.Labels {
+				if l, ok := userLabels[strings.ToLower(name)]; ok {
+					labelled = append(labelled, statsLabelUse{l.LabelID, l.Name, true})
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+	return computeStats(settings, r, tasks, labelled)
+}
+
+// projectName returns the name of a stored project, or nil if there is none.
+func (st *memState) projectName(projectID *uuid.UUID) *string {
+	if projectID == nil {
+		return nil
+	}
+	p, ok := st.projects[*projectID]
+	if !ok {
+		return nil
+	}
+	name := p.ProjectName
+	return &name
+}
@@ -0,0 +1,1178 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memoryTasks is MemoryStore's TaskStore.
+type memoryTasks struct {
+	db memDB
+}
+
+func (m *memoryTasks) Atomic(ctx context.Context, fn func(TaskStore) error) error {
+	return m.db.atomic(func(tx *memTx) error {
+		return fn(&memoryTasks{db: tx})
+	})
+}
+
+func (m *memoryTasks) AddTask(input NewTask, userID uuid.UUID) (Task, error) {
+	var created Task
+	err := m.db.write(func(st *memState) error {
+		// Without an explicit order the task is appended after its siblings.
+		order := 0
+		if input.Order != nil {
+			order = *input.Order
+		} else {
+			order = st.nextOrder(siblingList{userID: userID, projectID: input.ProjectID, parentTaskID: input.ParentTaskID})
+		}
+
+		task := Task{
+			TaskID:       input.TaskID,
+			ProjectID:    input.ProjectID,
+			UserID:       userID,
+			Content:      input.Content,
+			DueDate:      input.DueDate,
+			DueDatetime:  input.DueDatetime,
+			ParentTaskID: input.ParentTaskID,
+			Order:        order,
+			AssigneeID:   input.AssigneeID,
+			CreatedBy:    userID,
+			CreatedAt:    memNow(),
+		}
+		if input.Description != nil {
+			task.Description = *input.Description
+		}
+		if input.Priority != nil {
+			task.Priority = *input.Priority
+		}
+		if err := st.insertTask(task); err != nil {
+			return fmt.Errorf("unable to execute query: %v", err)
+		}
+		if err := st.setTaskLabels(task.TaskID, userID, input.LabelIDs); err != nil {
+			return err
+		}
+		created = st.taskView(task.TaskID)
+		return nil
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return created, nil
+}
+
+func (m *memoryTasks) EditTaskByID(task Task) (Task, error) {
+	var updated Task
+	err := m.db.write(func(st *memState) error {
+		stored, ok := st.tasks[task.TaskID]
+		if !ok || stored.UserID != task.UserID {
+			return ErrNoRecord
+		}
+		previousAssignee := stored.AssigneeID
+
+		stored.ProjectID = task.ProjectID
+		stored.Content = task.Content
+		stored.Description = task.Description
+		stored.DueDate = task.DueDate
+		stored.DueDatetime = task.DueDatetime
+		stored.Priority = task.Priority
+		stored.ParentTaskID = task.ParentTaskID
+		stored.Order = task.Order
+		stored.AssigneeID = task.AssigneeID
+		if err := st.checkTaskRefs(stored.Task); err != nil {
+			return fmt.Errorf("unable to execute query: %v", err)
+		}
+		st.tasks[task.TaskID] = stored
+
+		if err := st.setTaskLabels(task.TaskID, task.UserID, task.LabelIDs); err != nil {
+			return err
+		}
+
+		if !sameUUID(previousAssignee, task.AssigneeID) {
+			st.addTaskEvent(TaskEvent{
+				TaskID:    task.TaskID,
+				UserID:    task.UserID,
+				EventType: TaskEventReassigned,
+				OldValue:  uuidString(previousAssignee),
+				NewValue:  uuidString(task.AssigneeID),
+			})
+		}
+
+		updated = st.taskView(task.TaskID)
+		return nil
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return updated, nil
+}
+
+func (m *memoryTasks) GetTasksByUserID(userID uuid.UUID, filter TaskFilter) ([]Task, error) {
+	var tasks []Task
+	err := m.db.read(func(st *memState) error {
+		var matched []memTask
+		for _, t := range st.tasks {
+			if !t.visibleTo(userID) {
+				continue
+			}
+			if filter.AssigneeID != nil && !sameUUID(t.AssigneeID, filter.AssigneeID) {
+				continue
+			}
+			if t.IsCompleted && !filter.IncludeCompleted {
+				continue
+			}
+			matched = append(matched, t)
+		}
+		sort.Slice(matched, func(i, j int) bool {
+			return createdBefore(matched[i].CreatedAt, matched[i].seq, matched[j].CreatedAt, matched[j].seq)
+		})
+		for _, t := range matched {
+			tasks = append(tasks, st.taskView(t.TaskID))
+		}
+		return nil
+	})
+	return tasks, err
+}
+
+func (m *memoryTasks) ToggleTaskCompleted(taskID, userID uuid.UUID, rules CompletionRules) (Task, error) {
+	task, _, err := m.updateCompletion(taskID, userID, nil, nil, rules)
+	return task, err
+}
+
+func (m *memoryTasks) SetTaskCompleted(taskID, userID uuid.UUID, completed bool, completedAt *time.Time, rules CompletionRules) (Task, bool, error) {
+	return m.updateCompletion(taskID, userID, &completed, completedAt, rules)
+}
+
+// updateCompletion mirrors TaskModel.updateCompletion.
+func (m *memoryTasks) updateCompletion(taskID, userID uuid.UUID, target *bool, completedAt *time.Time, rules CompletionRules) (Task, bool, error) {
+	var task Task
+	var changed bool
+	err := m.db.write(func(st *memState) error {
+		stored, ok := st.tasks[taskID]
+		if !ok || !stored.visibleTo(userID) {
+			return ErrNoRecord
+		}
+
+		completed := !stored.IsCompleted
+		if target != nil {
+			completed = *target
+		}
+		changed = completed != stored.IsCompleted
+		if changed {
+			if rules.RefuseIfBlocked && completed && st.taskView(taskID).Blocked {
+				return ErrTaskBlocked
+			}
+			stored.IsCompleted = completed
+			stored.CompletedAt = nil
+			if completed {
+				at := memNow()
+				if completedAt != nil {
+					at = completedAt.Truncate(time.Microsecond)
+				}
+				stored.CompletedAt = &at
+			}
+			st.tasks[taskID] = stored
+			st.applyCompletionRules(stored.Task, rules)
+		}
+
+		task = st.taskView(taskID)
+		return nil
+	})
+	if err != nil {
+		return Task{}, false, err
+	}
+	return task, changed, nil
+}
+
+// applyCompletionRules mirrors the package function of the same name.
+func (st *memState) applyCompletionRules(task Task, rules CompletionRules) {
+	if task.IsCompleted && rules.CompleteSubtasks {
+		for _, id := range st.descendants(task.TaskID) {
+			st.setCompleted(id, true, task.CompletedAt)
+		}
+	}
+
+	if task.ParentTaskID == nil || !rules.CompleteParent {
+		return
+	}
+
+	if task.IsCompleted {
+		// Walk up from the parent, stopping at the first ancestor that still has another open child.
+		var completing []uuid.UUID
+		child := task.TaskID
+		for _, id := range st.ancestors(task.TaskID) {
+			if st.hasOpenChild(id, child) {
+				break
+			}
+			completing = append(completing, id)
+			child = id
+		}
+		for _, id := range completing {
+			st.setCompleted(id, true, task.CompletedAt)
+		}
+		return
+	}
+
+	for _, id := range st.ancestors(task.TaskID) {
+		st.setCompleted(id, false, nil)
+	}
+}
+
+// setCompleted changes a task's completion state if it isn't in it already.
+func (st *memState) setCompleted(taskID uuid.UUID, completed bool, completedAt *time.Time) {
+	t := st.tasks[taskID]
+	if t.IsCompleted == completed {
+		return
+	}
+	t.IsCompleted = completed
+	t.CompletedAt = nil
+	if completed {
+		t.CompletedAt = completedAt
+	}
+	st.tasks[taskID] = t
+}
+
+// hasOpenChild reports whether taskID has an open subtask other than except.
+func (st *memState) hasOpenChild(taskID, except uuid.UUID) bool {
+	for _, t := range st.tasks {
+		if t.ParentTaskID != nil && *t.ParentTaskID == taskID && t.TaskID != except && !t.IsCompleted {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memoryTasks) DeleteTaskByID(taskID, userID uuid.UUID) (int64, error) {
+	var deleted int64
+	err := m.db.write(func(st *memState) error {
+		if t, ok := st.tasks[taskID]; ok && t.UserID == userID {
+			st.deleteTasks([]uuid.UUID{taskID})
+			deleted = 1
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+func (m *memoryTasks) BulkUpdateTaskOrder(userID uuid.UUID, updates []TaskOrderUpdate) (TaskOrderRejection, error) {
+	r := TaskOrderRejection{Rejected: []uuid.UUID{}, Missing: []uuid.UUID{}, DuplicateIDs: []uuid.UUID{}, DuplicateOrders: []int{}}
+	err := m.db.write(func(st *memState) error {
+		// The list is the one holding the first task in updates that userID owns.
+		var siblings []memTask
+		for _, u := range updates {
+			if t, ok := st.tasks[u.TaskID]; ok && t.UserID == userID {
+				siblings = st.siblings(siblingList{userID: userID, projectID: t.ProjectID, parentTaskID: t.ParentTaskID}, uuid.Nil)
+				break
+			}
+		}
+		inList := map[uuid.UUID]bool{}
+		for _, t := range siblings {
+			inList[t.TaskID] = true
+		}
+
+		idCount := map[uuid.UUID]int{}
+		orderCount := map[int]int{}
+		for _, u := range updates {
+			idCount[u.TaskID]++
+			orderCount[u.Order]++
+		}
+		for _, id := range uniqueUUIDs(taskOrderIDs(updates)) {
+			if !inList[id] {
+				r.Rejected = append(r.Rejected, id)
+			}
+			if idCount[id] > 1 {
+				r.DuplicateIDs = append(r.DuplicateIDs, id)
+			}
+		}
+		for _, t := range siblings {
+			if idCount[t.TaskID] == 0 {
+				r.Missing = append(r.Missing, t.TaskID)
+			}
+		}
+		for order, n := range orderCount {
+			if n > 1 {
+				r.DuplicateOrders = append(r.DuplicateOrders, order)
+			}
+		}
+		sort.Ints(r.DuplicateOrders)
+
+		if len(r.Rejected)+len(r.Missing)+len(r.DuplicateIDs)+len(r.DuplicateOrders) > 0 {
+			return nil
+		}
+		for _, u := range updates {
+			t := st.tasks[u.TaskID]
+			t.Order = u.Order
+			st.tasks[u.TaskID] = t
+		}
+		return nil
+	})
+	if err != nil {
+		return TaskOrderRejection{}, err
+	}
+	if len(r.Rejected)+len(r.Missing)+len(r.DuplicateIDs)+len(r.DuplicateOrders) > 0 {
+		return r, ErrInvalidReorder
+	}
+	return TaskOrderRejection{}, nil
+}
+
+// taskOrderIDs returns the task IDs of updates in request order.
+func taskOrderIDs(updates []TaskOrderUpdate) []uuid.UUID {
+	ids := make([]uuid.UUID, len(updates))
+	for i, u := range updates {
+		ids[i] = u.TaskID
+	}
+	return ids
+}
+
+func (m *memoryTasks) PositionTask(taskID, userID uuid.UUID, pos TaskPosition) (Task, error) {
+	var positioned Task
+	err := m.db.write(func(st *memState) error {
+		t, ok := st.tasks[taskID]
+		if !ok || t.UserID != userID {
+			return ErrNoRecord
+		}
+
+		list := siblingList{userID: userID, projectID: t.ProjectID, parentTaskID: t.ParentTaskID}
+		order, err := st.orderAt(list, taskID, pos)
+		if err != nil {
+			return err
+		}
+		st.setOrder(taskID, order)
+
+		positioned = st.taskView(taskID)
+		return nil
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return positioned, nil
+}
+
+func (m *memoryTasks) MoveTask(taskID, userID uuid.UUID, move TaskMove) (Task, error) {
+	var moved Task
+	err := m.db.write(func(st *memState) error {
+		_, _, err := st.relocateTask(taskID, userID, move.ProjectID, move.ParentTaskID, func(dest siblingList) (int, error) {
+			if move.Position == nil {
+				return st.nextOrder(dest), nil
+			}
+			return st.orderAtIndex(dest, taskID, *move.Position)
+		})
+		if err != nil {
+			return err
+		}
+		moved = st.taskView(taskID)
+		return nil
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return moved, nil
+}
+
+func (m *memoryTasks) DropTask(userID uuid.UUID, drop TaskDrop) (TaskDropResult, error) {
+	var result TaskDropResult
+	err := m.db.write(func(st *memState) error {
+		pos := TaskPosition{BeforeID: drop.BeforeID, AfterID: drop.AfterID}
+		source, dest, err := st.relocateTask(drop.TaskID, userID, drop.NewProjectID, drop.NewParentID, func(dest siblingList) (int, error) {
+			return st.orderAt(dest, drop.TaskID, pos)
+		})
+		if err != nil {
+			return err
+		}
+
+		st.rebalance(dest, uuid.Nil)
+		result.Siblings = st.siblingViews(dest)
+		if !source.same(dest) {
+			st.rebalance(source, uuid.Nil)
+			result.SourceSiblings = st.siblingViews(source)
+		}
+		result.Task = st.taskView(drop.TaskID)
+		return nil
+	})
+	if err != nil {
+		return TaskDropResult{}, err
+	}
+	return result, nil
+}
+
+// relocateTask mirrors the package function of the same name.
+func (st *memState) relocateTask(taskID, userID uuid.UUID, projectID, parentTaskID *uuid.UUID, place func(dest siblingList) (int, error)) (source, dest siblingList, err error) {
+	t, ok := st.tasks[taskID]
+	if !ok || t.UserID != userID {
+		return source, dest, ErrNoRecord
+	}
+	source = siblingList{userID: userID, projectID: t.ProjectID, parentTaskID: t.ParentTaskID}
+
+	if parentTaskID != nil {
+		if *parentTaskID == taskID || st.isDescendant(taskID, *parentTaskID) {
+			return source, dest, ErrMoveIntoSubtree
+		}
+		parent, ok := st.tasks[*parentTaskID]
+		if !ok || parent.UserID != userID {
+			return source, dest, ErrNoRecord
+		}
+		projectID = parent.ProjectID
+	} else if projectID != nil {
+		if p, ok := st.projects[*projectID]; !ok || p.UserID != userID {
+			return source, dest, ErrNoRecord
+		}
+	}
+
+	dest = siblingList{userID: userID, projectID: projectID, parentTaskID: parentTaskID}
+	order, err := place(dest)
+	if err != nil {
+		return source, dest, err
+	}
+
+	t = st.tasks[taskID]
+	t.ProjectID = projectID
+	t.ParentTaskID = parentTaskID
+	t.Order = order
+	st.tasks[taskID] = t
+
+	// Subtasks keep their parents and order but follow the task into its new project.
+	for _, id := range st.descendants(taskID) {
+		d := st.tasks[id]
+		if !sameUUID(d.ProjectID, projectID) {
+			d.ProjectID = projectID
+			st.tasks[id] = d
+		}
+	}
+	return source, dest, nil
+}
+
+func (m *memoryTasks) DuplicateTask(taskID, userID uuid.UUID, opts DuplicateOptions) (Task, error) {
+	var duplicate Task
+	err := m.db.write(func(st *memState) error {
+		root, ok := st.tasks[taskID]
+		if !ok || root.UserID != userID {
+			return ErrNoRecord
+		}
+		tasks := st.taskViews(append([]uuid.UUID{taskID}, st.descendants(taskID)...))
+
+		list := siblingList{userID: userID, projectID: root.ProjectID, parentTaskID: root.ParentTaskID}
+		nextOrder := st.nextOrder(list)
+
+		idMap := map[uuid.UUID]uuid.UUID{}
+		for _, task := range orderParentsFirst(tasks) {
+			copied := task
+			if task.TaskID == taskID {
+				copied.Order = nextOrder
+			}
+			if err := st.insertTaskCopy(copied, userID, idMap, nil, opts); err != nil {
+				return err
+			}
+		}
+
+		duplicate = st.taskView(idMap[taskID])
+		return nil
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return duplicate, nil
+}
+
+// insertTaskCopy mirrors the package function of the same name.
+func (st *memState) insertTaskCopy(task Task, userID uuid.UUID, idMap, projectMap map[uuid.UUID]uuid.UUID, opts DuplicateOptions) error {
+	newID := uuid.New()
+	idMap[task.TaskID] = newID
+
+	var parentID *uuid.UUID
+	if task.ParentTaskID != nil {
+		if mapped, ok := idMap[*task.ParentTaskID]; ok {
+			parentID = &mapped
+		}
+	}
+
+	projectID := task.ProjectID
+	if projectMap != nil && projectID != nil {
+		mapped := projectMap[*projectID]
+		projectID = &mapped
+	}
+
+	dueDate := task.DueDate
+	if dueDate != nil && opts.DueDateOffsetDays != 0 {
+		shifted := dueDate.AddDate(0, 0, opts.DueDateOffsetDays)
+		dueDate = &shifted
+	}
+
+	err := st.insertTask(Task{
+		TaskID:       newID,
+		ProjectID:    projectID,
+		UserID:       userID,
+		Content:      task.Content,
+		Description:  task.Description,
+		DueDate:      dueDate,
+		DueDatetime:  task.DueDatetime,
+		Priority:     task.Priority,
+		ParentTaskID: parentID,
+		Order:        task.Order,
+		AssigneeID:   task.AssigneeID,
+		CreatedBy:    userID,
+		CreatedAt:    memNow(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to copy task: %w", err)
+	}
+	return st.setTaskLabels(newID, userID, task.LabelIDs)
+}
+
+func (m *memoryTasks) RebalanceTaskOrders(ctx context.Context) (int64, error) {
+	var renumbered int64
+	err := m.db.write(func(st *memState) error {
+		lists := map[memListKey]siblingList{}
+		for _, t := range st.tasks {
+			l := siblingList{userID: t.UserID, projectID: t.ProjectID, parentTaskID: t.ParentTaskID}
+			lists[l.key()] = l
+		}
+		for _, l := range lists {
+			tasks := st.siblings(l, uuid.Nil)
+			crowded := false
+			for i, t := range tasks {
+				if (i > 0 && t.Order-tasks[i-1].Order < 2) || t.Order > maxOrderMagnitude || t.Order < -maxOrderMagnitude {
+					crowded = true
+					break
+				}
+			}
+			if crowded {
+				renumbered += st.rebalance(l, uuid.Nil)
+			}
+		}
+		return nil
+	})
+	return renumbered, err
+}
+
+func (m *memoryTasks) AddDependency(taskID, blockedByID, userID uuid.UUID) (TaskDependency, error) {
+	if taskID == blockedByID {
+		return TaskDependency{}, ErrDependencyCycle
+	}
+
+	var dep TaskDependency
+	err := m.db.write(func(st *memState) error {
+		a, okA := st.tasks[taskID]
+		b, okB := st.tasks[blockedByID]
+		if !okA || !okB || a.UserID != userID || b.UserID != userID {
+			return ErrNoRecord
+		}
+
+		if st.upstream(blockedByID)[taskID] {
+			return ErrDependencyCycle
+		}
+
+		key := memDepKey{taskID: taskID, blockedByID: blockedByID}
+		existing, ok := st.deps[key]
+		if ok {
+			dep = existing
+			return nil
+		}
+		dep = TaskDependency{TaskID: taskID, BlockedByTaskID: blockedByID, UserID: userID, CreatedAt: memNow()}
+		st.deps[key] = dep
+		return nil
+	})
+	if err != nil {
+		return TaskDependency{}, err
+	}
+	return dep, nil
+}
+
+// upstream returns every task that taskID (transitively) waits on.
+func (st *memState) upstream(taskID uuid.UUID) map[uuid.UUID]bool {
+	seen := map[uuid.UUID]bool{}
+	queue := []uuid.UUID{taskID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for k := range st.deps {
+			if k.taskID == id && !seen[k.blockedByID] {
+				seen[k.blockedByID] = true
+				queue = append(queue, k.blockedByID)
+			}
+		}
+	}
+	return seen
+}
+
+func (m *memoryTasks) RemoveDependency(taskID, blockedByID, userID uuid.UUID) (int64, error) {
+	var removed int64
+	err := m.db.write(func(st *memState) error {
+		key := memDepKey{taskID: taskID, blockedByID: blockedByID}
+		if dep, ok := st.deps[key]; ok && dep.UserID == userID {
+			delete(st.deps, key)
+			removed = 1
+		}
+		return nil
+	})
+	return removed, err
+}
+
+func (m *memoryTasks) GetTaskDependencies(taskID, userID uuid.UUID) (TaskDependencies, error) {
+	deps := TaskDependencies{BlockedBy: []uuid.UUID{}, Blocks: []uuid.UUID{}}
+	err := m.db.read(func(st *memState) error {
+		var edges []TaskDependency
+		for _, dep := range st.deps {
+			if dep.UserID == userID && (dep.TaskID == taskID || dep.BlockedByTaskID == taskID) {
+				edges = append(edges, dep)
+			}
+		}
+		sortDependencies(edges)
+		for _, dep := range edges {
+			if dep.TaskID == taskID {
+				deps.BlockedBy = append(deps.BlockedBy, dep.BlockedByTaskID)
+			} else {
+				deps.Blocks = append(deps.Blocks, dep.TaskID)
+			}
+		}
+		return nil
+	})
+	return deps, err
+}
+
+// sortDependencies sorts edges oldest first, breaking ties by ID so the result is stable.
+func sortDependencies(deps []TaskDependency) {
+	sort.Slice(deps, func(i, j int) bool {
+		a, b := deps[i], deps[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		if a.TaskID != b.TaskID {
+			return lessUUID(a.TaskID, b.TaskID)
+		}
+		return lessUUID(a.BlockedByTaskID, b.BlockedByTaskID)
+	})
+}
+
+func (m *memoryTasks) GetProjectTasksInTopologicalOrder(projectID, userID uuid.UUID) ([]Task, error) {
+	var tasks []Task
+	var deps []TaskDependency
+	err := m.db.read(func(st *memState) error {
+		var stored []memTask
+		for _, t := range st.tasks {
+			if t.ProjectID != nil && *t.ProjectID == projectID && t.UserID == userID {
+				stored = append(stored, t)
+			}
+		}
+		sortTasks(stored)
+		tasks = make([]Task, 0, len(stored))
+		for _, t := range stored {
+			tasks = append(tasks, st.taskView(t.TaskID))
+		}
+
+		inProject := func(id uuid.UUID) bool {
+			t, ok := st.tasks[id]
+			return ok && t.ProjectID != nil && *t.ProjectID == projectID
+		}
+		for _, dep := range st.deps {
+			if dep.UserID == userID && inProject(dep.TaskID) && inProject(dep.BlockedByTaskID) {
+				deps = append(deps, dep)
+			}
+		}
+		sortDependencies(deps)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return TopologicalSort(tasks, deps)
+}
+
+func (m *memoryTasks) GetTaskHistory(taskID, userID uuid.UUID) ([]TaskEvent, error) {
+	var events []TaskEvent
+	err := m.db.read(func(st *memState) error {
+		if t, ok := st.tasks[taskID]; !ok || !t.visibleTo(userID) {
+			return nil
+		}
+		// history is kept in insertion order, which is also created_at order.
+		for _, event := range st.history {
+			if event.TaskID == taskID {
+				events = append(events, event)
+			}
+		}
+		return nil
+	})
+	return events, err
+}
+
+// addTaskEvent appends event to the task history.
+func (st *memState) addTaskEvent(event TaskEvent) {
+	event.EventID = uuid.New()
+	event.CreatedAt = memNow()
+	st.history = append(st.history, event)
+}
+
+func (m *memoryTasks) BulkLabelTasks(userID uuid.UUID, req BulkLabel) (BulkLabelResult, error) {
+	var result BulkLabelResult
+	err := m.db.write(func(st *memState) error {
+		labelIDs := append(append([]uuid.UUID{}, req.AddLabelIDs...), req.RemoveLabelIDs...)
+		for _, id := range uniqueUUIDs(labelIDs) {
+			if l, ok := st.labels[id]; !ok || l.UserID != userID {
+				return ErrUnknownLabel
+			}
+		}
+
+		// An empty ID list means "no ID filter", not "no tasks".
+		wanted := map[uuid.UUID]bool{}
+		for _, id := range req.TaskIDs {
+			wanted[id] = true
+		}
+		var taskIDs []uuid.UUID
+		for _, t := range st.tasks {
+			switch {
+			case t.UserID != userID:
+			case len(wanted) > 0 && !wanted[t.TaskID]:
+			case req.ProjectID != nil && !sameUUID(t.ProjectID, req.ProjectID):
+			case req.LabelID != nil && !st.taskLabels[t.TaskID][*req.LabelID]:
+			case req.IsCompleted != nil && t.IsCompleted != *req.IsCompleted:
+			default:
+				taskIDs = append(taskIDs, t.TaskID)
+			}
+		}
+
+		result = BulkLabelResult{Matched: len(taskIDs), Added: map[uuid.UUID]int64{}, Removed: map[uuid.UUID]int64{}}
+		for _, labelID := range uniqueUUIDs(req.AddLabelIDs) {
+			result.Added[labelID] = 0
+			for _, taskID := range taskIDs {
+				if !st.taskLabels[taskID][labelID] {
+					st.attachLabel(taskID, labelID)
+					result.Added[labelID]++
+				}
+			}
+		}
+		for _, labelID := range uniqueUUIDs(req.RemoveLabelIDs) {
+			result.Removed[labelID] = 0
+			for _, taskID := range taskIDs {
+				if st.taskLabels[taskID][labelID] {
+					delete(st.taskLabels[taskID], labelID)
+					result.Removed[labelID]++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return BulkLabelResult{}, err
+	}
+	return result, nil
+}
+
+func (m *memoryTasks) GetCompletedTasks(userID uuid.UUID, filter CompletedTaskFilter) ([]CompletedTask, error) {
+	tasks := []CompletedTask{}
+	err := m.db.read(func(st *memState) error {
+		matches := func(t CompletedTask) bool {
+			if t.UserID != userID && !sameUUID(t.AssigneeID, &userID) {
+				return false
+			}
+			if filter.From != nil && t.CompletedAt.Before(*filter.From) {
+				return false
+			}
+			if filter.To != nil && !t.CompletedAt.Before(*filter.To) {
+				return false
+			}
+			return filter.ProjectID == nil || sameUUID(t.ProjectID, filter.ProjectID)
+		}
+
+		for _, t := range st.tasks {
+			if !t.IsCompleted {
+				continue
+			}
+			if completed := st.completedTask(t.TaskID); matches(completed) {
+				tasks = append(tasks, completed)
+			}
+		}
+		for _, t := range st.archived {
+			if matches(t) {
+				tasks = append(tasks, t)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].CompletedAt.Equal(tasks[j].CompletedAt) {
+			return tasks[i].CompletedAt.After(tasks[j].CompletedAt)
+		}
+		return lessUUID(tasks[i].TaskID, tasks[j].TaskID)
+	})
+	if len(tasks) > filter.Limit {
+		tasks = tasks[:filter.Limit]
+	}
+	return tasks, nil
+}
+
+// completedTask returns a live task in the shape GetCompletedTasks and the archive use.
+func (st *memState) completedTask(taskID uuid.UUID) CompletedTask {
+	t := st.taskView(taskID)
+	completed := CompletedTask{
+		TaskID:       t.TaskID,
+		ProjectID:    t.ProjectID,
+		UserID:       t.UserID,
+		Content:      t.Content,
+		Description:  &t.Description,
+		DueDate:      t.DueDate,
+		DueDatetime:  t.DueDatetime,
+		Priority:     t.Priority,
+		ParentTaskID: t.ParentTaskID,
+		Order:        t.Order,
+		Labels:       t.Labels,
+		AssigneeID:   t.AssigneeID,
+		CreatedBy:    t.CreatedBy,
+		CreatedAt:    t.CreatedAt,
+	}
+	if t.CompletedAt != nil {
+		completed.CompletedAt = *t.CompletedAt
+	}
+	return completed
+}
+
+func (m *memoryTasks) ArchiveCompletedTasks(ctx context.Context, cutoff time.Time) (int64, error) {
+	var archived int64
+	err := m.db.write(func(st *memState) error {
+		doneBefore := func(t memTask) bool {
+			return t.IsCompleted && t.CompletedAt != nil && t.CompletedAt.Before(cutoff)
+		}
+
+		// A tree is only archived once every task in it was completed before cutoff.
+		var ids []uuid.UUID
+		for _, root := range st.tasks {
+			if root.ParentTaskID != nil || !doneBefore(root) {
+				continue
+			}
+			tree := append([]uuid.UUID{root.TaskID}, st.descendants(root.TaskID)...)
+			eligible := true
+			for _, id := range tree {
+				if !doneBefore(st.tasks[id]) {
+					eligible = false
+					break
+				}
+			}
+			if eligible {
+				ids = append(ids, tree...)
+			}
+		}
+
+		archivedAt := memNow()
+		for _, id := range ids {
+			snapshot := st.completedTask(id)
+			snapshot.Archived = true
+			snapshot.ArchivedAt = &archivedAt
+			st.archived[id] = snapshot
+		}
+		st.deleteTasks(ids)
+		archived = int64(len(ids))
+		return nil
+	})
+	return archived, err
+}
+
+// taskView returns a stored task with its computed columns filled in.
+func (st *memState) taskView(taskID uuid.UUID) Task {
+	task := st.tasks[taskID].Task
+	task.Labels = []string{}
+	task.LabelIDs = []uuid.UUID{}
+	for _, l := range st.labelsOf(taskID) {
+		task.Labels = append(task.Labels, l.Name)
+		task.LabelIDs = append(task.LabelIDs, l.LabelID)
+	}
+	for k := range st.deps {
+		if k.taskID == taskID && !st.tasks[k.blockedByID].IsCompleted {
+			task.Blocked = true
+			break
+		}
+	}
+	for _, t := range st.tasks {
+		if t.ParentTaskID != nil && *t.ParentTaskID == taskID {
+			task.SubtaskCount++
+			if t.IsCompleted {
+				task.CompletedSubtaskCount++
+			}
+		}
+	}
+	return task
+}
+
+// taskViews returns the given tasks in creation order.
+func (st *memState) taskViews(ids []uuid.UUID) []Task {
+	stored := make([]memTask, 0, len(ids))
+	for _, id := range ids {
+		stored = append(stored, st.tasks[id])
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return createdBefore(stored[i].CreatedAt, stored[i].seq, stored[j].CreatedAt, stored[j].seq)
+	})
+	tasks := make([]Task, 0, len(stored))
+	for _, t := range stored {
+		tasks = append(tasks, st.taskView(t.TaskID))
+	}
+	return tasks
+}
+
+// visibleTo reports whether userID owns the task or is assigned to it.
+func (t memTask) visibleTo(userID uuid.UUID) bool {
+	return t.UserID == userID || sameUUID(t.AssigneeID, &userID)
+}
+
+// insertTask stores a new task, enforcing the primary and foreign keys of the tasks table.
+func (st *memState) insertTask(task Task) error {
+	if _, ok := st.tasks[task.TaskID]; ok {
+		return fmt.Errorf("task %s already exists", task.TaskID)
+	}
+	if err := st.checkTaskRefs(task); err != nil {
+		return err
+	}
+	task.Labels, task.LabelIDs = nil, nil
+	task.Blocked, task.SubtaskCount, task.CompletedSubtaskCount = false, 0, 0
+	st.tasks[task.TaskID] = memTask{Task: task, seq: st.nextSeq()}
+	return nil
+}
+
+// checkTaskRefs enforces the foreign keys of a task's project and parent.
+func (st *memState) checkTaskRefs(task Task) error {
+	if task.ProjectID != nil {
+		if _, ok := st.projects[*task.ProjectID]; !ok {
+			return memRowMissing("project", *task.ProjectID)
+		}
+	}
+	if task.ParentTaskID != nil {
+		if _, ok := st.tasks[*task.ParentTaskID]; !ok {
+			return memRowMissing("task", *task.ParentTaskID)
+		}
+	}
+	return nil
+}
+
+// deleteTasks deletes the given tasks with the cascades of the tasks table: subtasks, label
+// links and dependencies go too, while the history is kept.
+func (st *memState) deleteTasks(ids []uuid.UUID) {
+	for _, root := range ids {
+		if _, ok := st.tasks[root]; !ok {
+			continue
+		}
+		for _, id := range append([]uuid.UUID{root}, st.descendants(root)...) {
+			delete(st.tasks, id)
+			delete(st.taskLabels, id)
+			for k := range st.deps {
+				if k.taskID == id || k.blockedByID == id {
+					delete(st.deps, k)
+				}
+			}
+		}
+	}
+}
+
+// descendants returns every subtask of taskID, parents before their children.
+func (st *memState) descendants(taskID uuid.UUID) []uuid.UUID {
+	children := map[uuid.UUID][]uuid.UUID{}
+	for _, t := range st.tasks {
+		if t.ParentTaskID != nil {
+			children[*t.ParentTaskID] = append(children[*t.ParentTaskID], t.TaskID)
+		}
+	}
+
+	seen := map[uuid.UUID]bool{taskID: true}
+	var ids []uuid.UUID
+	for queue := []uuid.UUID{taskID}; len(queue) > 0; queue = queue[1:] {
+		for _, child := range children[queue[0]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+				queue = append(queue, child)
+			}
+		}
+	}
+	return ids
+}
+
+// isDescendant reports whether candidateID is a subtask of taskID at any depth.
+func (st *memState) isDescendant(taskID, candidateID uuid.UUID) bool {
+	for _, id := range st.descendants(taskID) {
+		if id == candidateID {
+			return true
+		}
+	}
+	return false
+}
+
+// ancestors returns the parent of taskID, its parent and so on up to the top-level task.
+func (st *memState) ancestors(taskID uuid.UUID) []uuid.UUID {
+	seen := map[uuid.UUID]bool{taskID: true}
+	var ids []uuid.UUID
+	for t, ok := st.tasks[taskID]; ok && t.ParentTaskID != nil && !seen[*t.ParentTaskID]; t, ok = st.tasks[*t.ParentTaskID] {
+		seen[*t.ParentTaskID] = true
+		ids = append(ids, *t.ParentTaskID)
+	}
+	return ids
+}
+
+// memListKey is a comparable form of siblingList.
+type memListKey struct {
+	userID, projectID, parentTaskID uuid.UUID
+	hasProject, hasParent           bool
+}
+
+func (l siblingList) key() memListKey {
+	k := memListKey{userID: l.userID}
+	if l.projectID != nil {
+		k.projectID, k.hasProject = *l.projectID, true
+	}
+	if l.parentTaskID != nil {
+		k.parentTaskID, k.hasParent = *l.parentTaskID, true
+	}
+	return k
+}
+
+// siblings returns the tasks in l in display order, leaving out exclude.
+func (st *memState) siblings(l siblingList, exclude uuid.UUID) []memTask {
+	var tasks []memTask
+	for _, t := range st.tasks {
+		if t.TaskID != exclude && t.UserID == l.userID && sameUUID(t.ProjectID, l.projectID) && sameUUID(t.ParentTaskID, l.parentTaskID) {
+			tasks = append(tasks, t)
+		}
+	}
+	sortTasks(tasks)
+	return tasks
+}
+
+// siblingViews returns the full tasks in l in display order.
+func (st *memState) siblingViews(l siblingList) []Task {
+	stored := st.siblings(l, uuid.Nil)
+	tasks := make([]Task, 0, len(stored))
+	for _, t := range stored {
+		tasks = append(tasks, st.taskView(t.TaskID))
+	}
+	return tasks
+}
+
+// nextOrder mirrors siblingList.nextOrder.
+func (st *memState) nextOrder(l siblingList) int {
+	tasks := st.siblings(l, uuid.Nil)
+	if len(tasks) == 0 {
+		return 0
+	}
+	return tasks[len(tasks)-1].Order + OrderGap
+}
+
+// orderAt mirrors siblingList.orderAt.
+func (st *memState) orderAt(l siblingList, taskID uuid.UUID, pos TaskPosition) (int, error) {
+	if pos.BeforeID == nil && pos.AfterID == nil {
+		return st.nextOrder(l), nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		lo, hi, err := st.neighbourOrders(l, taskID, pos)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case lo == nil:
+			return *hi - OrderGap, nil
+		case hi == nil:
+			return *lo + OrderGap, nil
+		case *hi-*lo > 1:
+			return *lo + (*hi-*lo)/2, nil
+		}
+		if attempt > 0 {
+			return 0, fmt.Errorf("no room between sibling tasks after rebalancing")
+		}
+		st.rebalance(l, taskID)
+	}
+}
+
+// orderAtIndex mirrors siblingList.orderAtIndex.
+func (st *memState) orderAtIndex(l siblingList, taskID uuid.UUID, index int) (int, error) {
+	others := st.siblings(l, taskID)
+	if index >= len(others) {
+		return st.nextOrder(l), nil
+	}
+	beforeID := others[index].TaskID
+	return st.orderAt(l, taskID, TaskPosition{BeforeID: &beforeID})
+}
+
+// neighbourOrders mirrors siblingList.neighbourOrders.
+func (st *memState) neighbourOrders(l siblingList, taskID uuid.UUID, pos TaskPosition) (lo, hi *int, err error) {
+	others := st.siblings(l, taskID)
+	orders := make([]int, len(others))
+	for i, t := range others {
+		orders[i] = t.Order
+	}
+	rank := func(id *uuid.UUID) *int {
+		if id == nil {
+			return nil
+		}
+		for i, t := range others {
+			if t.TaskID == *id {
+				r := i + 1
+				return &r
+			}
+		}
+		return nil
+	}
+	afterRank, beforeRank := rank(pos.AfterID), rank(pos.BeforeID)
+
+	for _, ref := range []struct {
+		id   *uuid.UUID
+		rank *int
+	}{{pos.AfterID, afterRank}, {pos.BeforeID, beforeRank}} {
+		if ref.id == nil || ref.rank != nil {
+			continue
+		}
+		return nil, nil, st.checkSibling(l, *ref.id, taskID)
+	}
+
+	// Ranks are 1-based, so orders[rank-1] is the neighbour itself.
+	switch {
+	case afterRank != nil && beforeRank != nil:
+		if *beforeRank != *afterRank+1 {
+			return nil, nil, ErrInvalidPosition
+		}
+		return &orders[*afterRank-1], &orders[*beforeRank-1], nil
+	case afterRank != nil:
+		lo = &orders[*afterRank-1]
+		if *afterRank < len(orders) {
+			hi = &orders[*afterRank]
+		}
+	default:
+		hi = &orders[*beforeRank-1]
+		if *beforeRank > 1 {
+			lo = &orders[*beforeRank-2]
+		}
+	}
+	return lo, hi, nil
+}
+
+// checkSibling mirrors siblingList.checkSibling.
+func (st *memState) checkSibling(l siblingList, id, taskID uuid.UUID) error {
+	if id == taskID {
+		return ErrInvalidPosition
+	}
+	if t, ok := st.tasks[id]; ok && t.UserID == l.userID {
+		return ErrNotSiblings
+	}
+	return ErrNoRecord
+}
+
+// rebalance mirrors siblingList.rebalance and returns how many tasks were renumbered.
+func (st *memState) rebalance(l siblingList, exclude uuid.UUID) int64 {
+	var renumbered int64
+	for i, t := range st.siblings(l, exclude) {
+		if t.Order != i*OrderGap {
+			st.setOrder(t.TaskID, i*OrderGap)
+			renumbered++
+		}
+	}
+	return renumbered
+}
+
+// setOrder changes the order of a stored task.
+func (st *memState) setOrder(taskID uuid.UUID, order int) {
+	t := st.tasks[taskID]
+	t.Order = order
+	st.tasks[taskID] = t
+}
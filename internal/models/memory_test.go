@@ -0,0 +1,21 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/dmcleish91/go_todo_api/internal/models"
+	"github.com/dmcleish91/go_todo_api/internal/models/storetest"
+	"github.com/google/uuid"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		store := models.NewMemoryStore()
+		return storetest.Stores{
+			Tasks:    store.Tasks(),
+			Projects: store.Projects(),
+			Labels:   store.Labels(),
+			NewUser:  func(t *testing.T) uuid.UUID { return uuid.New() },
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// TaskMove describes where MoveTask should put a task.
+// When ParentTaskID is set the task joins that parent's project and ProjectID is ignored.
+// A nil Position appends the task after its new siblings.
+type TaskMove struct {
+	ProjectID    *uuid.UUID `json:"project_id"`
+	ParentTaskID *uuid.UUID `json:"parent_task_id"`
+	Position     *int       `json:"position"`
+}
+
+// MoveTask relocates a task and its whole subtree to a new project and/or parent at the given
+// position. Only the moved task's order changes unless the destination list has to be rebalanced.
+// It returns ErrMoveIntoSubtree if the new parent is the task itself or one of its descendants.
+func (m *TaskModel) MoveTask(taskID, userID uuid.UUID, move TaskMove) (Task, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return Task{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The source list keeps its gap; only the moved task gets a new order in the destination list.
+	_, _, err = relocateTask(ctx, tx, taskID, userID, move.ProjectID, move.ParentTaskID, func(dest siblingList) (int, error) {
+		if move.Position == nil {
+			return dest.nextOrder(ctx, tx)
+		}
+		return dest.orderAtIndex(ctx, tx, taskID, *move.Position)
+	})
+	if err != nil {
+		return Task{}, err
+	}
+
+	moved, err := getTask(ctx, tx, taskID)
+	if err != nil {
+		return Task{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Task{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return moved, nil
+}
+
+// TaskDrop describes a drag-and-drop of a task into a possibly different parent or project.
+// NewParentID and NewProjectID follow the TaskMove rules; BeforeID and AfterID follow TaskPosition's.
+type TaskDrop struct {
+	TaskID       uuid.UUID  `json:"task_id"`
+	NewParentID  *uuid.UUID `json:"new_parent_id"`
+	NewProjectID *uuid.UUID `json:"new_project_id"`
+	BeforeID     *uuid.UUID `json:"before_id"`
+	AfterID      *uuid.UUID `json:"after_id"`
+}
+
+// TaskDropResult is the state of the affected sibling lists after DropTask. SourceSiblings is
+// nil when the task stayed in the same list.
+type TaskDropResult struct {
+	Task           Task   `json:"task"`
+	Siblings       []Task `json:"siblings"`
+	SourceSiblings []Task `json:"source_siblings,omitempty"`
+}
+
+// DropTask moves a task next to before/after siblings in its new list and renumbers both the
+// source and destination lists to multiples of OrderGap, all in one transaction. It returns the
+// errors of MoveTask and PositionTask.
+func (m *TaskModel) DropTask(userID uuid.UUID, drop TaskDrop) (TaskDropResult, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return TaskDropResult{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	pos := TaskPosition{BeforeID: drop.BeforeID, AfterID: drop.AfterID}
+	source, dest, err := relocateTask(ctx, tx, drop.TaskID, userID, drop.NewProjectID, drop.NewParentID, func(dest siblingList) (int, error) {
+		return dest.orderAt(ctx, tx, drop.TaskID, pos)
+	})
+	if err != nil {
+		return TaskDropResult{}, err
+	}
+
+	var result TaskDropResult
+	if err := dest.rebalance(ctx, tx, uuid.Nil); err != nil {
+		return TaskDropResult{}, err
+	}
+	if result.Siblings, err = dest.tasks(ctx, tx); err != nil {
+		return TaskDropResult{}, err
+	}
+	if !source.same(dest) {
+		if err := source.rebalance(ctx, tx, uuid.Nil); err != nil {
+			return TaskDropResult{}, err
+		}
+		if result.SourceSiblings, err = source.tasks(ctx, tx); err != nil {
+			return TaskDropResult{}, err
+		}
+	}
+	if result.Task, err = getTask(ctx, tx, drop.TaskID); err != nil {
+		return TaskDropResult{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return TaskDropResult{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// relocateTask moves taskID and its subtree under parentTaskID (or to the top level of projectID)
+// with the order returned by place, and returns the sibling lists the task left and joined.
+// When parentTaskID is set the task joins the parent's project and projectID is ignored.
+func relocateTask(ctx context.Context, tx pgx.Tx, taskID, userID uuid.UUID, projectID, parentTaskID *uuid.UUID, place func(dest siblingList) (int, error)) (source, dest siblingList, err error) {
+	source.userID = userID
+	err = tx.QueryRow(ctx, `SELECT project_id, parent_task_id FROM tasks WHERE task_id = $1 AND user_id = $2 FOR UPDATE`, taskID, userID).Scan(&source.projectID, &source.parentTaskID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return source, dest, ErrNoRecord
+		}
+		return source, dest, fmt.Errorf("unable to fetch task: %w", err)
+	}
+
+	if parentTaskID != nil {
+		inSubtree, err := isInSubtree(ctx, tx, taskID, *parentTaskID)
+		if err != nil {
+			return source, dest, err
+		}
+		if inSubtree {
+			return source, dest, ErrMoveIntoSubtree
+		}
+
+		err = tx.QueryRow(ctx, `SELECT project_id FROM tasks WHERE task_id = $1 AND user_id = $2`, *parentTaskID, userID).Scan(&projectID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return source, dest, ErrNoRecord
+			}
+			return source, dest, fmt.Errorf("unable to fetch parent task: %w", err)
+		}
+	} else if projectID != nil {
+		var exists bool
+		err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM projects WHERE project_id = $1 AND user_id = $2)`, *projectID, userID).Scan(&exists)
+		if err != nil {
+			return source, dest, fmt.Errorf("unable to fetch project: %w", err)
+		}
+		if !exists {
+			return source, dest, ErrNoRecord
+		}
+	}
+
+	dest = siblingList{userID: userID, projectID: projectID, parentTaskID: parentTaskID}
+	order, err := place(dest)
+	if err != nil {
+		return source, dest, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE tasks SET project_id = $2, parent_task_id = $3, "order" = $4 WHERE task_id = $1`, taskID, projectID, parentTaskID, order)
+	if err != nil {
+		return source, dest, fmt.Errorf("unable to move task: %w", err)
+	}
+
+	// Subtasks keep their parents and order but follow the task into its new project.
+	_, err = tx.Exec(ctx, `
+		WITH RECURSIVE descendants(task_id) AS (
+			SELECT task_id FROM tasks WHERE parent_task_id = $1
+			UNION
+			SELECT t.task_id FROM tasks t JOIN descendants d ON t.parent_task_id = d.task_id
+		)
+		UPDATE tasks SET project_id = $2
+		WHERE task_id IN (SELECT task_id FROM descendants) AND project_id IS DISTINCT FROM $2::uuid`,
+		taskID, projectID)
+	if err != nil {
+		return source, dest, fmt.Errorf("unable to move subtasks: %w", err)
+	}
+	return source, dest, nil
+}
+
+// isInSubtree reports whether candidateID is rootID or one of its descendants.
+func isInSubtree(ctx context.Context, tx pgx.Tx, rootID, candidateID uuid.UUID) (bool, error) {
+	query := `
+		WITH RECURSIVE subtree(task_id) AS (
+			SELECT $1::uuid
+			UNION
+			SELECT t.task_id FROM tasks t JOIN subtree s ON t.parent_task_id = s.task_id
+		)
+		SELECT EXISTS (SELECT 1 FROM subtree WHERE task_id = $2)`
+
+	var inSubtree bool
+	if err := tx.QueryRow(ctx, query, rootID, candidateID).Scan(&inSubtree); err != nil {
+		return false, fmt.Errorf("unable to check task subtree: %w", err)
+	}
+	return inSubtree, nil
+}
@@ -0,0 +1,277 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// OrderGap is the spacing between the orders of neighbouring tasks after a rebalance. New tasks
+// are appended OrderGap after the last sibling and a positioned task takes the midpoint of its
+// neighbours, so a single row is updated until the gap between two neighbours runs out.
+const OrderGap = 1024
+
+// maxOrderMagnitude bounds how far orders may drift from zero before the rebalance worker
+// spreads the list out again. Repeatedly inserting at the front or back moves them by OrderGap.
+const maxOrderMagnitude = 1 << 30
+
+// TaskPosition places a task between two of its siblings. BeforeID is the sibling the task
+// should come before and AfterID the one it should come after; when both are set they must be
+// neighbours. With only BeforeID the task goes directly in front of it, with only AfterID
+// directly behind it, and with neither at the end of the list.
+type TaskPosition struct {
+	BeforeID *uuid.UUID `json:"before_id"`
+	AfterID  *uuid.UUID `json:"after_id"`
+}
+
+// PositionTask moves a task within its sibling list, normally by updating only its own order.
+// It returns ErrNoRecord if the task or a neighbour doesn't exist, ErrNotSiblings if a neighbour
+// has a different parent or project, and ErrInvalidPosition if the neighbours aren't adjacent.
+func (m *TaskModel) PositionTask(taskID, userID uuid.UUID, pos TaskPosition) (Task, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return Task{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var projectID, parentTaskID *uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT project_id, parent_task_id FROM tasks WHERE task_id = $1 AND user_id = $2 FOR UPDATE`, taskID, userID).Scan(&projectID, &parentTaskID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Task{}, ErrNoRecord
+		}
+		return Task{}, fmt.Errorf("unable to fetch task: %w", err)
+	}
+
+	list := siblingList{userID: userID, projectID: projectID, parentTaskID: parentTaskID}
+	order, err := list.orderAt(ctx, tx, taskID, pos)
+	if err != nil {
+		return Task{}, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE tasks SET "order" = $2 WHERE task_id = $1`, taskID, order); err != nil {
+		return Task{}, fmt.Errorf("unable to update task order: %w", err)
+	}
+
+	positioned, err := getTask(ctx, tx, taskID)
+	if err != nil {
+		return Task{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Task{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return positioned, nil
+}
+
+// siblingList identifies the tasks that share an owner, project and parent.
+type siblingList struct {
+	userID       uuid.UUID
+	projectID    *uuid.UUID
+	parentTaskID *uuid.UUID
+}
+
+// siblingListWhere matches the tasks of a siblingList given as $1, $2 and $3.
+const siblingListWhere = `user_id = $1 AND project_id IS NOT DISTINCT FROM $2::uuid AND parent_task_id IS NOT DISTINCT FROM $3::uuid`
+
+// same reports whether l and o are the same list.
+func (l siblingList) same(o siblingList) bool {
+	return l.userID == o.userID && sameUUID(l.projectID, o.projectID) && sameUUID(l.parentTaskID, o.parentTaskID)
+}
+
+// tasks returns the tasks in the list in display order.
+func (l siblingList) tasks(ctx context.Context, tx pgx.Tx) ([]Task, error) {
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE ` + siblingListWhere + `
+		ORDER BY "order" ASC, created_at ASC`
+
+	return queryTasks(ctx, tx, query, l.userID, l.projectID, l.parentTaskID)
+}
+
+// nextOrder returns the order that appends a task after every task in the list.
+func (l siblingList) nextOrder(ctx context.Context, tx pgx.Tx) (int, error) {
+	var order int
+	err := tx.QueryRow(ctx, `SELECT COALESCE(max("order") + $4, 0) FROM tasks WHERE `+siblingListWhere,
+		l.userID, l.projectID, l.parentTaskID, OrderGap).Scan(&order)
+	if err != nil {
+		return 0, fmt.Errorf("unable to find next task order: %w", err)
+	}
+	return order, nil
+}
+
+// orderAt returns an order that puts taskID at pos, which may or may not already be in the list.
+// When the neighbours have no room between them the list is rebalanced first.
+func (l siblingList) orderAt(ctx context.Context, tx pgx.Tx, taskID uuid.UUID, pos TaskPosition) (int, error) {
+	if pos.BeforeID == nil && pos.AfterID == nil {
+		return l.nextOrder(ctx, tx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		lo, hi, err := l.neighbourOrders(ctx, tx, taskID, pos)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case lo == nil:
+			return *hi - OrderGap, nil
+		case hi == nil:
+			return *lo + OrderGap, nil
+		case *hi-*lo > 1:
+			return *lo + (*hi-*lo)/2, nil
+		}
+		if attempt > 0 {
+			return 0, fmt.Errorf("no room between sibling tasks after rebalancing")
+		}
+		if err := l.rebalance(ctx, tx, taskID); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// orderAtIndex returns an order that puts taskID at the zero-based index among the other tasks
+// in the list, appending it when index is past the end.
+func (l siblingList) orderAtIndex(ctx context.Context, tx pgx.Tx, taskID uuid.UUID, index int) (int, error) {
+	var beforeID uuid.UUID
+	err := tx.QueryRow(ctx, `
+		SELECT task_id FROM tasks
+		WHERE `+siblingListWhere+` AND task_id <> $4
+		ORDER BY "order" ASC, created_at ASC
+		OFFSET $5 LIMIT 1`,
+		l.userID, l.projectID, l.parentTaskID, taskID, index).Scan(&beforeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return l.nextOrder(ctx, tx)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to fetch sibling tasks: %w", err)
+	}
+	return l.orderAt(ctx, tx, taskID, TaskPosition{BeforeID: &beforeID})
+}
+
+// neighbourOrders resolves pos to the orders of the siblings directly before (lo) and after (hi)
+// the new slot, ignoring taskID itself. A nil bound means the slot is at that end of the list.
+func (l siblingList) neighbourOrders(ctx context.Context, tx pgx.Tx, taskID uuid.UUID, pos TaskPosition) (lo, hi *int, err error) {
+	// The rows are the list without taskID, numbered in display order.
+	query := `
+		WITH siblings AS (
+			SELECT task_id, "order", ROW_NUMBER() OVER (ORDER BY "order" ASC, created_at ASC) AS rn
+			FROM tasks
+			WHERE ` + siblingListWhere + ` AND task_id <> $4
+		)
+		SELECT
+			(SELECT rn FROM siblings WHERE task_id = $5),
+			(SELECT rn FROM siblings WHERE task_id = $6),
+			array_agg("order" ORDER BY rn)
+		FROM siblings`
+
+	var afterRank, beforeRank *int
+	var orders []int
+	err = tx.QueryRow(ctx, query, l.userID, l.projectID, l.parentTaskID, taskID, pos.AfterID, pos.BeforeID).Scan(&afterRank, &beforeRank, &orders)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to fetch sibling tasks: %w", err)
+	}
+
+	for _, ref := range []struct {
+		id   *uuid.UUID
+		rank *int
+	}{{pos.AfterID, afterRank}, {pos.BeforeID, beforeRank}} {
+		if ref.id == nil || ref.rank != nil {
+			continue
+		}
+		if err := l.checkSibling(ctx, tx, *ref.id, taskID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Ranks are 1-based, so orders[rank-1] is the neighbour itself.
+	switch {
+	case afterRank != nil && beforeRank != nil:
+		if *beforeRank != *afterRank+1 {
+			return nil, nil, ErrInvalidPosition
+		}
+		return &orders[*afterRank-1], &orders[*beforeRank-1], nil
+	case afterRank != nil:
+		lo = &orders[*afterRank-1]
+		if *afterRank < len(orders) {
+			hi = &orders[*afterRank]
+		}
+	default:
+		hi = &orders[*beforeRank-1]
+		if *beforeRank > 1 {
+			lo = &orders[*beforeRank-2]
+		}
+	}
+	return lo, hi, nil
+}
+
+// checkSibling explains why id isn't in the list: it is taskID itself, belongs to another
+// list, or doesn't exist.
+func (l siblingList) checkSibling(ctx context.Context, tx pgx.Tx, id, taskID uuid.UUID) error {
+	if id == taskID {
+		return ErrInvalidPosition
+	}
+	var exists bool
+	err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM tasks WHERE task_id = $1 AND user_id = $2)`, id, l.userID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("unable to fetch task: %w", err)
+	}
+	if !exists {
+		return ErrNoRecord
+	}
+	return ErrNotSiblings
+}
+
+// rebalance spreads the list out to multiples of OrderGap, keeping the display order. The task
+// identified by exclude is left out, so a task being positioned doesn't hold a slot.
+func (l siblingList) rebalance(ctx context.Context, tx pgx.Tx, exclude uuid.UUID) error {
+	query := `
+		UPDATE tasks t SET "order" = s.position
+		FROM (
+			SELECT task_id, (ROW_NUMBER() OVER (ORDER BY "order" ASC, created_at ASC) - 1) * $5 AS position
+			FROM tasks
+			WHERE ` + siblingListWhere + ` AND task_id <> $4
+		) s
+		WHERE t.task_id = s.task_id AND t."order" <> s.position`
+
+	if _, err := tx.Exec(ctx, query, l.userID, l.projectID, l.parentTaskID, exclude, OrderGap); err != nil {
+		return fmt.Errorf("unable to rebalance sibling tasks: %w", err)
+	}
+	return nil
+}
+
+// RebalanceTaskOrders respreads every sibling list whose neighbours have run out of room (or tie)
+// or whose orders have drifted past maxOrderMagnitude, and returns how many tasks were renumbered.
+func (m *TaskModel) RebalanceTaskOrders(ctx context.Context) (int64, error) {
+	query := `
+		WITH ranked AS (
+			SELECT user_id, project_id, parent_task_id, "order",
+				"order" - lag("order") OVER (PARTITION BY user_id, project_id, parent_task_id ORDER BY "order" ASC, created_at ASC) AS gap
+			FROM tasks
+		), crowded AS (
+			SELECT DISTINCT user_id, project_id, parent_task_id
+			FROM ranked
+			WHERE gap < 2 OR abs("order") > $1
+		), renumbered AS (
+			SELECT t.task_id,
+				(ROW_NUMBER() OVER (PARTITION BY t.user_id, t.project_id, t.parent_task_id ORDER BY t."order" ASC, t.created_at ASC) - 1) * $2 AS position
+			FROM tasks t
+			JOIN crowded c ON c.user_id = t.user_id
+				AND c.project_id IS NOT DISTINCT FROM t.project_id
+				AND c.parent_task_id IS NOT DISTINCT FROM t.parent_task_id
+		)
+		UPDATE tasks t SET "order" = r.position
+		FROM renumbered r
+		WHERE t.task_id = r.task_id AND t."order" <> r.position`
+
+	result, err := m.DB.Exec(ctx, query, maxOrderMagnitude, OrderGap)
+	if err != nil {
+		return 0, fmt.Errorf("unable to rebalance task orders: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,54 @@
+package models_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/dmcleish91/go_todo_api/internal/migrations"
+	"github.com/dmcleish91/go_todo_api/internal/models"
+	"github.com/dmcleish91/go_todo_api/internal/models/storetest"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TestPostgresStore runs the conformance suite against the database in TEST_DATABASE_URL.
+// The database is migrated first; outside Supabase a minimal auth.users table is created for
+// the foreign keys to point at. Tests leave their rows behind, so use a throwaway database.
+func TestPostgresStore(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("unable to connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	_, err = pool.Exec(ctx, `CREATE SCHEMA IF NOT EXISTS auth;
+		CREATE TABLE IF NOT EXISTS auth.users (id uuid PRIMARY KEY)`)
+	if err != nil {
+		t.Fatalf("unable to create auth.users: %v", err)
+	}
+	if _, err := (&migrations.Migrator{DB: pool}).Up(ctx); err != nil {
+		t.Fatalf("unable to migrate: %v", err)
+	}
+
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		return storetest.Stores{
+			Tasks:    &models.TaskModel{DB: pool},
+			Projects: &models.ProjectModel{DB: pool},
+			Labels:   &models.LabelModel{DB: pool},
+			NewUser: func(t *testing.T) uuid.UUID {
+				id := uuid.New()
+				if _, err := pool.Exec(ctx, `INSERT INTO auth.users (id) VALUES ($1)`, id); err != nil {
+					t.Fatalf("unable to create user: %v", err)
+				}
+				return id
+			},
+		}
+	})
+}
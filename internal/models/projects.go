@@ -2,10 +2,13 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -16,6 +19,9 @@ type Project struct {
 	Color           *string    `json:"color"`
 	IsInbox         *bool      `json:"is_inbox"`
 	ParentProjectID *uuid.UUID `json:"parent_project_id"`
+	IsArchived      bool       `json:"is_archived"` // set with ArchiveProject, not EditProjectByID
+	IsFavorite      bool       `json:"is_favorite"`
+	Order           int        `json:"order"` // position among sibling projects; set with BulkUpdateProjectOrder
 	CreatedAt       time.Time  `json:"created_at"`
 }
 
@@ -23,17 +29,38 @@ type ProjectModel struct {
 	DB *pgxpool.Pool
 }
 
+// projectColumns is the column list shared by every query that returns a full Project.
+// It must stay in sync with scanProject.
+const projectColumns = `project_id, user_id, project_name, color, is_inbox, parent_project_id, is_archived, is_favorite, "order", created_at`
+
+// scanProject scans a row selected with projectColumns into project.
+func scanProject(row pgx.Row, project *Project) error {
+	return row.Scan(
+		&project.ProjectID,
+		&project.UserID,
+		&project.ProjectName,
+		&project.Color,
+		&project.IsInbox,
+		&project.ParentProjectID,
+		&project.IsArchived,
+		&project.IsFavorite,
+		&project.Order,
+		&project.CreatedAt,
+	)
+}
+
+// AddProject creates a project after its existing siblings.
 func (m *ProjectModel) AddProject(project Project) (Project, error) {
 	query := `
 		INSERT INTO projects (
-			user_id, project_name, color, is_inbox, parent_project_id
+			user_id, project_name, color, is_inbox, parent_project_id, is_favorite, "order"
 		) VALUES (
-			$1, $2, $3, $4, $5
-		) RETURNING project_id, user_id, project_name, color, is_inbox, parent_project_id, created_at
-	`
+			$1, $2, $3, $4, $5, $6,
+			(SELECT COALESCE(max("order") + 1, 0) FROM projects WHERE user_id = $1 AND parent_project_id IS NOT DISTINCT FROM $5::uuid)
+		) RETURNING ` + projectColumns
 
 	var createdProject Project
-	err := m.DB.QueryRow(
+	err := scanProject(m.DB.QueryRow(
 		context.Background(),
 		query,
 		project.UserID,
@@ -41,15 +68,8 @@ func (m *ProjectModel) AddProject(project Project) (Project, error) {
 		project.Color,
 		project.IsInbox,
 		project.ParentProjectID,
-	).Scan(
-		&createdProject.ProjectID,
-		&createdProject.UserID,
-		&createdProject.ProjectName,
-		&createdProject.Color,
-		&createdProject.IsInbox,
-		&createdProject.ParentProjectID,
-		&createdProject.CreatedAt,
-	)
+		project.IsFavorite,
+	), &createdProject)
 
 	if err != nil {
 		return Project{}, fmt.Errorf("unable to execute query: %v", err)
@@ -64,13 +84,13 @@ func (m *ProjectModel) EditProjectByID(project Project) (Project, error) {
 			project_name = $3,
 			color = $4,
 			is_inbox = $5,
-			parent_project_id = $6
+			parent_project_id = $6,
+			is_favorite = $7
 		WHERE project_id = $1 AND user_id = $2
-		RETURNING project_id, user_id, project_name, color, is_inbox, parent_project_id, created_at
-	`
+		RETURNING ` + projectColumns
 
 	var updatedProject Project
-	err := m.DB.QueryRow(
+	err := scanProject(m.DB.QueryRow(
 		context.Background(),
 		query,
 		project.ProjectID,
@@ -79,15 +99,8 @@ func (m *ProjectModel) EditProjectByID(project Project) (Project, error) {
 		project.Color,
 		project.IsInbox,
 		project.ParentProjectID,
-	).Scan(
-		&updatedProject.ProjectID,
-		&updatedProject.UserID,
-		&updatedProject.ProjectName,
-		&updatedProject.Color,
-		&updatedProject.IsInbox,
-		&updatedProject.ParentProjectID,
-		&updatedProject.CreatedAt,
-	)
+		project.IsFavorite,
+	), &updatedProject)
 
 	if err != nil {
 		return Project{}, fmt.Errorf("unable to execute query: %v", err)
@@ -96,14 +109,16 @@ func (m *ProjectModel) EditProjectByID(project Project) (Project, error) {
 	return updatedProject, nil
 }
 
-func (m *ProjectModel) GetProjectsByUserID(userID uuid.UUID) ([]Project, error) {
+// GetProjectsByUserID returns the user's projects in their manual order. Archived projects are
+// only included when includeArchived is set.
+func (m *ProjectModel) GetProjectsByUserID(userID uuid.UUID, includeArchived bool) ([]Project, error) {
 	query := `
-		SELECT project_id, user_id, project_name, color, is_inbox, parent_project_id, created_at
+		SELECT ` + projectColumns + `
 		FROM projects
-		WHERE user_id = $1
-		ORDER BY created_at ASC`
+		WHERE user_id = $1 AND ($2 OR NOT is_archived)
+		ORDER BY "order" ASC, created_at ASC`
 
-	rows, err := m.DB.Query(context.Background(), query, userID)
+	rows, err := m.DB.Query(context.Background(), query, userID, includeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("unable to query projects: %v", err)
 	}
@@ -113,16 +128,7 @@ func (m *ProjectModel) GetProjectsByUserID(userID uuid.UUID) ([]Project, error)
 
 	for rows.Next() {
 		var project Project
-		err := rows.Scan(
-			&project.ProjectID,
-			&project.UserID,
-			&project.ProjectName,
-			&project.Color,
-			&project.IsInbox,
-			&project.ParentProjectID,
-			&project.CreatedAt,
-		)
-		if err != nil {
+		if err := scanProject(rows, &project); err != nil {
 			return nil, fmt.Errorf("unable to scan row: %v", err)
 		}
 		projects = append(projects, project)
@@ -131,6 +137,101 @@ func (m *ProjectModel) GetProjectsByUserID(userID uuid.UUID) ([]Project, error)
 	return projects, nil
 }
 
+// ArchiveProject archives or unarchives a project together with all of its sub-projects and
+// returns the updated projects.
+func (m *ProjectModel) ArchiveProject(projectID, userID uuid.UUID, archived bool) ([]Project, error) {
+	query := `
+		WITH RECURSIVE subtree(project_id) AS (
+			SELECT project_id FROM projects WHERE project_id = $1 AND user_id = $2
+			UNION
+			SELECT p.project_id FROM projects p JOIN subtree s ON p.parent_project_id = s.project_id
+		)
+		UPDATE projects SET is_archived = $3
+		WHERE project_id IN (SELECT project_id FROM subtree)
+		RETURNING ` + projectColumns
+
+	rows, err := m.DB.Query(context.Background(), query, projectID, userID, archived)
+	if err != nil {
+		return nil, fmt.Errorf("unable to archive project: %w", err)
+	}
+	projects, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Project, error) {
+		var p Project
+		err := scanProject(row, &p)
+		return p, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan row: %w", err)
+	}
+	if len(projects) == 0 {
+		return nil, ErrNoRecord
+	}
+	return projects, nil
+}
+
+// ProjectOrderUpdate sets the order of one project in BulkUpdateProjectOrder.
+type ProjectOrderUpdate struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	Order     int       `json:"order"`
+}
+
+// ValidOrder reports whether order fits the 32-bit "order" columns of projects and tasks.
+func ValidOrder(order int) bool {
+	return order >= math.MinInt32 && order <= math.MaxInt32
+}
+
+// BulkUpdateProjectOrder sets the order of sibling projects in one statement. Every project must
+// be listed once (ErrInvalidReorder otherwise), belong to userID (ErrNoRecord otherwise) and
+// share the same parent (ErrNotSiblings otherwise).
+func (m *ProjectModel) BulkUpdateProjectOrder(userID uuid.UUID, updates []ProjectOrderUpdate) error {
+	ctx := context.Background()
+
+	ids := make([]uuid.UUID, len(updates))
+	orders := make([]int32, len(updates))
+	for i, u := range updates {
+		ids[i] = u.ProjectID
+		orders[i] = int32(u.Order)
+	}
+	// The UPDATE would pick one of several rows for the same project arbitrarily.
+	if len(uniqueUUIDs(ids)) != len(ids) {
+		return ErrInvalidReorder
+	}
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var owned, parents int
+	err = tx.QueryRow(ctx, `
+		SELECT count(*), count(DISTINCT COALESCE(parent_project_id, '00000000-0000-0000-0000-000000000000'))
+		FROM projects
+		WHERE project_id = ANY($1) AND user_id = $2`, ids, userID).Scan(&owned, &parents)
+	if err != nil {
+		return fmt.Errorf("unable to fetch projects: %w", err)
+	}
+	if owned != len(ids) {
+		return ErrNoRecord
+	}
+	if parents > 1 {
+		return ErrNotSiblings
+	}
+
+	query := `
+		UPDATE projects p SET "order" = u.new_order
+		FROM unnest($1::uuid[], $2::int[]) AS u(project_id, new_order)
+		WHERE p.project_id = u.project_id AND p.user_id = $3`
+
+	if _, err := tx.Exec(ctx, query, ids, orders, userID); err != nil {
+		return fmt.Errorf("unable to update project order: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
 func (m *ProjectModel) DeleteProjectByID(projectID uuid.UUID, userID uuid.UUID) (int64, error) {
 	query := `DELETE FROM projects WHERE project_id = $1 AND user_id = $2`
 
@@ -141,3 +242,91 @@ func (m *ProjectModel) DeleteProjectByID(projectID uuid.UUID, userID uuid.UUID)
 
 	return result.RowsAffected(), nil
 }
+
+// ProjectMember grants a user other than the owner access to a shared project.
+type ProjectMember struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddProjectMember shares a project owned by ownerID with memberID.
+// It returns ErrNoRecord if ownerID does not own the project.
+func (m *ProjectModel) AddProjectMember(projectID, ownerID, memberID uuid.UUID) (ProjectMember, error) {
+	query := `
+		INSERT INTO project_members (project_id, user_id)
+		SELECT project_id, $3 FROM projects WHERE project_id = $1 AND user_id = $2
+		ON CONFLICT (project_id, user_id) DO UPDATE SET created_at = project_members.created_at
+		RETURNING project_id, user_id, created_at`
+
+	var member ProjectMember
+	err := m.DB.QueryRow(context.Background(), query, projectID, ownerID, memberID).Scan(
+		&member.ProjectID,
+		&member.UserID,
+		&member.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ProjectMember{}, ErrNoRecord
+		}
+		return ProjectMember{}, fmt.Errorf("unable to add project member: %w", err)
+	}
+	return member, nil
+}
+
+// GetProjectMembers lists the members of a project the user owns or is a member of.
+func (m *ProjectModel) GetProjectMembers(projectID, userID uuid.UUID) ([]ProjectMember, error) {
+	query := `
+		SELECT pm.project_id, pm.user_id, pm.created_at
+		FROM project_members pm
+		JOIN projects p ON p.project_id = pm.project_id
+		WHERE pm.project_id = $1
+			AND (p.user_id = $2 OR EXISTS (
+				SELECT 1 FROM project_members me WHERE me.project_id = pm.project_id AND me.user_id = $2
+			))
+		ORDER BY pm.created_at ASC`
+
+	rows, err := m.DB.Query(context.Background(), query, projectID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query project members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []ProjectMember
+	for rows.Next() {
+		var member ProjectMember
+		if err := rows.Scan(&member.ProjectID, &member.UserID, &member.CreatedAt); err != nil {
+			return nil, fmt.Errorf("unable to scan row: %w", err)
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// RemoveProjectMember revokes memberID's access to a project owned by ownerID.
+func (m *ProjectModel) RemoveProjectMember(projectID, ownerID, memberID uuid.UUID) (int64, error) {
+	query := `
+		DELETE FROM project_members pm
+		USING projects p
+		WHERE pm.project_id = p.project_id
+			AND pm.project_id = $1 AND p.user_id = $2 AND pm.user_id = $3`
+
+	result, err := m.DB.Exec(context.Background(), query, projectID, ownerID, memberID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to remove project member: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// IsProjectMember reports whether userID owns the project or has been added as a member.
+func (m *ProjectModel) IsProjectMember(projectID, userID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM projects WHERE project_id = $1 AND user_id = $2)
+			OR EXISTS (SELECT 1 FROM project_members WHERE project_id = $1 AND user_id = $2)`
+
+	var ok bool
+	if err := m.DB.QueryRow(context.Background(), query, projectID, userID).Scan(&ok); err != nil {
+		return false, fmt.Errorf("unable to check project membership: %w", err)
+	}
+	return ok, nil
+}
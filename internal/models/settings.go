@@ -0,0 +1,101 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Defaults for users who haven't saved settings.
+const (
+	DefaultDailyGoal = 5
+	DefaultTimezone  = "UTC"
+)
+
+// UserSettings holds per-user preferences. Users without a stored row get DefaultSettings.
+type UserSettings struct {
+	UserID                        uuid.UUID `json:"user_id"`
+	CompleteSubtasksWithParent    bool      `json:"complete_subtasks_with_parent"`
+	CompleteParentWithLastSubtask bool      `json:"complete_parent_with_last_subtask"`
+	DailyGoal                     int       `json:"daily_goal"` // completions per day that count towards a streak
+	Timezone                      string    `json:"timezone"`   // IANA name used to bucket days for stats
+	UpdatedAt                     time.Time `json:"updated_at"`
+}
+
+// DefaultSettings returns the settings used for a user who hasn't saved any.
+func DefaultSettings(userID uuid.UUID) UserSettings {
+	return UserSettings{UserID: userID, DailyGoal: DefaultDailyGoal, Timezone: DefaultTimezone}
+}
+
+type SettingsModel struct {
+	DB *pgxpool.Pool
+}
+
+const settingsColumns = `user_id, complete_subtasks_with_parent, complete_parent_with_last_subtask, daily_goal, timezone, updated_at`
+
+func scanSettings(row pgx.Row, settings *UserSettings) error {
+	return row.Scan(
+		&settings.UserID,
+		&settings.CompleteSubtasksWithParent,
+		&settings.CompleteParentWithLastSubtask,
+		&settings.DailyGoal,
+		&settings.Timezone,
+		&settings.UpdatedAt,
+	)
+}
+
+// GetSettings returns the user's settings, or the defaults if none have been saved.
+func (m *SettingsModel) GetSettings(userID uuid.UUID) (UserSettings, error) {
+	query := `SELECT ` + settingsColumns + ` FROM user_settings WHERE user_id = $1`
+
+	var settings UserSettings
+	err := scanSettings(m.DB.QueryRow(context.Background(), query, userID), &settings)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return DefaultSettings(userID), nil
+	}
+	if err != nil {
+		return UserSettings{}, fmt.Errorf("unable to get settings: %w", err)
+	}
+	return settings, nil
+}
+
+// UpdateSettings creates or replaces the settings for settings.UserID.
+func (m *SettingsModel) UpdateSettings(settings UserSettings) (UserSettings, error) {
+	query := `
+		INSERT INTO user_settings (user_id, complete_subtasks_with_parent, complete_parent_with_last_subtask, daily_goal, timezone)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			complete_subtasks_with_parent = EXCLUDED.complete_subtasks_with_parent,
+			complete_parent_with_last_subtask = EXCLUDED.complete_parent_with_last_subtask,
+			daily_goal = EXCLUDED.daily_goal,
+			timezone = EXCLUDED.timezone,
+			updated_at = now()
+		RETURNING ` + settingsColumns
+
+	var updated UserSettings
+	err := scanSettings(m.DB.QueryRow(
+		context.Background(),
+		query,
+		settings.UserID,
+		settings.CompleteSubtasksWithParent,
+		settings.CompleteParentWithLastSubtask,
+		settings.DailyGoal,
+		settings.Timezone,
+	), &updated)
+	if err != nil {
+		return UserSettings{}, fmt.Errorf("unable to update settings: %w", err)
+	}
+	return updated, nil
+}
+
+// ValidateSettings checks the daily goal and that the timezone is a known IANA name.
+func ValidateSettings(settings *UserSettings, v *Validator) {
+	v.Check(settings.DailyGoal >= 1 && settings.DailyGoal <= 1000, "daily_goal", "Daily goal must be between 1 and 1000")
+	_, err := time.LoadLocation(settings.Timezone)
+	v.Check(settings.Timezone != "" && err == nil, "timezone", "Timezone must be an IANA name like Europe/Berlin")
+}
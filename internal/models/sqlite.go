@@ -0,0 +1,287 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteStore keeps tasks, projects, labels, settings, templates and idempotency keys in a
+// single SQLite file, with the semantics of the Postgres models. It uses a pure-Go driver, so
+// it needs neither cgo nor a database server. The schema is created by
+// migrations.SQLiteMigrator.
+//
+// Writes take SQLite's database-wide write lock for the length of their transaction, so they
+// are serialised; reads run concurrently thanks to the write-ahead log.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens (creating if needed) the SQLite database at path. Foreign keys are enforced,
+// the write-ahead log is enabled and transactions take the write lock when they begin, so two
+// transactions can't deadlock upgrading their locks.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	dsn := "file:" + path + "?" + url.Values{
+		"_pragma": {"foreign_keys(1)", "journal_mode(WAL)", "busy_timeout(5000)"},
+		"_txlock": {"immediate"},
+	}.Encode()
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open sqlite database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to open sqlite database: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// DB returns the underlying database, for migrations and health checks.
+func (s *SQLiteStore) DB() *sql.DB { return s.db }
+
+// Close closes the database.
+func (s *SQLiteStore) Close() error { return s.db.Close() }
+
+// Tasks returns the store's TaskStore.
+func (s *SQLiteStore) Tasks() TaskStore { return &sqliteTasks{db: sqliteConn{db: s.db}} }
+
+// Projects returns the store's ProjectStore.
+func (s *SQLiteStore) Projects() ProjectStore { return &sqliteProjects{db: sqliteConn{db: s.db}} }
+
+// Labels returns the store's LabelStore.
+func (s *SQLiteStore) Labels() LabelStore { return &sqliteLabels{db: sqliteConn{db: s.db}} }
+
+// Settings returns the store's SettingsStore.
+func (s *SQLiteStore) Settings() SettingsStore { return &sqliteSettings{db: sqliteConn{db: s.db}} }
+
+// Templates returns the store's TemplateStore.
+func (s *SQLiteStore) Templates() TemplateStore { return &sqliteTemplates{db: sqliteConn{db: s.db}} }
+
+// Idempotency returns the store's IdempotencyStore.
+func (s *SQLiteStore) Idempotency() IdempotencyStore {
+	return &sqliteIdempotency{db: sqliteConn{db: s.db}}
+}
+
+var (
+	_ TaskStore        = (*sqliteTasks)(nil)
+	_ ProjectStore     = (*sqliteProjects)(nil)
+	_ LabelStore       = (*sqliteLabels)(nil)
+	_ SettingsStore    = (*sqliteSettings)(nil)
+	_ TemplateStore    = (*sqliteTemplates)(nil)
+	_ IdempotencyStore = (*sqliteIdempotency)(nil)
+)
+
+// sqliteQuerier is satisfied by both *sql.DB and *sql.Tx.
+type sqliteQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// sqliteConn runs statements on the database, or inside tx once a transaction has begun. It
+// plays the part DBTX plays for the Postgres models.
+type sqliteConn struct {
+	db *sql.DB
+	tx *sql.Tx
+	// depth counts the savepoints open inside tx.
+	depth int
+}
+
+// q returns what statements should run on.
+func (c sqliteConn) q() sqliteQuerier {
+	if c.tx != nil {
+		return c.tx
+	}
+	return c.db
+}
+
+// begin runs fn in a new transaction, or in a savepoint when c is already in one. The changes
+// fn makes are kept only if it returns nil.
+func (c sqliteConn) begin(ctx context.Context, fn func(c sqliteConn) error) error {
+	if c.tx == nil {
+		tx, err := c.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		if err := fn(sqliteConn{tx: tx}); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	}
+
+	savepoint := fmt.Sprintf("sp_%d", c.depth+1)
+	if _, err := c.tx.ExecContext(ctx, `SAVEPOINT `+savepoint); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(sqliteConn{tx: c.tx, depth: c.depth + 1}); err != nil {
+		if _, rbErr := c.tx.ExecContext(ctx, `ROLLBACK TO `+savepoint+`; RELEASE `+savepoint); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to roll back savepoint: %w", rbErr))
+		}
+		return err
+	}
+	if _, err := c.tx.ExecContext(ctx, `RELEASE `+savepoint); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// sqliteCollect scans every row of a query with scan, closing the rows.
+func sqliteCollect[T any](rows *sql.Rows, err error, scan func(rows *sql.Rows, v *T) error) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []T
+	for rows.Next() {
+		var v T
+		if err := scan(rows, &v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// rowsAffected returns how many rows a statement changed.
+func rowsAffected(result sql.Result) int64 {
+	n, _ := result.RowsAffected()
+	return n
+}
+
+// isSQLiteUniqueViolation reports whether err is a SQLite unique constraint violation.
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+}
+
+// Timestamps are stored as fixed-width UTC text so they compare correctly as strings. Dates and
+// times of day are stored the way Postgres prints its date and time types.
+const (
+	sqliteTimestampLayout = "2006-01-02T15:04:05.000000Z"
+	sqliteDateLayout      = "2006-01-02"
+	sqliteClockLayout     = "15:04:05.000000"
+)
+
+// sqliteNow returns the current time at the precision timestamps are stored with.
+func sqliteNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// sqliteTimestamp formats t for a timestamp column.
+func sqliteTimestamp(t time.Time) string {
+	return t.UTC().Format(sqliteTimestampLayout)
+}
+
+// sqliteNullTimestamp formats t for a nullable timestamp column.
+func sqliteNullTimestamp(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return sqliteTimestamp(*t)
+}
+
+// sqliteDate formats the calendar date of t, in t's location, for a date column.
+func sqliteDate(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Format(sqliteDateLayout)
+}
+
+// sqliteClock formats the time of day of t, in t's location, for a time column.
+func sqliteClock(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Format(sqliteClockLayout)
+}
+
+// sqliteTime scans a column written by sqliteTimestamp, sqliteDate or sqliteClock into dst,
+// which is a *time.Time or, for nullable columns, a **time.Time. Dates come back as midnight
+// UTC and times of day on 2000-01-01 UTC, as pgx returns them.
+type sqliteTime struct {
+	dst    any
+	layout string
+}
+
+func scanTimestamp(dst *time.Time) sqliteTime      { return sqliteTime{dst, time.RFC3339Nano} }
+func scanNullTimestamp(dst **time.Time) sqliteTime { return sqliteTime{dst, time.RFC3339Nano} }
+func scanDate(dst **time.Time) sqliteTime          { return sqliteTime{dst, sqliteDateLayout} }
+func scanClock(dst **time.Time) sqliteTime         { return sqliteTime{dst, "15:04:05.999999999"} }
+
+func (s sqliteTime) Scan(src any) error {
+	var text string
+	switch v := src.(type) {
+	case nil:
+		if dst, ok := s.dst.(**time.Time); ok {
+			*dst = nil
+			return nil
+		}
+		return errors.New("models: cannot scan NULL into time.Time")
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("models: cannot scan %T into time.Time", src)
+	}
+
+	t, err := time.ParseInLocation(s.layout, text, time.UTC)
+	if err != nil {
+		return err
+	}
+	if t.Year() == 0 {
+		t = time.Date(2000, 1, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	}
+
+	switch dst := s.dst.(type) {
+	case *time.Time:
+		*dst = t
+	case **time.Time:
+		*dst = &t
+	}
+	return nil
+}
+
+// sqliteJSON scans a JSON column, such as one built with json_group_array, into dst.
+type sqliteJSON struct {
+	dst any
+}
+
+func (s sqliteJSON) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), s.dst)
+	case []byte:
+		return json.Unmarshal(v, s.dst)
+	}
+	return fmt.Errorf("models: cannot scan %T as JSON", src)
+}
+
+// sqliteIDs encodes ids as a JSON array, which queries expand with json_each in place of
+// Postgres array parameters.
+func sqliteIDs(ids []uuid.UUID) string {
+	if ids == nil {
+		ids = []uuid.UUID{}
+	}
+	encoded, _ := json.Marshal(ids)
+	return string(encoded)
+}
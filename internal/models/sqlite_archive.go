@@ -0,0 +1,181 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (m *sqliteTasks) GetCompletedTasks(userID uuid.UUID, filter CompletedTaskFilter) ([]CompletedTask, error) {
+	query := `
+		SELECT ` + completedTaskColumns + `, ` + sqliteTaskLabelNamesColumn + `, assignee_id, created_by, created_at, NULL
+		FROM tasks
+		WHERE (user_id = ?1 OR assignee_id = ?1) AND is_completed
+			AND (?2 IS NULL OR completed_at >= ?2)
+			AND (?3 IS NULL OR completed_at < ?3)
+			AND (?4 IS NULL OR project_id = ?4)
+		UNION ALL
+		SELECT ` + completedTaskColumns + `, labels, assignee_id, created_by, created_at, archived_at
+		FROM archived_tasks
+		WHERE (user_id = ?1 OR assignee_id = ?1)
+			AND (?2 IS NULL OR completed_at >= ?2)
+			AND (?3 IS NULL OR completed_at < ?3)
+			AND (?4 IS NULL OR project_id = ?4)
+		ORDER BY completed_at DESC, task_id
+		LIMIT ?5`
+
+	rows, err := m.db.q().QueryContext(context.Background(), query,
+		userID, sqliteNullTimestamp(filter.From), sqliteNullTimestamp(filter.To), filter.ProjectID, filter.Limit)
+	tasks, err := sqliteCollect(rows, err, func(rows *sql.Rows, task *CompletedTask) error {
+		err := rows.Scan(
+			&task.TaskID,
+			&task.ProjectID,
+			&task.UserID,
+			&task.Content,
+			&task.Description,
+			scanDate(&task.DueDate),
+			scanClock(&task.DueDatetime),
+			&task.Priority,
+			scanTimestamp(&task.CompletedAt),
+			&task.ParentTaskID,
+			&task.Order,
+			sqliteJSON{&task.Labels},
+			&task.AssigneeID,
+			&task.CreatedBy,
+			scanTimestamp(&task.CreatedAt),
+			scanNullTimestamp(&task.ArchivedAt),
+		)
+		task.Archived = task.ArchivedAt != nil
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to query completed tasks: %w", err)
+	}
+	if tasks == nil {
+		tasks = []CompletedTask{}
+	}
+	return tasks, nil
+}
+
+// ArchiveCompletedTasks mirrors TaskModel.ArchiveCompletedTasks. The eligible tasks are
+// collected first because SQLite can't insert and delete in one statement.
+func (m *sqliteTasks) ArchiveCompletedTasks(ctx context.Context, cutoff time.Time) (int64, error) {
+	var archived int64
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		query := `
+			WITH RECURSIVE tree(root_id, task_id) AS (
+				SELECT task_id, task_id FROM tasks
+				WHERE parent_task_id IS NULL AND is_completed AND completed_at < ?1
+				UNION
+				SELECT tr.root_id, t.task_id FROM tasks t JOIN tree tr ON t.parent_task_id = tr.task_id
+			), eligible AS (
+				SELECT tr.root_id
+				FROM tree tr
+				JOIN tasks t ON t.task_id = tr.task_id
+				GROUP BY tr.root_id
+				HAVING min(t.is_completed AND t.completed_at IS NOT NULL AND t.completed_at < ?1)
+			)
+			SELECT task_id FROM tree WHERE root_id IN (SELECT root_id FROM eligible)`
+
+		rows, err := c.q().QueryContext(ctx, query, sqliteTimestamp(cutoff))
+		ids, err := sqliteCollect(rows, err, func(rows *sql.Rows, id *uuid.UUID) error {
+			return rows.Scan(id)
+		})
+		if err != nil {
+			return fmt.Errorf("unable to archive completed tasks: %w", err)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		result, err := c.q().ExecContext(ctx, `
+			INSERT INTO archived_tasks (`+completedTaskColumns+`, labels, assignee_id, created_by, created_at, archived_at)
+			SELECT `+completedTaskColumns+`, `+sqliteTaskLabelNamesColumn+`, assignee_id, created_by, created_at, ?2
+			FROM tasks
+			WHERE task_id IN (SELECT value FROM json_each(?1))`,
+			sqliteIDs(ids), sqliteTimestamp(sqliteNow()))
+		if err != nil {
+			return fmt.Errorf("unable to archive completed tasks: %w", err)
+		}
+		archived = rowsAffected(result)
+
+		// Subtasks deleted by the cascade aren't counted by the delete, so the insert is.
+		if _, err := c.q().ExecContext(ctx, `DELETE FROM tasks WHERE task_id IN (SELECT value FROM json_each(?1))`, sqliteIDs(ids)); err != nil {
+			return fmt.Errorf("unable to archive completed tasks: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return archived, nil
+}
+
+// GetStats mirrors TaskModel.GetStats. SQLite has no time zone support, so the rows are read in
+// one transaction and bucketed in Go.
+func (m *sqliteTasks) GetStats(userID uuid.UUID, settings UserSettings, r StatsRange) (Stats, error) {
+	ctx := context.Background()
+
+	var tasks []statsTask
+	var labelled []statsLabelUse
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		query := `
+			WITH user_tasks AS (
+				SELECT project_id, is_completed, completed_at, created_at, due_date, due_datetime
+				FROM tasks
+				WHERE user_id = ?1 OR assignee_id = ?1
+				UNION ALL
+				SELECT project_id, true, completed_at, created_at, due_date, due_datetime
+				FROM archived_tasks
+				WHERE user_id = ?1 OR assignee_id = ?1
+			)
+			SELECT t.project_id, p.project_name, t.is_completed, t.completed_at, t.created_at, t.due_date, t.due_datetime
+			FROM user_tasks t
+			LEFT JOIN projects p ON p.project_id = t.project_id`
+
+		rows, err := c.q().QueryContext(ctx, query, userID)
+		tasks, err = sqliteCollect(rows, err, func(rows *sql.Rows, t *statsTask) error {
+			return rows.Scan(
+				&t.projectID,
+				&t.projectName,
+				&t.completed,
+				scanNullTimestamp(&t.completedAt),
+				scanTimestamp(&t.createdAt),
+				scanDate(&t.dueDate),
+				scanClock(&t.dueDatetime),
+			)
+		})
+		if err != nil {
+			return fmt.Errorf("unable to query stats: %w", err)
+		}
+
+		// Archived tasks only keep label names, so they are matched to the user's labels by name.
+		labelQuery := `
+			SELECT l.label_id, l.name, t.is_completed
+			FROM task_labels tl
+			JOIN tasks t ON t.task_id = tl.task_id
+			JOIN labels l ON l.label_id = tl.label_id
+			WHERE (t.user_id = ?1 OR t.assignee_id = ?1) AND l.user_id = ?1
+			UNION ALL
+			SELECT l.label_id, l.name, true
+			FROM archived_tasks a, json_each(a.labels) AS n
+			JOIN labels l ON l.user_id = ?1 AND lower(l.name) = lower(n.value)
+			WHERE a.user_id = ?1 OR a.assignee_id = ?1`
+
+		rows, err = c.q().QueryContext(ctx, labelQuery, userID)
+		labelled, err = sqliteCollect(rows, err, func(rows *sql.Rows, l *statsLabelUse) error {
+			return rows.Scan(&l.labelID, &l.name, &l.completed)
+		})
+		if err != nil {
+			return fmt.Errorf("unable to query label stats: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+	return computeStats(settings, r, tasks, labelled)
+}
@@ -0,0 +1,227 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// sqliteLabels is SQLiteStore's LabelStore.
+type sqliteLabels struct {
+	db sqliteConn
+}
+
+// scanSQLiteLabel scans a row selected with labelColumns into label.
+func scanSQLiteLabel(row sqliteRow, label *Label) error {
+	return row.Scan(
+		&label.LabelID,
+		&label.UserID,
+		&label.Name,
+		&label.Color,
+		&label.IsFavorite,
+		&label.Order,
+		&label.TaskCount,
+		&label.OpenTaskCount,
+		scanTimestamp(&label.CreatedAt),
+	)
+}
+
+func (m *sqliteLabels) AddLabel(label Label) (Label, error) {
+	query := `
+		INSERT INTO labels (label_id, user_id, name, color, is_favorite, "order", created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, (SELECT COALESCE(max("order") + 1, 0) FROM labels WHERE user_id = ?2), ?6)
+		RETURNING ` + labelColumns
+
+	var created Label
+	err := scanSQLiteLabel(m.db.q().QueryRowContext(context.Background(), query,
+		uuid.New(), label.UserID, label.Name, label.Color, label.IsFavorite, sqliteTimestamp(sqliteNow())), &created)
+	if err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return Label{}, ErrDuplicateLabel
+		}
+		return Label{}, fmt.Errorf("unable to add label: %w", err)
+	}
+	return created, nil
+}
+
+func (m *sqliteLabels) EditLabelByID(label Label) (Label, error) {
+	query := `
+		UPDATE labels SET name = ?3, color = ?4, is_favorite = ?5
+		WHERE label_id = ?1 AND user_id = ?2
+		RETURNING ` + labelColumns
+
+	var updated Label
+	err := scanSQLiteLabel(m.db.q().QueryRowContext(context.Background(), query, label.LabelID, label.UserID, label.Name, label.Color, label.IsFavorite), &updated)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Label{}, ErrNoRecord
+		}
+		if isSQLiteUniqueViolation(err) {
+			return Label{}, ErrDuplicateLabel
+		}
+		return Label{}, fmt.Errorf("unable to edit label: %w", err)
+	}
+	return updated, nil
+}
+
+func (m *sqliteLabels) GetLabelsByUserID(userID uuid.UUID) ([]Label, error) {
+	query := `SELECT ` + labelColumns + ` FROM labels WHERE user_id = ?1 ORDER BY "order" ASC, name ASC`
+	rows, err := m.db.q().QueryContext(context.Background(), query, userID)
+	labels, err := sqliteCollect(rows, err, func(rows *sql.Rows, label *Label) error {
+		return scanSQLiteLabel(rows, label)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get labels: %w", err)
+	}
+	if labels == nil {
+		labels = []Label{}
+	}
+	return labels, nil
+}
+
+func (m *sqliteLabels) ReorderLabels(userID uuid.UUID, labelIDs []uuid.UUID) ([]Label, error) {
+	ctx := context.Background()
+
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		var owned int
+		err := c.q().QueryRowContext(ctx, `SELECT count(*) FROM labels WHERE label_id IN (SELECT value FROM json_each(?1)) AND user_id = ?2`, sqliteIDs(labelIDs), userID).Scan(&owned)
+		if err != nil {
+			return fmt.Errorf("unable to check labels: %w", err)
+		}
+		if owned != len(labelIDs) {
+			return ErrUnknownLabel
+		}
+
+		// json_each numbers the array elements in key, which stands in for WITH ORDINALITY.
+		query := `
+			WITH input AS (
+				SELECT value AS label_id, key AS position FROM json_each(?2)
+			), ranked AS (
+				SELECT l.label_id, ROW_NUMBER() OVER (ORDER BY i.position IS NULL, i.position ASC, l."order" ASC, l.name ASC) - 1 AS position
+				FROM labels l
+				LEFT JOIN input i ON i.label_id = l.label_id
+				WHERE l.user_id = ?1
+			)
+			UPDATE labels SET "order" = ranked.position
+			FROM ranked
+			WHERE labels.label_id = ranked.label_id AND labels."order" <> ranked.position`
+
+		if _, err := c.q().ExecContext(ctx, query, userID, sqliteIDs(labelIDs)); err != nil {
+			return fmt.Errorf("unable to reorder labels: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.GetLabelsByUserID(userID)
+}
+
+func (m *sqliteLabels) DeleteLabelByID(labelID, userID uuid.UUID) (int64, error) {
+	query := `DELETE FROM labels WHERE label_id = ?1 AND user_id = ?2`
+	result, err := m.db.q().ExecContext(context.Background(), query, labelID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete label: %w", err)
+	}
+	return rowsAffected(result), nil
+}
+
+func (m *sqliteLabels) MergeLabels(userID, targetID uuid.UUID, sourceIDs []uuid.UUID) (LabelMergeResult, error) {
+	ctx := context.Background()
+
+	var result LabelMergeResult
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		ids := append([]uuid.UUID{targetID}, sourceIDs...)
+		var owned int
+		err := c.q().QueryRowContext(ctx, `SELECT count(*) FROM labels WHERE label_id IN (SELECT value FROM json_each(?1)) AND user_id = ?2`, sqliteIDs(ids), userID).Scan(&owned)
+		if err != nil {
+			return fmt.Errorf("unable to check labels: %w", err)
+		}
+		if owned != len(ids) {
+			return ErrUnknownLabel
+		}
+
+		err = c.q().QueryRowContext(ctx, `SELECT count(DISTINCT task_id) FROM task_labels WHERE label_id IN (SELECT value FROM json_each(?1))`, sqliteIDs(sourceIDs)).Scan(&result.TasksUpdated)
+		if err != nil {
+			return fmt.Errorf("unable to count labelled tasks: %w", err)
+		}
+
+		query := `
+			INSERT INTO task_labels (task_id, label_id)
+			SELECT DISTINCT task_id, ?1 FROM task_labels WHERE label_id IN (SELECT value FROM json_each(?2))
+			ON CONFLICT (task_id, label_id) DO NOTHING`
+
+		res, err := c.q().ExecContext(ctx, query, targetID, sqliteIDs(sourceIDs))
+		if err != nil {
+			return fmt.Errorf("unable to relabel tasks: %w", err)
+		}
+
+		// Deleting the source labels cascades to their task_labels rows.
+		res, err = c.q().ExecContext(ctx, `DELETE FROM labels WHERE label_id IN (SELECT value FROM json_each(?1)) AND user_id = ?2`, sqliteIDs(sourceIDs), userID)
+		if err != nil {
+			return fmt.Errorf("unable to delete merged labels: %w", err)
+		}
+		result.LabelsMerged = rowsAffected(res)
+
+		err = scanSQLiteLabel(c.q().QueryRowContext(ctx, `SELECT `+labelColumns+` FROM labels WHERE label_id = ?1`, targetID), &result.Label)
+		if err != nil {
+			return fmt.Errorf("unable to fetch label: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return LabelMergeResult{}, err
+	}
+	return result, nil
+}
+
+// sqliteEnsureLabels mirrors ensureLabels. Label IDs are generated in Go, so the missing labels
+// are inserted one at a time.
+func sqliteEnsureLabels(ctx context.Context, c sqliteConn, userID uuid.UUID, names []string) (map[string]uuid.UUID, error) {
+	ids := map[string]uuid.UUID{}
+	if len(names) == 0 {
+		return ids, nil
+	}
+
+	query := `
+		INSERT INTO labels (label_id, user_id, name, "order", created_at)
+		VALUES (?1, ?2, ?3, (SELECT COALESCE(max("order") + 1, 0) FROM labels WHERE user_id = ?2), ?4)
+		ON CONFLICT DO NOTHING`
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		if _, err := c.q().ExecContext(ctx, query, uuid.New(), userID, name, sqliteTimestamp(sqliteNow())); err != nil {
+			return nil, fmt.Errorf("unable to create labels: %w", err)
+		}
+	}
+
+	encoded, err := json.Marshal(names)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get labels: %w", err)
+	}
+	query = `SELECT label_id, name FROM labels WHERE user_id = ?1 AND lower(name) IN (SELECT lower(value) FROM json_each(?2))`
+	rows, err := c.q().QueryContext(ctx, query, userID, string(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get labels: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("unable to scan label: %w", err)
+		}
+		ids[strings.ToLower(name)] = id
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,417 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// sqliteList is a siblingList read and written through a SQLite connection.
+type sqliteList struct {
+	siblingList
+	c sqliteConn
+}
+
+// sqliteListWhere matches the tasks of a siblingList given as ?1, ?2 and ?3.
+const sqliteListWhere = `user_id = ?1 AND project_id IS ?2 AND parent_task_id IS ?3`
+
+// sqliteSibling is a task's place in its sibling list.
+type sqliteSibling struct {
+	taskID uuid.UUID
+	order  int
+}
+
+// tasks mirrors siblingList.tasks.
+func (l sqliteList) tasks(ctx context.Context) ([]Task, error) {
+	query := `
+		SELECT ` + sqliteTaskColumns + `
+		FROM tasks
+		WHERE ` + sqliteListWhere + `
+		ORDER BY ` + sqliteTaskOrder
+
+	return sqliteQueryTasks(ctx, l.c, query, l.userID, l.projectID, l.parentTaskID)
+}
+
+// siblings returns the list in display order, leaving out exclude.
+func (l sqliteList) siblings(ctx context.Context, exclude uuid.UUID) ([]sqliteSibling, error) {
+	query := `
+		SELECT task_id, "order"
+		FROM tasks
+		WHERE ` + sqliteListWhere + ` AND task_id <> ?4
+		ORDER BY ` + sqliteTaskOrder
+
+	rows, err := l.c.q().QueryContext(ctx, query, l.userID, l.projectID, l.parentTaskID, exclude)
+	siblings, err := sqliteCollect(rows, err, func(rows *sql.Rows, s *sqliteSibling) error {
+		return rows.Scan(&s.taskID, &s.order)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch sibling tasks: %w", err)
+	}
+	return siblings, nil
+}
+
+// ids returns the IDs of the tasks in the list in display order.
+func (l sqliteList) ids(ctx context.Context) ([]uuid.UUID, error) {
+	siblings, err := l.siblings(ctx, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(siblings))
+	for i, s := range siblings {
+		ids[i] = s.taskID
+	}
+	return ids, nil
+}
+
+// nextOrder mirrors siblingList.nextOrder.
+func (l sqliteList) nextOrder(ctx context.Context) (int, error) {
+	var order int
+	err := l.c.q().QueryRowContext(ctx, `SELECT COALESCE(max("order") + ?4, 0) FROM tasks WHERE `+sqliteListWhere,
+		l.userID, l.projectID, l.parentTaskID, OrderGap).Scan(&order)
+	if err != nil {
+		return 0, fmt.Errorf("unable to find next task order: %w", err)
+	}
+	return order, nil
+}
+
+// orderAt mirrors siblingList.orderAt.
+func (l sqliteList) orderAt(ctx context.Context, taskID uuid.UUID, pos TaskPosition) (int, error) {
+	if pos.BeforeID == nil && pos.AfterID == nil {
+		return l.nextOrder(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		lo, hi, err := l.neighbourOrders(ctx, taskID, pos)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case lo == nil:
+			return *hi - OrderGap, nil
+		case hi == nil:
+			return *lo + OrderGap, nil
+		case *hi-*lo > 1:
+			return *lo + (*hi-*lo)/2, nil
+		}
+		if attempt > 0 {
+			return 0, fmt.Errorf("no room between sibling tasks after rebalancing")
+		}
+		if err := l.rebalance(ctx, taskID); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// orderAtIndex mirrors siblingList.orderAtIndex.
+func (l sqliteList) orderAtIndex(ctx context.Context, taskID uuid.UUID, index int) (int, error) {
+	var beforeID uuid.UUID
+	err := l.c.q().QueryRowContext(ctx, `
+		SELECT task_id FROM tasks
+		WHERE `+sqliteListWhere+` AND task_id <> ?4
+		ORDER BY `+sqliteTaskOrder+`
+		LIMIT 1 OFFSET ?5`,
+		l.userID, l.projectID, l.parentTaskID, taskID, index).Scan(&beforeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return l.nextOrder(ctx)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to fetch sibling tasks: %w", err)
+	}
+	return l.orderAt(ctx, taskID, TaskPosition{BeforeID: &beforeID})
+}
+
+// neighbourOrders mirrors siblingList.neighbourOrders, ranking the list in Go.
+func (l sqliteList) neighbourOrders(ctx context.Context, taskID uuid.UUID, pos TaskPosition) (lo, hi *int, err error) {
+	others, err := l.siblings(ctx, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	orders := make([]int, len(others))
+	for i, s := range others {
+		orders[i] = s.order
+	}
+	rank := func(id *uuid.UUID) *int {
+		if id == nil {
+			return nil
+		}
+		for i, s := range others {
+			if s.taskID == *id {
+				r := i + 1
+				return &r
+			}
+		}
+		return nil
+	}
+	afterRank, beforeRank := rank(pos.AfterID), rank(pos.BeforeID)
+
+	for _, ref := range []struct {
+		id   *uuid.UUID
+		rank *int
+	}{{pos.AfterID, afterRank}, {pos.BeforeID, beforeRank}} {
+		if ref.id == nil || ref.rank != nil {
+			continue
+		}
+		if err := l.checkSibling(ctx, *ref.id, taskID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Ranks are 1-based, so orders[rank-1] is the neighbour itself.
+	switch {
+	case afterRank != nil && beforeRank != nil:
+		if *beforeRank != *afterRank+1 {
+			return nil, nil, ErrInvalidPosition
+		}
+		return &orders[*afterRank-1], &orders[*beforeRank-1], nil
+	case afterRank != nil:
+		lo = &orders[*afterRank-1]
+		if *afterRank < len(orders) {
+			hi = &orders[*afterRank]
+		}
+	default:
+		hi = &orders[*beforeRank-1]
+		if *beforeRank > 1 {
+			lo = &orders[*beforeRank-2]
+		}
+	}
+	return lo, hi, nil
+}
+
+// checkSibling mirrors siblingList.checkSibling.
+func (l sqliteList) checkSibling(ctx context.Context, id, taskID uuid.UUID) error {
+	if id == taskID {
+		return ErrInvalidPosition
+	}
+	var exists bool
+	err := l.c.q().QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM tasks WHERE task_id = ?1 AND user_id = ?2)`, id, l.userID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("unable to fetch task: %w", err)
+	}
+	if !exists {
+		return ErrNoRecord
+	}
+	return ErrNotSiblings
+}
+
+// rebalance mirrors siblingList.rebalance.
+func (l sqliteList) rebalance(ctx context.Context, exclude uuid.UUID) error {
+	query := `
+		UPDATE tasks SET "order" = s.position
+		FROM (
+			SELECT task_id, (ROW_NUMBER() OVER (ORDER BY ` + sqliteTaskOrder + `) - 1) * ?5 AS position
+			FROM tasks
+			WHERE ` + sqliteListWhere + ` AND task_id <> ?4
+		) s
+		WHERE tasks.task_id = s.task_id AND tasks."order" <> s.position`
+
+	if _, err := l.c.q().ExecContext(ctx, query, l.userID, l.projectID, l.parentTaskID, exclude, OrderGap); err != nil {
+		return fmt.Errorf("unable to rebalance sibling tasks: %w", err)
+	}
+	return nil
+}
+
+func (m *sqliteTasks) PositionTask(taskID, userID uuid.UUID, pos TaskPosition) (Task, error) {
+	ctx := context.Background()
+
+	var positioned Task
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		list := sqliteList{siblingList{userID: userID}, c}
+		err := c.q().QueryRowContext(ctx, `SELECT project_id, parent_task_id FROM tasks WHERE task_id = ?1 AND user_id = ?2`, taskID, userID).Scan(&list.projectID, &list.parentTaskID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNoRecord
+			}
+			return fmt.Errorf("unable to fetch task: %w", err)
+		}
+
+		order, err := list.orderAt(ctx, taskID, pos)
+		if err != nil {
+			return err
+		}
+		if _, err := c.q().ExecContext(ctx, `UPDATE tasks SET "order" = ?2 WHERE task_id = ?1`, taskID, order); err != nil {
+			return fmt.Errorf("unable to update task order: %w", err)
+		}
+
+		positioned, err = sqliteGetTask(ctx, c, taskID)
+		return err
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return positioned, nil
+}
+
+func (m *sqliteTasks) RebalanceTaskOrders(ctx context.Context) (int64, error) {
+	query := `
+		WITH ranked AS (
+			SELECT user_id, project_id, parent_task_id, "order",
+				"order" - lag("order") OVER (PARTITION BY user_id, project_id, parent_task_id ORDER BY ` + sqliteTaskOrder + `) AS gap
+			FROM tasks
+		), crowded AS (
+			SELECT DISTINCT user_id, project_id, parent_task_id
+			FROM ranked
+			WHERE gap < 2 OR abs("order") > ?1
+		), renumbered AS (
+			SELECT t.task_id,
+				(ROW_NUMBER() OVER (PARTITION BY t.user_id, t.project_id, t.parent_task_id ORDER BY t."order" ASC, t.created_at ASC, t.rowid ASC) - 1) * ?2 AS position
+			FROM tasks t
+			JOIN crowded c ON c.user_id = t.user_id
+				AND c.project_id IS t.project_id
+				AND c.parent_task_id IS t.parent_task_id
+		)
+		UPDATE tasks SET "order" = r.position
+		FROM renumbered r
+		WHERE tasks.task_id = r.task_id AND tasks."order" <> r.position`
+
+	result, err := m.db.q().ExecContext(ctx, query, maxOrderMagnitude, OrderGap)
+	if err != nil {
+		return 0, fmt.Errorf("unable to rebalance task orders: %w", err)
+	}
+	return rowsAffected(result), nil
+}
+
+func (m *sqliteTasks) MoveTask(taskID, userID uuid.UUID, move TaskMove) (Task, error) {
+	ctx := context.Background()
+
+	var moved Task
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		// The source list keeps its gap; only the moved task gets a new order in the destination list.
+		_, _, err := sqliteRelocateTask(ctx, c, taskID, userID, move.ProjectID, move.ParentTaskID, func(dest sqliteList) (int, error) {
+			if move.Position == nil {
+				return dest.nextOrder(ctx)
+			}
+			return dest.orderAtIndex(ctx, taskID, *move.Position)
+		})
+		if err != nil {
+			return err
+		}
+
+		moved, err = sqliteGetTask(ctx, c, taskID)
+		return err
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return moved, nil
+}
+
+func (m *sqliteTasks) DropTask(userID uuid.UUID, drop TaskDrop) (TaskDropResult, error) {
+	ctx := context.Background()
+
+	var result TaskDropResult
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		pos := TaskPosition{BeforeID: drop.BeforeID, AfterID: drop.AfterID}
+		source, dest, err := sqliteRelocateTask(ctx, c, drop.TaskID, userID, drop.NewProjectID, drop.NewParentID, func(dest sqliteList) (int, error) {
+			return dest.orderAt(ctx, drop.TaskID, pos)
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := dest.rebalance(ctx, uuid.Nil); err != nil {
+			return err
+		}
+		if result.Siblings, err = dest.tasks(ctx); err != nil {
+			return err
+		}
+		if !source.same(dest.siblingList) {
+			if err := source.rebalance(ctx, uuid.Nil); err != nil {
+				return err
+			}
+			if result.SourceSiblings, err = source.tasks(ctx); err != nil {
+				return err
+			}
+		}
+		result.Task, err = sqliteGetTask(ctx, c, drop.TaskID)
+		return err
+	})
+	if err != nil {
+		return TaskDropResult{}, err
+	}
+	return result, nil
+}
+
+// sqliteRelocateTask mirrors relocateTask.
+func sqliteRelocateTask(ctx context.Context, c sqliteConn, taskID, userID uuid.UUID, projectID, parentTaskID *uuid.UUID, place func(dest sqliteList) (int, error)) (source, dest sqliteList, err error) {
+	source = sqliteList{siblingList{userID: userID}, c}
+	err = c.q().QueryRowContext(ctx, `SELECT project_id, parent_task_id FROM tasks WHERE task_id = ?1 AND user_id = ?2`, taskID, userID).Scan(&source.projectID, &source.parentTaskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return source, dest, ErrNoRecord
+		}
+		return source, dest, fmt.Errorf("unable to fetch task: %w", err)
+	}
+
+	if parentTaskID != nil {
+		inSubtree, err := sqliteIsInSubtree(ctx, c, taskID, *parentTaskID)
+		if err != nil {
+			return source, dest, err
+		}
+		if inSubtree {
+			return source, dest, ErrMoveIntoSubtree
+		}
+
+		err = c.q().QueryRowContext(ctx, `SELECT project_id FROM tasks WHERE task_id = ?1 AND user_id = ?2`, *parentTaskID, userID).Scan(&projectID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return source, dest, ErrNoRecord
+			}
+			return source, dest, fmt.Errorf("unable to fetch parent task: %w", err)
+		}
+	} else if projectID != nil {
+		var exists bool
+		err = c.q().QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM projects WHERE project_id = ?1 AND user_id = ?2)`, *projectID, userID).Scan(&exists)
+		if err != nil {
+			return source, dest, fmt.Errorf("unable to fetch project: %w", err)
+		}
+		if !exists {
+			return source, dest, ErrNoRecord
+		}
+	}
+
+	dest = sqliteList{siblingList{userID: userID, projectID: projectID, parentTaskID: parentTaskID}, c}
+	order, err := place(dest)
+	if err != nil {
+		return source, dest, err
+	}
+
+	_, err = c.q().ExecContext(ctx, `UPDATE tasks SET project_id = ?2, parent_task_id = ?3, "order" = ?4 WHERE task_id = ?1`, taskID, projectID, parentTaskID, order)
+	if err != nil {
+		return source, dest, fmt.Errorf("unable to move task: %w", err)
+	}
+
+	// Subtasks keep their parents and order but follow the task into its new project.
+	_, err = c.q().ExecContext(ctx, `
+		WITH RECURSIVE descendants(task_id) AS (
+			SELECT task_id FROM tasks WHERE parent_task_id = ?1
+			UNION
+			SELECT t.task_id FROM tasks t JOIN descendants d ON t.parent_task_id = d.task_id
+		)
+		UPDATE tasks SET project_id = ?2
+		WHERE task_id IN (SELECT task_id FROM descendants) AND project_id IS NOT ?2`,
+		taskID, projectID)
+	if err != nil {
+		return source, dest, fmt.Errorf("unable to move subtasks: %w", err)
+	}
+	return source, dest, nil
+}
+
+// sqliteIsInSubtree mirrors isInSubtree.
+func sqliteIsInSubtree(ctx context.Context, c sqliteConn, rootID, candidateID uuid.UUID) (bool, error) {
+	query := `
+		WITH RECURSIVE subtree(task_id) AS (
+			SELECT ?1
+			UNION
+			SELECT t.task_id FROM tasks t JOIN subtree s ON t.parent_task_id = s.task_id
+		)
+		SELECT EXISTS (SELECT 1 FROM subtree WHERE task_id = ?2)`
+
+	var inSubtree bool
+	if err := c.q().QueryRowContext(ctx, query, rootID, candidateID).Scan(&inSubtree); err != nil {
+		return false, fmt.Errorf("unable to check task subtree: %w", err)
+	}
+	return inSubtree, nil
+}
@@ -0,0 +1,375 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// sqliteProjects is SQLiteStore's ProjectStore.
+type sqliteProjects struct {
+	db sqliteConn
+}
+
+// scanSQLiteProject scans a row selected with projectColumns into project.
+func scanSQLiteProject(row sqliteRow, project *Project) error {
+	return row.Scan(
+		&project.ProjectID,
+		&project.UserID,
+		&project.ProjectName,
+		&project.Color,
+		&project.IsInbox,
+		&project.ParentProjectID,
+		&project.IsArchived,
+		&project.IsFavorite,
+		&project.Order,
+		scanTimestamp(&project.CreatedAt),
+	)
+}
+
+// sqliteQueryProjects runs a query selecting projectColumns and collects the rows.
+func sqliteQueryProjects(ctx context.Context, c sqliteConn, query string, args ...any) ([]Project, error) {
+	rows, err := c.q().QueryContext(ctx, query, args...)
+	return sqliteCollect(rows, err, func(rows *sql.Rows, p *Project) error {
+		return scanSQLiteProject(rows, p)
+	})
+}
+
+// sqliteProjectSubtree selects a project owned by ?2 and every project below it as
+// subtree(project_id, depth), where ?1 is the project.
+const sqliteProjectSubtree = `
+	WITH RECURSIVE subtree(project_id, depth) AS (
+		SELECT project_id, 0 FROM projects WHERE project_id = ?1 AND user_id = ?2
+		UNION
+		SELECT p.project_id, s.depth + 1 FROM projects p JOIN subtree s ON p.parent_project_id = s.project_id
+	)`
+
+// sqliteNextProjectOrder returns the order that appends a project after its siblings.
+func sqliteNextProjectOrder(ctx context.Context, c sqliteConn, userID uuid.UUID, parentID *uuid.UUID) (int, error) {
+	var order int
+	err := c.q().QueryRowContext(ctx, `
+		SELECT COALESCE(max("order") + 1, 0) FROM projects
+		WHERE user_id = ?1 AND parent_project_id IS ?2`,
+		userID, parentID).Scan(&order)
+	if err != nil {
+		return 0, fmt.Errorf("unable to find next project order: %w", err)
+	}
+	return order, nil
+}
+
+func (m *sqliteProjects) AddProject(project Project) (Project, error) {
+	ctx := context.Background()
+
+	var created Project
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		order, err := sqliteNextProjectOrder(ctx, c, project.UserID, project.ParentProjectID)
+		if err != nil {
+			return err
+		}
+
+		projectID := uuid.New()
+		_, err = c.q().ExecContext(ctx, `
+			INSERT INTO projects (
+				project_id, user_id, project_name, color, is_inbox, parent_project_id, is_favorite, "order", created_at
+			) VALUES (
+				?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9
+			)`,
+			projectID,
+			project.UserID,
+			project.ProjectName,
+			project.Color,
+			project.IsInbox,
+			project.ParentProjectID,
+			project.IsFavorite,
+			order,
+			sqliteTimestamp(sqliteNow()),
+		)
+		if err != nil {
+			return err
+		}
+		return scanSQLiteProject(c.q().QueryRowContext(ctx, `SELECT `+projectColumns+` FROM projects WHERE project_id = ?`, projectID), &created)
+	})
+	if err != nil {
+		return Project{}, fmt.Errorf("unable to execute query: %v", err)
+	}
+	return created, nil
+}
+
+func (m *sqliteProjects) EditProjectByID(project Project) (Project, error) {
+	query := `
+		UPDATE projects SET
+			project_name = ?3,
+			color = ?4,
+			is_inbox = ?5,
+			parent_project_id = ?6,
+			is_favorite = ?7
+		WHERE project_id = ?1 AND user_id = ?2
+		RETURNING ` + projectColumns
+
+	var updatedProject Project
+	err := scanSQLiteProject(m.db.q().QueryRowContext(
+		context.Background(),
+		query,
+		project.ProjectID,
+		project.UserID,
+		project.ProjectName,
+		project.Color,
+		project.IsInbox,
+		project.ParentProjectID,
+		project.IsFavorite,
+	), &updatedProject)
+
+	if err != nil {
+		return Project{}, fmt.Errorf("unable to execute query: %v", err)
+	}
+
+	return updatedProject, nil
+}
+
+func (m *sqliteProjects) GetProjectsByUserID(userID uuid.UUID, includeArchived bool) ([]Project, error) {
+	query := `
+		SELECT ` + projectColumns + `
+		FROM projects
+		WHERE user_id = ?1 AND (?2 OR NOT is_archived)
+		ORDER BY "order" ASC, created_at ASC, rowid ASC`
+
+	projects, err := sqliteQueryProjects(context.Background(), m.db, query, userID, includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query projects: %v", err)
+	}
+	return projects, nil
+}
+
+func (m *sqliteProjects) ArchiveProject(projectID, userID uuid.UUID, archived bool) ([]Project, error) {
+	ctx := context.Background()
+
+	var projects []Project
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		_, err := c.q().ExecContext(ctx, sqliteProjectSubtree+`
+			UPDATE projects SET is_archived = ?3
+			WHERE project_id IN (SELECT project_id FROM subtree)`, projectID, userID, archived)
+		if err != nil {
+			return fmt.Errorf("unable to archive project: %w", err)
+		}
+
+		projects, err = sqliteQueryProjects(ctx, c, sqliteProjectSubtree+`
+			SELECT `+projectColumns+`
+			FROM projects
+			JOIN subtree USING (project_id)
+			ORDER BY depth ASC, created_at ASC, projects.rowid ASC`, projectID, userID)
+		if err != nil {
+			return fmt.Errorf("unable to scan row: %w", err)
+		}
+		if len(projects) == 0 {
+			return ErrNoRecord
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (m *sqliteProjects) BulkUpdateProjectOrder(userID uuid.UUID, updates []ProjectOrderUpdate) error {
+	ctx := context.Background()
+
+	ids := make([]uuid.UUID, len(updates))
+	for i, u := range updates {
+		ids[i] = u.ProjectID
+	}
+	if len(uniqueUUIDs(ids)) != len(ids) {
+		return ErrInvalidReorder
+	}
+
+	return m.db.begin(ctx, func(c sqliteConn) error {
+		var owned, parents int
+		err := c.q().QueryRowContext(ctx, `
+			SELECT count(*), count(DISTINCT COALESCE(parent_project_id, ''))
+			FROM projects
+			WHERE project_id IN (SELECT value FROM json_each(?1)) AND user_id = ?2`, sqliteIDs(ids), userID).Scan(&owned, &parents)
+		if err != nil {
+			return fmt.Errorf("unable to fetch projects: %w", err)
+		}
+		if owned != len(ids) {
+			return ErrNoRecord
+		}
+		if parents > 1 {
+			return ErrNotSiblings
+		}
+
+		for _, u := range updates {
+			_, err := c.q().ExecContext(ctx, `UPDATE projects SET "order" = ?2 WHERE project_id = ?1 AND user_id = ?3`, u.ProjectID, u.Order, userID)
+			if err != nil {
+				return fmt.Errorf("unable to update project order: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (m *sqliteProjects) DeleteProjectByID(projectID, userID uuid.UUID) (int64, error) {
+	query := `DELETE FROM projects WHERE project_id = ?1 AND user_id = ?2`
+
+	result, err := m.db.q().ExecContext(context.Background(), query, projectID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete project: %v", err)
+	}
+
+	return rowsAffected(result), nil
+}
+
+func (m *sqliteProjects) DuplicateProject(projectID, userID uuid.UUID, opts DuplicateOptions) (Project, error) {
+	ctx := context.Background()
+
+	var duplicate Project
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		projects, err := sqliteQueryProjects(ctx, c, sqliteProjectSubtree+`
+			SELECT `+projectColumns+`
+			FROM projects
+			JOIN subtree USING (project_id)
+			ORDER BY depth ASC, created_at ASC, projects.rowid ASC`, projectID, userID)
+		if err != nil {
+			return fmt.Errorf("unable to query projects: %w", err)
+		}
+		if len(projects) == 0 {
+			return ErrNoRecord
+		}
+
+		projectMap := map[uuid.UUID]uuid.UUID{}
+		projectIDs := make([]uuid.UUID, 0, len(projects))
+		for _, p := range projects {
+			newID := uuid.New()
+			projectMap[p.ProjectID] = newID
+			projectIDs = append(projectIDs, p.ProjectID)
+
+			name := p.ProjectName
+			parentID := p.ParentProjectID
+			isFavorite := p.IsFavorite
+			order := p.Order
+			if p.ProjectID == projectID {
+				name = "Copy of " + p.ProjectName
+				if opts.ProjectName != nil {
+					name = *opts.ProjectName
+				}
+				// The copy starts out as an ordinary project after the original's siblings.
+				isFavorite = false
+				if order, err = sqliteNextProjectOrder(ctx, c, userID, parentID); err != nil {
+					return err
+				}
+			} else if parentID != nil {
+				mapped := projectMap[*parentID]
+				parentID = &mapped
+			}
+
+			// Copies are never the inbox, so is_inbox is left to its default.
+			_, err := c.q().ExecContext(ctx, `
+				INSERT INTO projects (project_id, user_id, project_name, color, parent_project_id, is_favorite, "order", created_at)
+				VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)`,
+				newID, userID, name, p.Color, parentID, isFavorite, order, sqliteTimestamp(sqliteNow()))
+			if err != nil {
+				return fmt.Errorf("unable to copy project: %w", err)
+			}
+		}
+
+		taskQuery := `
+			SELECT ` + sqliteTaskColumns + `
+			FROM tasks
+			WHERE project_id IN (SELECT value FROM json_each(?1)) AND user_id = ?2
+			ORDER BY created_at ASC, rowid ASC`
+
+		tasks, err := sqliteQueryTasks(ctx, c, taskQuery, sqliteIDs(projectIDs), userID)
+		if err != nil {
+			return err
+		}
+
+		idMap := map[uuid.UUID]uuid.UUID{}
+		for _, task := range orderParentsFirst(tasks) {
+			task.AssigneeID = nil
+			if err := sqliteInsertTaskCopy(ctx, c, task, userID, idMap, projectMap, opts); err != nil {
+				return err
+			}
+		}
+
+		err = scanSQLiteProject(c.q().QueryRowContext(ctx, `SELECT `+projectColumns+` FROM projects WHERE project_id = ?`, projectMap[projectID]), &duplicate)
+		if err != nil {
+			return fmt.Errorf("unable to fetch project: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Project{}, err
+	}
+	return duplicate, nil
+}
+
+func (m *sqliteProjects) AddProjectMember(projectID, ownerID, memberID uuid.UUID) (ProjectMember, error) {
+	query := `
+		INSERT INTO project_members (project_id, user_id, created_at)
+		SELECT project_id, ?3, ?4 FROM projects WHERE project_id = ?1 AND user_id = ?2
+		ON CONFLICT (project_id, user_id) DO UPDATE SET created_at = project_members.created_at
+		RETURNING project_id, user_id, created_at`
+
+	var member ProjectMember
+	err := m.db.q().QueryRowContext(context.Background(), query, projectID, ownerID, memberID, sqliteTimestamp(sqliteNow())).Scan(
+		&member.ProjectID,
+		&member.UserID,
+		scanTimestamp(&member.CreatedAt),
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ProjectMember{}, ErrNoRecord
+		}
+		return ProjectMember{}, fmt.Errorf("unable to add project member: %w", err)
+	}
+	return member, nil
+}
+
+func (m *sqliteProjects) GetProjectMembers(projectID, userID uuid.UUID) ([]ProjectMember, error) {
+	query := `
+		SELECT pm.project_id, pm.user_id, pm.created_at
+		FROM project_members pm
+		JOIN projects p ON p.project_id = pm.project_id
+		WHERE pm.project_id = ?1
+			AND (p.user_id = ?2 OR EXISTS (
+				SELECT 1 FROM project_members me WHERE me.project_id = pm.project_id AND me.user_id = ?2
+			))
+		ORDER BY pm.created_at ASC, pm.user_id ASC`
+
+	rows, err := m.db.q().QueryContext(context.Background(), query, projectID, userID)
+	members, err := sqliteCollect(rows, err, func(rows *sql.Rows, member *ProjectMember) error {
+		return rows.Scan(&member.ProjectID, &member.UserID, scanTimestamp(&member.CreatedAt))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to query project members: %w", err)
+	}
+	return members, nil
+}
+
+func (m *sqliteProjects) RemoveProjectMember(projectID, ownerID, memberID uuid.UUID) (int64, error) {
+	query := `
+		DELETE FROM project_members
+		WHERE project_id = ?1 AND user_id = ?3
+			AND EXISTS (SELECT 1 FROM projects p WHERE p.project_id = ?1 AND p.user_id = ?2)`
+
+	result, err := m.db.q().ExecContext(context.Background(), query, projectID, ownerID, memberID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to remove project member: %w", err)
+	}
+	return rowsAffected(result), nil
+}
+
+func (m *sqliteProjects) IsProjectMember(projectID, userID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM projects WHERE project_id = ?1 AND user_id = ?2)
+			OR EXISTS (SELECT 1 FROM project_members WHERE project_id = ?1 AND user_id = ?2)`
+
+	var ok bool
+	if err := m.db.q().QueryRowContext(context.Background(), query, projectID, userID).Scan(&ok); err != nil {
+		return false, fmt.Errorf("unable to check project membership: %w", err)
+	}
+	return ok, nil
+}
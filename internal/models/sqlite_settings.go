@@ -0,0 +1,174 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sqliteSettings is SQLiteStore's SettingsStore.
+type sqliteSettings struct {
+	db sqliteConn
+}
+
+// scanSQLiteSettings scans a row selected with settingsColumns into settings.
+func scanSQLiteSettings(row sqliteRow, settings *UserSettings) error {
+	return row.Scan(
+		&settings.UserID,
+		&settings.CompleteSubtasksWithParent,
+		&settings.CompleteParentWithLastSubtask,
+		&settings.DailyGoal,
+		&settings.Timezone,
+		scanTimestamp(&settings.UpdatedAt),
+	)
+}
+
+func (m *sqliteSettings) GetSettings(userID uuid.UUID) (UserSettings, error) {
+	query := `SELECT ` + settingsColumns + ` FROM user_settings WHERE user_id = ?1`
+
+	var settings UserSettings
+	err := scanSQLiteSettings(m.db.q().QueryRowContext(context.Background(), query, userID), &settings)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultSettings(userID), nil
+	}
+	if err != nil {
+		return UserSettings{}, fmt.Errorf("unable to get settings: %w", err)
+	}
+	return settings, nil
+}
+
+func (m *sqliteSettings) UpdateSettings(settings UserSettings) (UserSettings, error) {
+	query := `
+		INSERT INTO user_settings (user_id, complete_subtasks_with_parent, complete_parent_with_last_subtask, daily_goal, timezone, updated_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+		ON CONFLICT (user_id) DO UPDATE SET
+			complete_subtasks_with_parent = excluded.complete_subtasks_with_parent,
+			complete_parent_with_last_subtask = excluded.complete_parent_with_last_subtask,
+			daily_goal = excluded.daily_goal,
+			timezone = excluded.timezone,
+			updated_at = excluded.updated_at
+		RETURNING ` + settingsColumns
+
+	var updated UserSettings
+	err := scanSQLiteSettings(m.db.q().QueryRowContext(
+		context.Background(),
+		query,
+		settings.UserID,
+		settings.CompleteSubtasksWithParent,
+		settings.CompleteParentWithLastSubtask,
+		settings.DailyGoal,
+		settings.Timezone,
+		sqliteTimestamp(sqliteNow()),
+	), &updated)
+	if err != nil {
+		return UserSettings{}, fmt.Errorf("unable to update settings: %w", err)
+	}
+	return updated, nil
+}
+
+// sqliteIdempotency is SQLiteStore's IdempotencyStore.
+type sqliteIdempotency struct {
+	db sqliteConn
+}
+
+func (m *sqliteIdempotency) Reserve(userID uuid.UUID, key, requestHash string, lease time.Duration) (bool, IdempotentResponse, error) {
+	ctx := context.Background()
+	now := sqliteNow()
+
+	// An expired key is treated as unused and taken over. That includes a key whose request
+	// never saved a response before its lease ran out, for example because the process died.
+	query := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, created_at, expires_at)
+		VALUES (?1, ?2, ?3, ?4, ?5)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE SET
+			request_hash = excluded.request_hash,
+			status_code = NULL,
+			content_type = NULL,
+			response_headers = NULL,
+			response_body = NULL,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at
+		WHERE idempotency_keys.expires_at <= excluded.created_at
+		RETURNING true`
+
+	storedQuery := `
+		SELECT request_hash, status_code, COALESCE(content_type, ''), response_headers, response_body
+		FROM idempotency_keys
+		WHERE user_id = ?1 AND idempotency_key = ?2`
+
+	for attempt := 1; ; attempt++ {
+		var claimed bool
+		err := m.db.q().QueryRowContext(ctx, query, userID, key, requestHash, sqliteTimestamp(now), sqliteTimestamp(now.Add(lease))).Scan(&claimed)
+		if err == nil {
+			return true, IdempotentResponse{}, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return false, IdempotentResponse{}, fmt.Errorf("unable to reserve idempotency key: %w", err)
+		}
+
+		var stored IdempotentResponse
+		var headers sql.NullString
+		err = m.db.q().QueryRowContext(ctx, storedQuery, userID, key).Scan(&stored.RequestHash, &stored.StatusCode, &stored.ContentType, &headers, &stored.Body)
+		if errors.Is(err, sql.ErrNoRows) && attempt < reserveAttempts {
+			// The key was released after the claim failed, so it is free to claim again.
+			continue
+		}
+		if err != nil {
+			return false, IdempotentResponse{}, fmt.Errorf("unable to get idempotency key: %w", err)
+		}
+		if headers.Valid {
+			if err := json.Unmarshal([]byte(headers.String), &stored.Headers); err != nil {
+				return false, IdempotentResponse{}, fmt.Errorf("unable to decode idempotent response headers: %w", err)
+			}
+		}
+		return false, stored, nil
+	}
+}
+
+func (m *sqliteIdempotency) Save(userID uuid.UUID, key string, response IdempotentResponse, ttl time.Duration) error {
+	var headers any
+	if response.Headers != nil {
+		encoded, err := json.Marshal(response.Headers)
+		if err != nil {
+			return fmt.Errorf("unable to encode idempotent response headers: %w", err)
+		}
+		headers = string(encoded)
+	}
+
+	query := `
+		UPDATE idempotency_keys SET
+			status_code = ?3,
+			content_type = ?4,
+			response_headers = ?5,
+			response_body = ?6,
+			expires_at = ?7
+		WHERE user_id = ?1 AND idempotency_key = ?2`
+
+	_, err := m.db.q().ExecContext(context.Background(), query, userID, key, response.StatusCode, response.ContentType, headers, response.Body, sqliteTimestamp(sqliteNow().Add(ttl)))
+	if err != nil {
+		return fmt.Errorf("unable to save idempotent response: %w", err)
+	}
+	return nil
+}
+
+func (m *sqliteIdempotency) Release(userID uuid.UUID, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = ?1 AND idempotency_key = ?2`
+
+	if _, err := m.db.q().ExecContext(context.Background(), query, userID, key); err != nil {
+		return fmt.Errorf("unable to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (m *sqliteIdempotency) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := m.db.q().ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= ?1`, sqliteTimestamp(sqliteNow()))
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete expired idempotency keys: %w", err)
+	}
+	return rowsAffected(result), nil
+}
@@ -0,0 +1,833 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sqliteTasks is SQLiteStore's TaskStore.
+type sqliteTasks struct {
+	db sqliteConn
+}
+
+// sqliteTaskColumns is the SQLite form of taskColumns. The label columns are JSON arrays built
+// with json_group_array in place of array_agg.
+const sqliteTaskColumns = `task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, "order", ` + sqliteTaskLabelNamesColumn + `, ` + sqliteTaskLabelIDsColumn + `, assignee_id, created_by, created_at, ` + taskBlockedColumn + `, ` + taskProgressColumns
+
+const sqliteTaskLabelNamesColumn = `(
+	SELECT json_group_array(l.name ORDER BY l.name) FROM task_labels tl JOIN labels l ON l.label_id = tl.label_id
+	WHERE tl.task_id = tasks.task_id
+)`
+
+const sqliteTaskLabelIDsColumn = `(
+	SELECT json_group_array(l.label_id ORDER BY l.name) FROM task_labels tl JOIN labels l ON l.label_id = tl.label_id
+	WHERE tl.task_id = tasks.task_id
+)`
+
+// sqliteTaskOrder is the display order of tasks. Tasks created in the same microsecond fall
+// back to insertion order.
+const sqliteTaskOrder = `"order" ASC, created_at ASC, rowid ASC`
+
+// sqliteRow is satisfied by both *sql.Row and *sql.Rows.
+type sqliteRow interface {
+	Scan(dest ...any) error
+}
+
+// scanSQLiteTask scans a row selected with sqliteTaskColumns into task.
+func scanSQLiteTask(row sqliteRow, task *Task) error {
+	return row.Scan(
+		&task.TaskID,
+		&task.ProjectID,
+		&task.UserID,
+		&task.Content,
+		&task.Description,
+		scanDate(&task.DueDate),
+		scanClock(&task.DueDatetime),
+		&task.Priority,
+		&task.IsCompleted,
+		scanNullTimestamp(&task.CompletedAt),
+		&task.ParentTaskID,
+		&task.Order,
+		sqliteJSON{&task.Labels},
+		sqliteJSON{&task.LabelIDs},
+		&task.AssigneeID,
+		&task.CreatedBy,
+		scanTimestamp(&task.CreatedAt),
+		&task.Blocked,
+		&task.SubtaskCount,
+		&task.CompletedSubtaskCount,
+	)
+}
+
+// sqliteGetTask re-reads a full task.
+func sqliteGetTask(ctx context.Context, c sqliteConn, taskID uuid.UUID) (Task, error) {
+	var task Task
+	if err := scanSQLiteTask(c.q().QueryRowContext(ctx, `SELECT `+sqliteTaskColumns+` FROM tasks WHERE task_id = ?`, taskID), &task); err != nil {
+		return Task{}, fmt.Errorf("unable to fetch task: %w", err)
+	}
+	return task, nil
+}
+
+// sqliteQueryTasks runs a query selecting sqliteTaskColumns and collects the rows.
+func sqliteQueryTasks(ctx context.Context, c sqliteConn, query string, args ...any) ([]Task, error) {
+	rows, err := c.q().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query tasks: %w", err)
+	}
+	tasks, err := sqliteCollect(rows, nil, func(rows *sql.Rows, task *Task) error {
+		return scanSQLiteTask(rows, task)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan row: %w", err)
+	}
+	return tasks, nil
+}
+
+func (m *sqliteTasks) Atomic(ctx context.Context, fn func(TaskStore) error) error {
+	return m.db.begin(ctx, func(c sqliteConn) error {
+		return fn(&sqliteTasks{db: c})
+	})
+}
+
+func (m *sqliteTasks) AddTask(input NewTask, userID uuid.UUID) (Task, error) {
+	ctx := context.Background()
+
+	var created Task
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		// Without an explicit order the task is appended after its siblings.
+		var order int
+		if input.Order != nil {
+			order = *input.Order
+		} else {
+			list := sqliteList{siblingList{userID: userID, projectID: input.ProjectID, parentTaskID: input.ParentTaskID}, c}
+			var err error
+			if order, err = list.nextOrder(ctx); err != nil {
+				return err
+			}
+		}
+
+		_, err := c.q().ExecContext(ctx, `
+			INSERT INTO tasks (
+				task_id, project_id, user_id, content, description, due_date, due_datetime, priority, parent_task_id, "order", assignee_id, created_by, created_at
+			) VALUES (
+				?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?3, ?12
+			)`,
+			input.TaskID,
+			input.ProjectID,
+			userID,
+			input.Content,
+			input.Description,
+			sqliteDate(input.DueDate),
+			sqliteClock(input.DueDatetime),
+			input.Priority,
+			input.ParentTaskID,
+			order,
+			input.AssigneeID,
+			sqliteTimestamp(sqliteNow()),
+		)
+		if err != nil {
+			return fmt.Errorf("unable to execute query: %v", err)
+		}
+
+		if err := sqliteSetTaskLabels(ctx, c, input.TaskID, userID, input.LabelIDs); err != nil {
+			return err
+		}
+		created, err = sqliteGetTask(ctx, c, input.TaskID)
+		return err
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return created, nil
+}
+
+func (m *sqliteTasks) EditTaskByID(task Task) (Task, error) {
+	ctx := context.Background()
+
+	var updated Task
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		var previousAssignee *uuid.UUID
+		err := c.q().QueryRowContext(ctx, `SELECT assignee_id FROM tasks WHERE task_id = ?1 AND user_id = ?2`, task.TaskID, task.UserID).Scan(&previousAssignee)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNoRecord
+			}
+			return fmt.Errorf("unable to fetch task: %w", err)
+		}
+
+		_, err = c.q().ExecContext(ctx, `
+			UPDATE tasks SET
+				project_id = ?3,
+				content = ?4,
+				description = ?5,
+				due_date = ?6,
+				due_datetime = ?7,
+				priority = ?8,
+				parent_task_id = ?9,
+				"order" = ?10,
+				assignee_id = ?11
+			WHERE task_id = ?1 AND user_id = ?2`,
+			task.TaskID,
+			task.UserID,
+			task.ProjectID,
+			task.Content,
+			task.Description,
+			sqliteDate(task.DueDate),
+			sqliteClock(task.DueDatetime),
+			task.Priority,
+			task.ParentTaskID,
+			task.Order,
+			task.AssigneeID,
+		)
+		if err != nil {
+			return fmt.Errorf("unable to execute query: %v", err)
+		}
+
+		if err := sqliteSetTaskLabels(ctx, c, task.TaskID, task.UserID, task.LabelIDs); err != nil {
+			return err
+		}
+
+		if !sameUUID(previousAssignee, task.AssigneeID) {
+			event := TaskEvent{
+				TaskID:    task.TaskID,
+				UserID:    task.UserID,
+				EventType: TaskEventReassigned,
+				OldValue:  uuidString(previousAssignee),
+				NewValue:  uuidString(task.AssigneeID),
+			}
+			if err := sqliteInsertTaskEvent(ctx, c, event); err != nil {
+				return err
+			}
+		}
+
+		updated, err = sqliteGetTask(ctx, c, task.TaskID)
+		return err
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return updated, nil
+}
+
+func (m *sqliteTasks) GetTasksByUserID(userID uuid.UUID, filter TaskFilter) ([]Task, error) {
+	query := `
+		SELECT ` + sqliteTaskColumns + `
+		FROM tasks
+		WHERE (user_id = ?1 OR assignee_id = ?1)
+			AND (?2 IS NULL OR assignee_id = ?2)
+			AND (?3 OR NOT is_completed)
+		ORDER BY created_at ASC, rowid ASC`
+
+	tasks, err := sqliteQueryTasks(context.Background(), m.db, query, userID, filter.AssigneeID, filter.IncludeCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query tasks: %v", err)
+	}
+	return tasks, nil
+}
+
+func (m *sqliteTasks) ToggleTaskCompleted(taskID, userID uuid.UUID, rules CompletionRules) (Task, error) {
+	task, _, err := m.updateCompletion(taskID, userID, nil, nil, rules)
+	return task, err
+}
+
+func (m *sqliteTasks) SetTaskCompleted(taskID, userID uuid.UUID, completed bool, completedAt *time.Time, rules CompletionRules) (Task, bool, error) {
+	return m.updateCompletion(taskID, userID, &completed, completedAt, rules)
+}
+
+// updateCompletion mirrors TaskModel.updateCompletion.
+func (m *sqliteTasks) updateCompletion(taskID, userID uuid.UUID, target *bool, completedAt *time.Time, rules CompletionRules) (Task, bool, error) {
+	ctx := context.Background()
+
+	var task Task
+	var changed bool
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		var isCompleted, blocked bool
+		err := c.q().QueryRowContext(ctx, `
+			SELECT is_completed, `+taskBlockedColumn+`
+			FROM tasks
+			WHERE task_id = ?1 AND (user_id = ?2 OR assignee_id = ?2)`, taskID, userID).Scan(&isCompleted, &blocked)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNoRecord
+			}
+			return fmt.Errorf("unable to fetch task: %w", err)
+		}
+
+		completed := !isCompleted
+		if target != nil {
+			completed = *target
+		}
+		changed = completed != isCompleted
+
+		if changed {
+			if rules.RefuseIfBlocked && completed && blocked {
+				return ErrTaskBlocked
+			}
+
+			at := sqliteNow()
+			if completedAt != nil {
+				at = *completedAt
+			}
+			query := `UPDATE tasks SET is_completed = ?2, completed_at = CASE WHEN ?2 THEN ?3 END WHERE task_id = ?1`
+			if _, err := c.q().ExecContext(ctx, query, taskID, completed, sqliteTimestamp(at)); err != nil {
+				return fmt.Errorf("unable to update task: %w", err)
+			}
+		}
+
+		if task, err = sqliteGetTask(ctx, c, taskID); err != nil {
+			return err
+		}
+		if changed {
+			if err := sqliteApplyCompletionRules(ctx, c, task, rules); err != nil {
+				return err
+			}
+			// The rules may have changed the task's subtask counts.
+			task, err = sqliteGetTask(ctx, c, taskID)
+		}
+		return err
+	})
+	if err != nil {
+		return Task{}, false, err
+	}
+	return task, changed, nil
+}
+
+// sqliteApplyCompletionRules mirrors applyCompletionRules.
+func sqliteApplyCompletionRules(ctx context.Context, c sqliteConn, task Task, rules CompletionRules) error {
+	if task.IsCompleted && rules.CompleteSubtasks {
+		query := `
+			WITH RECURSIVE descendants(task_id) AS (
+				SELECT task_id FROM tasks WHERE parent_task_id = ?1
+				UNION
+				SELECT t.task_id FROM tasks t JOIN descendants d ON t.parent_task_id = d.task_id
+			)
+			UPDATE tasks SET is_completed = true, completed_at = ?2
+			WHERE task_id IN (SELECT task_id FROM descendants) AND NOT is_completed`
+
+		if _, err := c.q().ExecContext(ctx, query, task.TaskID, sqliteNullTimestamp(task.CompletedAt)); err != nil {
+			return fmt.Errorf("unable to complete subtasks: %w", err)
+		}
+	}
+
+	if task.ParentTaskID == nil || !rules.CompleteParent {
+		return nil
+	}
+
+	if task.IsCompleted {
+		// Walk up from the parent, stopping at the first ancestor that still has another open child.
+		query := `
+			WITH RECURSIVE ancestors(task_id, parent_task_id) AS (
+				SELECT p.task_id, p.parent_task_id FROM tasks p
+				WHERE p.task_id = ?1
+					AND NOT EXISTS (SELECT 1 FROM tasks c WHERE c.parent_task_id = p.task_id AND NOT c.is_completed)
+				UNION
+				SELECT p.task_id, p.parent_task_id FROM tasks p
+				JOIN ancestors a ON p.task_id = a.parent_task_id
+				WHERE NOT EXISTS (
+					SELECT 1 FROM tasks c
+					WHERE c.parent_task_id = p.task_id AND c.task_id <> a.task_id AND NOT c.is_completed
+				)
+			)
+			UPDATE tasks SET is_completed = true, completed_at = ?2
+			WHERE task_id IN (SELECT task_id FROM ancestors) AND NOT is_completed`
+
+		if _, err := c.q().ExecContext(ctx, query, *task.ParentTaskID, sqliteNullTimestamp(task.CompletedAt)); err != nil {
+			return fmt.Errorf("unable to complete parent tasks: %w", err)
+		}
+		return nil
+	}
+
+	query := `
+		WITH RECURSIVE ancestors(task_id, parent_task_id) AS (
+			SELECT task_id, parent_task_id FROM tasks WHERE task_id = ?1
+			UNION
+			SELECT p.task_id, p.parent_task_id FROM tasks p JOIN ancestors a ON p.task_id = a.parent_task_id
+		)
+		UPDATE tasks SET is_completed = false, completed_at = NULL
+		WHERE task_id IN (SELECT task_id FROM ancestors) AND is_completed`
+
+	if _, err := c.q().ExecContext(ctx, query, *task.ParentTaskID); err != nil {
+		return fmt.Errorf("unable to reopen parent tasks: %w", err)
+	}
+	return nil
+}
+
+func (m *sqliteTasks) DeleteTaskByID(taskID, userID uuid.UUID) (int64, error) {
+	result, err := m.db.q().ExecContext(context.Background(), `DELETE FROM tasks WHERE task_id = ?1 AND user_id = ?2`, taskID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete task: %v", err)
+	}
+	return rowsAffected(result), nil
+}
+
+// BulkUpdateTaskOrder mirrors TaskModel.BulkUpdateTaskOrder. SQLite has no data-modifying CTEs,
+// so the checks and the updates are separate statements in one transaction.
+func (m *sqliteTasks) BulkUpdateTaskOrder(userID uuid.UUID, updates []TaskOrderUpdate) (TaskOrderRejection, error) {
+	ctx := context.Background()
+
+	r := TaskOrderRejection{Rejected: []uuid.UUID{}, Missing: []uuid.UUID{}, DuplicateIDs: []uuid.UUID{}, DuplicateOrders: []int{}}
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		type ownedTask struct {
+			taskID uuid.UUID
+			list   siblingList
+		}
+		rows, err := c.q().QueryContext(ctx, `
+			SELECT task_id, project_id, parent_task_id FROM tasks
+			WHERE user_id = ?1 AND task_id IN (SELECT value FROM json_each(?2))`, userID, sqliteIDs(taskOrderIDs(updates)))
+		owned, err := sqliteCollect(rows, err, func(rows *sql.Rows, t *ownedTask) error {
+			t.list.userID = userID
+			return rows.Scan(&t.taskID, &t.list.projectID, &t.list.parentTaskID)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update task order: %w", err)
+		}
+		lists := map[uuid.UUID]siblingList{}
+		for _, t := range owned {
+			lists[t.taskID] = t.list
+		}
+
+		// The list is the one holding the first task in updates that userID owns.
+		inList := map[uuid.UUID]bool{}
+		var siblings []uuid.UUID
+		for _, u := range updates {
+			if l, ok := lists[u.TaskID]; ok {
+				if siblings, err = (sqliteList{l, c}).ids(ctx); err != nil {
+					return fmt.Errorf("failed to update task order: %w", err)
+				}
+				break
+			}
+		}
+		for _, id := range siblings {
+			inList[id] = true
+		}
+
+		idCount := map[uuid.UUID]int{}
+		orderCount := map[int]int{}
+		for _, u := range updates {
+			idCount[u.TaskID]++
+			orderCount[u.Order]++
+		}
+		for _, id := range uniqueUUIDs(taskOrderIDs(updates)) {
+			if !inList[id] {
+				r.Rejected = append(r.Rejected, id)
+			}
+			if idCount[id] > 1 {
+				r.DuplicateIDs = append(r.DuplicateIDs, id)
+			}
+		}
+		for _, id := range siblings {
+			if idCount[id] == 0 {
+				r.Missing = append(r.Missing, id)
+			}
+		}
+		for order, n := range orderCount {
+			if n > 1 {
+				r.DuplicateOrders = append(r.DuplicateOrders, order)
+			}
+		}
+		sort.Ints(r.DuplicateOrders)
+
+		if len(r.Rejected)+len(r.Missing)+len(r.DuplicateIDs)+len(r.DuplicateOrders) > 0 {
+			return nil
+		}
+		for _, u := range updates {
+			_, err := c.q().ExecContext(ctx, `UPDATE tasks SET "order" = ?2 WHERE task_id = ?1 AND user_id = ?3`, u.TaskID, u.Order, userID)
+			if err != nil {
+				return fmt.Errorf("failed to update task order: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return TaskOrderRejection{}, err
+	}
+	if len(r.Rejected)+len(r.Missing)+len(r.DuplicateIDs)+len(r.DuplicateOrders) > 0 {
+		return r, ErrInvalidReorder
+	}
+	return TaskOrderRejection{}, nil
+}
+
+func (m *sqliteTasks) DuplicateTask(taskID, userID uuid.UUID, opts DuplicateOptions) (Task, error) {
+	ctx := context.Background()
+
+	var duplicate Task
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		query := `
+			WITH RECURSIVE subtree(task_id) AS (
+				SELECT task_id FROM tasks WHERE task_id = ?1 AND user_id = ?2
+				UNION
+				SELECT t.task_id FROM tasks t JOIN subtree s ON t.parent_task_id = s.task_id
+			)
+			SELECT ` + sqliteTaskColumns + `
+			FROM tasks
+			WHERE task_id IN (SELECT task_id FROM subtree)
+			ORDER BY created_at ASC, rowid ASC`
+
+		tasks, err := sqliteQueryTasks(ctx, c, query, taskID, userID)
+		if err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			return ErrNoRecord
+		}
+
+		var root Task
+		for _, task := range tasks {
+			if task.TaskID == taskID {
+				root = task
+			}
+		}
+		list := sqliteList{siblingList{userID: userID, projectID: root.ProjectID, parentTaskID: root.ParentTaskID}, c}
+		nextOrder, err := list.nextOrder(ctx)
+		if err != nil {
+			return err
+		}
+
+		idMap := map[uuid.UUID]uuid.UUID{}
+		for _, task := range orderParentsFirst(tasks) {
+			copied := task
+			if task.TaskID == taskID {
+				copied.Order = nextOrder
+			}
+			if err := sqliteInsertTaskCopy(ctx, c, copied, userID, idMap, nil, opts); err != nil {
+				return err
+			}
+		}
+
+		duplicate, err = sqliteGetTask(ctx, c, idMap[taskID])
+		return err
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return duplicate, nil
+}
+
+// sqliteInsertTaskCopy mirrors insertTaskCopy.
+func sqliteInsertTaskCopy(ctx context.Context, c sqliteConn, task Task, userID uuid.UUID, idMap, projectMap map[uuid.UUID]uuid.UUID, opts DuplicateOptions) error {
+	newID := uuid.New()
+	idMap[task.TaskID] = newID
+
+	var parentID *uuid.UUID
+	if task.ParentTaskID != nil {
+		if mapped, ok := idMap[*task.ParentTaskID]; ok {
+			parentID = &mapped
+		}
+	}
+
+	projectID := task.ProjectID
+	if projectMap != nil && projectID != nil {
+		mapped := projectMap[*projectID]
+		projectID = &mapped
+	}
+
+	dueDate := task.DueDate
+	if dueDate != nil && opts.DueDateOffsetDays != 0 {
+		shifted := dueDate.AddDate(0, 0, opts.DueDateOffsetDays)
+		dueDate = &shifted
+	}
+
+	query := `
+		INSERT INTO tasks (
+			task_id, project_id, user_id, content, description, due_date, due_datetime, priority, parent_task_id, "order", assignee_id, created_by, created_at
+		) VALUES (
+			?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?3, ?12
+		)`
+
+	_, err := c.q().ExecContext(ctx, query,
+		newID,
+		projectID,
+		userID,
+		task.Content,
+		task.Description,
+		sqliteDate(dueDate),
+		sqliteClock(task.DueDatetime),
+		task.Priority,
+		parentID,
+		task.Order,
+		task.AssigneeID,
+		sqliteTimestamp(sqliteNow()),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to copy task: %w", err)
+	}
+	return sqliteSetTaskLabels(ctx, c, newID, userID, task.LabelIDs)
+}
+
+func (m *sqliteTasks) AddDependency(taskID, blockedByID, userID uuid.UUID) (TaskDependency, error) {
+	if taskID == blockedByID {
+		return TaskDependency{}, ErrDependencyCycle
+	}
+
+	ctx := context.Background()
+
+	// Transactions hold SQLite's write lock, so two inserts can't close a cycle between them.
+	var dep TaskDependency
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		var owned int
+		err := c.q().QueryRowContext(ctx, `SELECT count(*) FROM tasks WHERE task_id IN (?1, ?2) AND user_id = ?3`, taskID, blockedByID, userID).Scan(&owned)
+		if err != nil {
+			return fmt.Errorf("unable to fetch tasks: %w", err)
+		}
+		if owned != 2 {
+			return ErrNoRecord
+		}
+
+		cycleQuery := `
+			WITH RECURSIVE upstream(task_id) AS (
+				SELECT blocked_by_task_id FROM task_dependencies WHERE task_id = ?1
+				UNION
+				SELECT d.blocked_by_task_id
+				FROM task_dependencies d
+				JOIN upstream u ON d.task_id = u.task_id
+			)
+			SELECT EXISTS (SELECT 1 FROM upstream WHERE task_id = ?2)`
+
+		var cycle bool
+		if err := c.q().QueryRowContext(ctx, cycleQuery, blockedByID, taskID).Scan(&cycle); err != nil {
+			return fmt.Errorf("unable to check for dependency cycle: %w", err)
+		}
+		if cycle {
+			return ErrDependencyCycle
+		}
+
+		_, err = c.q().ExecContext(ctx, `
+			INSERT INTO task_dependencies (task_id, blocked_by_task_id, user_id, created_at)
+			VALUES (?1, ?2, ?3, ?4)
+			ON CONFLICT (task_id, blocked_by_task_id) DO NOTHING`,
+			taskID, blockedByID, userID, sqliteTimestamp(sqliteNow()))
+		if err != nil {
+			return fmt.Errorf("unable to add dependency: %w", err)
+		}
+
+		err = c.q().QueryRowContext(ctx, `
+			SELECT task_id, blocked_by_task_id, user_id, created_at
+			FROM task_dependencies
+			WHERE task_id = ?1 AND blocked_by_task_id = ?2`, taskID, blockedByID).Scan(
+			&dep.TaskID,
+			&dep.BlockedByTaskID,
+			&dep.UserID,
+			scanTimestamp(&dep.CreatedAt),
+		)
+		if err != nil {
+			return fmt.Errorf("unable to add dependency: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return TaskDependency{}, err
+	}
+	return dep, nil
+}
+
+func (m *sqliteTasks) RemoveDependency(taskID, blockedByID, userID uuid.UUID) (int64, error) {
+	query := `DELETE FROM task_dependencies WHERE task_id = ?1 AND blocked_by_task_id = ?2 AND user_id = ?3`
+
+	result, err := m.db.q().ExecContext(context.Background(), query, taskID, blockedByID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to remove dependency: %w", err)
+	}
+	return rowsAffected(result), nil
+}
+
+// sqliteDependencyOrder sorts dependency edges oldest first, breaking ties by ID.
+const sqliteDependencyOrder = `created_at ASC, task_id ASC, blocked_by_task_id ASC`
+
+func (m *sqliteTasks) GetTaskDependencies(taskID, userID uuid.UUID) (TaskDependencies, error) {
+	query := `
+		SELECT blocked_by_task_id, task_id
+		FROM task_dependencies
+		WHERE user_id = ?2 AND (task_id = ?1 OR blocked_by_task_id = ?1)
+		ORDER BY ` + sqliteDependencyOrder
+
+	rows, err := m.db.q().QueryContext(context.Background(), query, taskID, userID)
+	if err != nil {
+		return TaskDependencies{}, fmt.Errorf("unable to query dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	deps := TaskDependencies{BlockedBy: []uuid.UUID{}, Blocks: []uuid.UUID{}}
+	for rows.Next() {
+		var blocker, blocked uuid.UUID
+		if err := rows.Scan(&blocker, &blocked); err != nil {
+			return TaskDependencies{}, fmt.Errorf("unable to scan dependency: %w", err)
+		}
+		if blocked == taskID {
+			deps.BlockedBy = append(deps.BlockedBy, blocker)
+		} else {
+			deps.Blocks = append(deps.Blocks, blocked)
+		}
+	}
+	return deps, rows.Err()
+}
+
+func (m *sqliteTasks) GetProjectTasksInTopologicalOrder(projectID, userID uuid.UUID) ([]Task, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT ` + sqliteTaskColumns + `
+		FROM tasks
+		WHERE project_id = ?1 AND user_id = ?2
+		ORDER BY ` + sqliteTaskOrder
+
+	tasks, err := sqliteQueryTasks(ctx, m.db, query, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	depQuery := `
+		SELECT d.task_id, d.blocked_by_task_id, d.user_id, d.created_at
+		FROM task_dependencies d
+		JOIN tasks a ON a.task_id = d.task_id
+		JOIN tasks b ON b.task_id = d.blocked_by_task_id
+		WHERE a.project_id = ?1 AND b.project_id = ?1 AND d.user_id = ?2
+		ORDER BY d.created_at ASC, d.task_id ASC, d.blocked_by_task_id ASC`
+
+	rows, err := m.db.q().QueryContext(ctx, depQuery, projectID, userID)
+	deps, err := sqliteCollect(rows, err, func(rows *sql.Rows, dep *TaskDependency) error {
+		return rows.Scan(&dep.TaskID, &dep.BlockedByTaskID, &dep.UserID, scanTimestamp(&dep.CreatedAt))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to query dependencies: %w", err)
+	}
+
+	return TopologicalSort(tasks, deps)
+}
+
+// sqliteInsertTaskEvent mirrors insertTaskEvent.
+func sqliteInsertTaskEvent(ctx context.Context, c sqliteConn, event TaskEvent) error {
+	query := `
+		INSERT INTO task_history (event_id, task_id, user_id, event_type, old_value, new_value, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7)`
+
+	_, err := c.q().ExecContext(ctx, query, uuid.New(), event.TaskID, event.UserID, event.EventType, event.OldValue, event.NewValue, sqliteTimestamp(sqliteNow()))
+	if err != nil {
+		return fmt.Errorf("unable to record task event: %w", err)
+	}
+	return nil
+}
+
+func (m *sqliteTasks) GetTaskHistory(taskID, userID uuid.UUID) ([]TaskEvent, error) {
+	query := `
+		SELECT h.event_id, h.task_id, h.user_id, h.event_type, h.old_value, h.new_value, h.created_at
+		FROM task_history h
+		JOIN tasks t ON t.task_id = h.task_id
+		WHERE h.task_id = ?1 AND (t.user_id = ?2 OR t.assignee_id = ?2)
+		ORDER BY h.created_at ASC, h.rowid ASC`
+
+	rows, err := m.db.q().QueryContext(context.Background(), query, taskID, userID)
+	events, err := sqliteCollect(rows, err, func(rows *sql.Rows, event *TaskEvent) error {
+		return rows.Scan(
+			&event.EventID,
+			&event.TaskID,
+			&event.UserID,
+			&event.EventType,
+			&event.OldValue,
+			&event.NewValue,
+			scanTimestamp(&event.CreatedAt),
+		)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to query task history: %w", err)
+	}
+	return events, nil
+}
+
+func (m *sqliteTasks) BulkLabelTasks(userID uuid.UUID, req BulkLabel) (BulkLabelResult, error) {
+	ctx := context.Background()
+
+	var result BulkLabelResult
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		labelIDs := uniqueUUIDs(append(append([]uuid.UUID{}, req.AddLabelIDs...), req.RemoveLabelIDs...))
+		var owned int
+		err := c.q().QueryRowContext(ctx, `SELECT count(*) FROM labels WHERE label_id IN (SELECT value FROM json_each(?1)) AND user_id = ?2`, sqliteIDs(labelIDs), userID).Scan(&owned)
+		if err != nil {
+			return fmt.Errorf("unable to check labels: %w", err)
+		}
+		if owned != len(labelIDs) {
+			return ErrUnknownLabel
+		}
+
+		query := `
+			SELECT task_id FROM tasks
+			WHERE user_id = ?1
+				AND (?2 IS NULL OR task_id IN (SELECT value FROM json_each(?2)))
+				AND (?3 IS NULL OR project_id = ?3)
+				AND (?4 IS NULL OR EXISTS (SELECT 1 FROM task_labels tl WHERE tl.task_id = tasks.task_id AND tl.label_id = ?4))
+				AND (?5 IS NULL OR is_completed = ?5)`
+
+		// An empty ID list means "no ID filter", not "no tasks".
+		var filterIDs any
+		if len(req.TaskIDs) > 0 {
+			filterIDs = sqliteIDs(req.TaskIDs)
+		}
+
+		rows, err := c.q().QueryContext(ctx, query, userID, filterIDs, req.ProjectID, req.LabelID, req.IsCompleted)
+		taskIDs, err := sqliteCollect(rows, err, func(rows *sql.Rows, id *uuid.UUID) error {
+			return rows.Scan(id)
+		})
+		if err != nil {
+			return fmt.Errorf("unable to query tasks: %w", err)
+		}
+
+		result = BulkLabelResult{Matched: len(taskIDs), Added: map[uuid.UUID]int64{}, Removed: map[uuid.UUID]int64{}}
+		for _, labelID := range uniqueUUIDs(req.AddLabelIDs) {
+			res, err := c.q().ExecContext(ctx, `
+				INSERT INTO task_labels (task_id, label_id)
+				SELECT value, ?2 FROM json_each(?1) WHERE true
+				ON CONFLICT (task_id, label_id) DO NOTHING`, sqliteIDs(taskIDs), labelID)
+			if err != nil {
+				return fmt.Errorf("unable to add label: %w", err)
+			}
+			result.Added[labelID] = rowsAffected(res)
+		}
+		for _, labelID := range uniqueUUIDs(req.RemoveLabelIDs) {
+			res, err := c.q().ExecContext(ctx, `
+				DELETE FROM task_labels
+				WHERE task_id IN (SELECT value FROM json_each(?1)) AND label_id = ?2`, sqliteIDs(taskIDs), labelID)
+			if err != nil {
+				return fmt.Errorf("unable to remove label: %w", err)
+			}
+			result.Removed[labelID] = rowsAffected(res)
+		}
+		return nil
+	})
+	if err != nil {
+		return BulkLabelResult{}, err
+	}
+	return result, nil
+}
+
+// sqliteSetTaskLabels mirrors setTaskLabels.
+func sqliteSetTaskLabels(ctx context.Context, c sqliteConn, taskID, userID uuid.UUID, labelIDs []uuid.UUID) error {
+	ids := sqliteIDs(uniqueUUIDs(labelIDs))
+
+	var owned int
+	err := c.q().QueryRowContext(ctx, `SELECT count(*) FROM labels WHERE label_id IN (SELECT value FROM json_each(?1)) AND user_id = ?2`, ids, userID).Scan(&owned)
+	if err != nil {
+		return fmt.Errorf("unable to check labels: %w", err)
+	}
+	if owned != len(uniqueUUIDs(labelIDs)) {
+		return ErrUnknownLabel
+	}
+
+	if _, err := c.q().ExecContext(ctx, `DELETE FROM task_labels WHERE task_id = ?1 AND label_id NOT IN (SELECT value FROM json_each(?2))`, taskID, ids); err != nil {
+		return fmt.Errorf("unable to remove task labels: %w", err)
+	}
+
+	query := `
+		INSERT INTO task_labels (task_id, label_id)
+		SELECT ?1, value FROM json_each(?2) WHERE true
+		ON CONFLICT (task_id, label_id) DO NOTHING`
+
+	if _, err := c.q().ExecContext(ctx, query, taskID, ids); err != nil {
+		return fmt.Errorf("unable to add task labels: %w", err)
+	}
+	return nil
+}
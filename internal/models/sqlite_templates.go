@@ -0,0 +1,253 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sqliteTemplates is SQLiteStore's TemplateStore.
+type sqliteTemplates struct {
+	db sqliteConn
+}
+
+// sqliteTemplateColumns is the column list scanned by scanSQLiteTemplate.
+const sqliteTemplateColumns = `template_id, user_id, name, description, content, created_at`
+
+// scanSQLiteTemplate scans a row selected with sqliteTemplateColumns into template.
+func scanSQLiteTemplate(row sqliteRow, template *Template) error {
+	return row.Scan(
+		&template.TemplateID,
+		&template.UserID,
+		&template.Name,
+		&template.Description,
+		sqliteJSON{&template.Project},
+		scanTimestamp(&template.CreatedAt),
+	)
+}
+
+// sqliteInsertTemplate stores a template with content and returns it.
+func sqliteInsertTemplate(ctx context.Context, c sqliteConn, userID uuid.UUID, name string, description *string, content TemplateProject) (Template, error) {
+	encoded, err := json.Marshal(content)
+	if err != nil {
+		return Template{}, err
+	}
+
+	query := `
+		INSERT INTO templates (template_id, user_id, name, description, content, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+		RETURNING ` + sqliteTemplateColumns
+
+	var template Template
+	err = scanSQLiteTemplate(c.q().QueryRowContext(ctx, query, uuid.New(), userID, name, description, string(encoded), sqliteTimestamp(sqliteNow())), &template)
+	return template, err
+}
+
+func (m *sqliteTemplates) SaveProjectAsTemplate(projectID, userID uuid.UUID, name string, description *string, anchor *time.Time) (Template, error) {
+	ctx := context.Background()
+
+	var template Template
+	err := m.db.begin(ctx, func(c sqliteConn) error {
+		projects, err := sqliteQueryProjects(ctx, c, sqliteProjectSubtree+`
+			SELECT `+projectColumns+`
+			FROM projects
+			WHERE project_id IN (SELECT project_id FROM subtree)
+			ORDER BY created_at ASC, rowid ASC`, projectID, userID)
+		if err != nil {
+			return fmt.Errorf("unable to query projects: %w", err)
+		}
+		if len(projects) == 0 {
+			return ErrNoRecord
+		}
+
+		projectIDs := make([]uuid.UUID, 0, len(projects))
+		for _, p := range projects {
+			projectIDs = append(projectIDs, p.ProjectID)
+		}
+
+		taskQuery := `
+			SELECT ` + sqliteTaskColumns + `
+			FROM tasks
+			WHERE project_id IN (SELECT value FROM json_each(?1)) AND user_id = ?2
+			ORDER BY ` + sqliteTaskOrder
+
+		tasks, err := sqliteQueryTasks(ctx, c, taskQuery, sqliteIDs(projectIDs), userID)
+		if err != nil {
+			return err
+		}
+
+		if anchor == nil {
+			for _, task := range tasks {
+				if task.DueDate != nil && (anchor == nil || task.DueDate.Before(*anchor)) {
+					anchor = task.DueDate
+				}
+			}
+		}
+
+		template, err = sqliteInsertTemplate(ctx, c, userID, name, description, buildTemplateProject(projectID, projects, tasks, anchor))
+		if err != nil {
+			return fmt.Errorf("unable to save template: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Template{}, err
+	}
+	return template, nil
+}
+
+func (m *sqliteTemplates) ImportTemplate(userID uuid.UUID, file TemplateFile) (Template, error) {
+	template, err := sqliteInsertTemplate(context.Background(), m.db, userID, file.Name, file.Description, file.Project)
+	if err != nil {
+		return Template{}, fmt.Errorf("unable to import template: %w", err)
+	}
+	return template, nil
+}
+
+func (m *sqliteTemplates) GetTemplatesByUserID(userID uuid.UUID) ([]Template, error) {
+	query := `
+		SELECT ` + sqliteTemplateColumns + `
+		FROM templates
+		WHERE user_id = ?1
+		ORDER BY name ASC, rowid ASC`
+
+	rows, err := m.db.q().QueryContext(context.Background(), query, userID)
+	templates, err := sqliteCollect(rows, err, func(rows *sql.Rows, template *Template) error {
+		return scanSQLiteTemplate(rows, template)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to query templates: %w", err)
+	}
+	return templates, nil
+}
+
+func (m *sqliteTemplates) GetTemplateByID(templateID, userID uuid.UUID) (Template, error) {
+	query := `
+		SELECT ` + sqliteTemplateColumns + `
+		FROM templates
+		WHERE template_id = ?1 AND user_id = ?2`
+
+	var template Template
+	err := scanSQLiteTemplate(m.db.q().QueryRowContext(context.Background(), query, templateID, userID), &template)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Template{}, ErrNoRecord
+	}
+	if err != nil {
+		return Template{}, fmt.Errorf("unable to fetch template: %w", err)
+	}
+	return template, nil
+}
+
+func (m *sqliteTemplates) DeleteTemplateByID(templateID, userID uuid.UUID) (int64, error) {
+	query := `DELETE FROM templates WHERE template_id = ?1 AND user_id = ?2`
+
+	result, err := m.db.q().ExecContext(context.Background(), query, templateID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete template: %w", err)
+	}
+	return rowsAffected(result), nil
+}
+
+func (m *sqliteTemplates) InstantiateTemplate(templateID, userID uuid.UUID, anchor time.Time, projectName *string) (Project, error) {
+	template, err := m.GetTemplateByID(templateID, userID)
+	if err != nil {
+		return Project{}, err
+	}
+
+	root := template.Project
+	if projectName != nil {
+		root.ProjectName = *projectName
+	}
+
+	ctx := context.Background()
+
+	var project Project
+	err = m.db.begin(ctx, func(c sqliteConn) error {
+		rootID, err := sqliteInstantiateTemplateProject(ctx, c, userID, root, nil, anchor)
+		if err != nil {
+			return err
+		}
+
+		err = scanSQLiteProject(c.q().QueryRowContext(ctx, `SELECT `+projectColumns+` FROM projects WHERE project_id = ?`, rootID), &project)
+		if err != nil {
+			return fmt.Errorf("unable to fetch project: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Project{}, err
+	}
+	return project, nil
+}
+
+// sqliteInstantiateTemplateProject mirrors instantiateTemplateProject.
+func sqliteInstantiateTemplateProject(ctx context.Context, c sqliteConn, userID uuid.UUID, tp TemplateProject, parentID *uuid.UUID, anchor time.Time) (uuid.UUID, error) {
+	order, err := sqliteNextProjectOrder(ctx, c, userID, parentID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	projectID := uuid.New()
+	_, err = c.q().ExecContext(ctx, `
+		INSERT INTO projects (project_id, user_id, project_name, color, parent_project_id, "order", created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7)`,
+		projectID, userID, tp.ProjectName, tp.Color, parentID, order, sqliteTimestamp(sqliteNow()))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unable to create project: %w", err)
+	}
+
+	labelIDs, err := sqliteEnsureLabels(ctx, c, userID, templateLabelNames(tp.Tasks))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// sqliteInsertTaskCopy maps template-local IDs to fresh ones, so every template task gets a
+	// placeholder ID and its subtasks point at it.
+	idMap := map[uuid.UUID]uuid.UUID{}
+	projectMap := map[uuid.UUID]uuid.UUID{projectID: projectID}
+	var insert func(tasks []TemplateTask, parent *uuid.UUID) error
+	insert = func(tasks []TemplateTask, parent *uuid.UUID) error {
+		for i, tt := range tasks {
+			placeholder := uuid.New()
+			task := Task{
+				TaskID:       placeholder,
+				ProjectID:    &projectID,
+				Content:      tt.Content,
+				Description:  tt.Description,
+				Priority:     tt.Priority,
+				ParentTaskID: parent,
+				Order:        i * OrderGap,
+			}
+			for _, name := range tt.Labels {
+				task.LabelIDs = append(task.LabelIDs, labelIDs[strings.ToLower(name)])
+			}
+			if tt.DueOffsetDays != nil {
+				due := anchor.AddDate(0, 0, *tt.DueOffsetDays)
+				task.DueDate = &due
+			}
+			if err := sqliteInsertTaskCopy(ctx, c, task, userID, idMap, projectMap, DuplicateOptions{}); err != nil {
+				return err
+			}
+			if err := insert(tt.Subtasks, &placeholder); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := insert(tp.Tasks, nil); err != nil {
+		return uuid.Nil, err
+	}
+
+	for _, sub := range tp.SubProjects {
+		if _, err := sqliteInstantiateTemplateProject(ctx, c, userID, sub, &projectID, anchor); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	return projectID, nil
+}
@@ -0,0 +1,34 @@
+package models_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dmcleish91/go_todo_api/internal/migrations"
+	"github.com/dmcleish91/go_todo_api/internal/models"
+	"github.com/dmcleish91/go_todo_api/internal/models/storetest"
+	"github.com/google/uuid"
+)
+
+// TestSQLiteStore runs the conformance suite against a freshly migrated database file per test.
+func TestSQLiteStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Stores {
+		store, err := models.OpenSQLite(filepath.Join(t.TempDir(), "todo.db"))
+		if err != nil {
+			t.Fatalf("unable to open database: %v", err)
+		}
+		t.Cleanup(func() { store.Close() })
+
+		if _, err := (&migrations.SQLiteMigrator{DB: store.DB()}).Up(context.Background()); err != nil {
+			t.Fatalf("unable to migrate: %v", err)
+		}
+
+		return storetest.Stores{
+			Tasks:    store.Tasks(),
+			Projects: store.Projects(),
+			Labels:   store.Labels(),
+			NewUser:  func(t *testing.T) uuid.UUID { return uuid.New() },
+		}
+	})
+}
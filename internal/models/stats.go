@@ -0,0 +1,251 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Stats summarises a user's productivity. Days and weeks are bucketed in the user's timezone.
+type Stats struct {
+	Timezone  string `json:"timezone"`
+	DailyGoal int    `json:"daily_goal"`
+
+	Days  []DayCount  `json:"days"`  // oldest first, including days without completions
+	Weeks []WeekCount `json:"weeks"` // oldest first; weeks start on Monday
+
+	// Streaks count consecutive days on which at least DailyGoal tasks were completed. The current
+	// streak is still alive if it ended yesterday, since today may not be over yet.
+	CurrentStreak int `json:"current_streak"`
+	LongestStreak int `json:"longest_streak"`
+
+	ByProject []ProjectStats `json:"by_project"`
+	ByLabel   []LabelStats   `json:"by_label"`
+
+	// AverageCompletionHours is the mean time from created_at to completed_at, nil without completions.
+	AverageCompletionHours *float64 `json:"average_completion_hours"`
+	Overdue                int      `json:"overdue"`
+}
+
+type DayCount struct {
+	Date      string `json:"date"`
+	Completed int    `json:"completed"`
+}
+
+type WeekCount struct {
+	WeekStart string `json:"week_start"`
+	Completed int    `json:"completed"`
+}
+
+// ProjectStats counts tasks per project. Tasks outside any project have a nil ProjectID.
+type ProjectStats struct {
+	ProjectID   *uuid.UUID `json:"project_id"`
+	ProjectName *string    `json:"project_name"`
+	Completed   int        `json:"completed"`
+	Open        int        `json:"open"`
+}
+
+type LabelStats struct {
+	LabelID   uuid.UUID `json:"label_id"`
+	Name      string    `json:"name"`
+	Completed int       `json:"completed"`
+	Open      int       `json:"open"`
+}
+
+// StatsRange sets how many days and weeks of history GetStats returns, counting the current one.
+type StatsRange struct {
+	Days  int
+	Weeks int
+}
+
+// DefaultStatsRange is used when the client doesn't ask for a specific range, and
+// MaxStatsRange bounds what a single request can ask for.
+var (
+	DefaultStatsRange = StatsRange{Days: 30, Weeks: 12}
+	MaxStatsRange     = StatsRange{Days: 366, Weeks: 104}
+)
+
+// userTasksCTE selects every task a user owns or is assigned to, live or archived. $1 is the user.
+const userTasksCTE = `
+	user_tasks AS (
+		SELECT task_id, project_id, is_completed, completed_at, created_at, due_date, due_datetime
+		FROM tasks
+		WHERE user_id = $1 OR assignee_id = $1
+		UNION ALL
+		SELECT task_id, project_id, true, completed_at, created_at, due_date, due_datetime
+		FROM archived_tasks
+		WHERE user_id = $1 OR assignee_id = $1
+	)`
+
+// GetStats aggregates the completion statistics for userID using settings' timezone and daily goal.
+// All queries run in one read-only transaction so the numbers agree with each other.
+func (m *TaskModel) GetStats(userID uuid.UUID, settings UserSettings, r StatsRange) (Stats, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`); err != nil {
+		return Stats{}, fmt.Errorf("unable to set transaction mode: %w", err)
+	}
+
+	stats := Stats{Timezone: settings.Timezone, DailyGoal: settings.DailyGoal}
+	tz := settings.Timezone
+
+	dayQuery := `
+		WITH ` + userTasksCTE + `,
+		days AS (
+			SELECT generate_series((now() AT TIME ZONE $2)::date - ($3::int - 1), (now() AT TIME ZONE $2)::date, interval '1 day')::date AS day
+		)
+		SELECT to_char(d.day, 'YYYY-MM-DD'), count(t.task_id)
+		FROM days d
+		LEFT JOIN user_tasks t ON t.is_completed AND (t.completed_at AT TIME ZONE $2)::date = d.day
+		GROUP BY d.day
+		ORDER BY d.day`
+
+	rows, err := tx.Query(ctx, dayQuery, userID, tz, r.Days)
+	if err != nil {
+		return Stats{}, fmt.Errorf("unable to query daily completions: %w", err)
+	}
+	stats.Days, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (DayCount, error) {
+		var d DayCount
+		err := row.Scan(&d.Date, &d.Completed)
+		return d, err
+	})
+	if err != nil {
+		return Stats{}, fmt.Errorf("unable to scan daily completions: %w", err)
+	}
+
+	weekQuery := `
+		WITH ` + userTasksCTE + `,
+		weeks AS (
+			SELECT generate_series(
+				date_trunc('week', (now() AT TIME ZONE $2)::date) - ($3::int - 1) * interval '1 week',
+				date_trunc('week', (now() AT TIME ZONE $2)::date),
+				interval '1 week')::date AS week
+		)
+		SELECT to_char(w.week, 'YYYY-MM-DD'), count(t.task_id)
+		FROM weeks w
+		LEFT JOIN user_tasks t ON t.is_completed AND date_trunc('week', t.completed_at AT TIME ZONE $2)::date = w.week
+		GROUP BY w.week
+		ORDER BY w.week`
+
+	rows, err = tx.Query(ctx, weekQuery, userID, tz, r.Weeks)
+	if err != nil {
+		return Stats{}, fmt.Errorf("unable to query weekly completions: %w", err)
+	}
+	stats.Weeks, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (WeekCount, error) {
+		var w WeekCount
+		err := row.Scan(&w.WeekStart, &w.Completed)
+		return w, err
+	})
+	if err != nil {
+		return Stats{}, fmt.Errorf("unable to scan weekly completions: %w", err)
+	}
+
+	// Gaps and islands: consecutive goal days share the same day - row_number.
+	streakQuery := `
+		WITH ` + userTasksCTE + `,
+		goal_days AS (
+			SELECT (completed_at AT TIME ZONE $2)::date AS day
+			FROM user_tasks
+			WHERE is_completed
+			GROUP BY 1
+			HAVING count(*) >= $3
+		), runs AS (
+			SELECT max(day) AS last_day, count(*) AS length
+			FROM (SELECT day, day - (ROW_NUMBER() OVER (ORDER BY day))::int AS island FROM goal_days) g
+			GROUP BY island
+		)
+		SELECT
+			COALESCE(max(length) FILTER (WHERE last_day >= (now() AT TIME ZONE $2)::date - 1), 0),
+			COALESCE(max(length), 0)
+		FROM runs`
+
+	err = tx.QueryRow(ctx, streakQuery, userID, tz, settings.DailyGoal).Scan(&stats.CurrentStreak, &stats.LongestStreak)
+	if err != nil {
+		return Stats{}, fmt.Errorf("unable to query streaks: %w", err)
+	}
+
+	projectQuery := `
+		WITH ` + userTasksCTE + `
+		SELECT t.project_id, p.project_name, count(*) FILTER (WHERE t.is_completed), count(*) FILTER (WHERE NOT t.is_completed)
+		FROM user_tasks t
+		LEFT JOIN projects p ON p.project_id = t.project_id
+		GROUP BY t.project_id, p.project_name
+		ORDER BY 3 DESC, 2 ASC NULLS FIRST`
+
+	rows, err = tx.Query(ctx, projectQuery, userID)
+	if err != nil {
+		return Stats{}, fmt.Errorf("unable to query project stats: %w", err)
+	}
+	stats.ByProject, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (ProjectStats, error) {
+		var p ProjectStats
+		err := row.Scan(&p.ProjectID, &p.ProjectName, &p.Completed, &p.Open)
+		return p, err
+	})
+	if err != nil {
+		return Stats{}, fmt.Errorf("unable to scan project stats: %w", err)
+	}
+
+	// Archived tasks only keep label names, so they are matched to the user's labels by name.
+	labelQuery := `
+		WITH labelled AS (
+			SELECT tl.label_id, t.is_completed
+			FROM task_labels tl
+			JOIN tasks t ON t.task_id = tl.task_id
+			WHERE t.user_id = $1 OR t.assignee_id = $1
+			UNION ALL
+			SELECT l.label_id, true
+			FROM archived_tasks a
+			CROSS JOIN LATERAL unnest(a.labels) AS n(name)
+			JOIN labels l ON l.user_id = $1 AND lower(l.name) = lower(n.name)
+			WHERE a.user_id = $1 OR a.assignee_id = $1
+		)
+		SELECT l.label_id, l.name, count(*) FILTER (WHERE x.is_completed), count(*) FILTER (WHERE NOT x.is_completed)
+		FROM labels l
+		JOIN labelled x ON x.label_id = l.label_id
+		WHERE l.user_id = $1
+		GROUP BY l.label_id, l.name
+		ORDER BY 3 DESC, 2 ASC`
+
+	rows, err = tx.Query(ctx, labelQuery, userID)
+	if err != nil {
+		return Stats{}, fmt.Errorf("unable to query label stats: %w", err)
+	}
+	stats.ByLabel, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (LabelStats, error) {
+		var l LabelStats
+		err := row.Scan(&l.LabelID, &l.Name, &l.Completed, &l.Open)
+		return l, err
+	})
+	if err != nil {
+		return Stats{}, fmt.Errorf("unable to scan label stats: %w", err)
+	}
+
+	// A task is overdue once its due date has passed in the user's timezone, or on the due date
+	// once its due time has passed.
+	summaryQuery := `
+		WITH ` + userTasksCTE + `
+		SELECT
+			avg(extract(epoch FROM completed_at - created_at)) FILTER (WHERE is_completed AND completed_at IS NOT NULL) / 3600,
+			count(*) FILTER (WHERE NOT is_completed AND (
+				due_date < (now() AT TIME ZONE $2)::date
+				OR (due_date = (now() AT TIME ZONE $2)::date AND due_datetime < (now() AT TIME ZONE $2)::time)
+			))
+		FROM user_tasks`
+
+	err = tx.QueryRow(ctx, summaryQuery, userID, tz).Scan(&stats.AverageCompletionHours, &stats.Overdue)
+	if err != nil {
+		return Stats{}, fmt.Errorf("unable to query completion summary: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Stats{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// statsTask is the part of a live or archived task that computeStats looks at.
+type statsTask struct {
+	projectID   *uuid.UUID
+	projectName *string // nil when the task has no project or the project is gone
+	completed   bool
+	completedAt *time.Time
+	createdAt   time.Time
+	dueDate     *time.Time
+	dueDatetime *time.Time
+}
+
+// statsLabelUse records that one of the user's tasks carries a label.
+type statsLabelUse struct {
+	labelID   uuid.UUID
+	name      string
+	completed bool
+}
+
+// computeStats aggregates tasks the way TaskModel.GetStats does in SQL, bucketing days in
+// settings' timezone. Stores whose database can't convert timezones gather the rows and call it.
+func computeStats(settings UserSettings, r StatsRange, tasks []statsTask, labelled []statsLabelUse) (Stats, error) {
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return Stats{}, fmt.Errorf("unable to load timezone: %w", err)
+	}
+
+	stats := Stats{Timezone: settings.Timezone, DailyGoal: settings.DailyGoal}
+	now := time.Now().In(loc)
+	today := civilDate(now)
+
+	perDay := map[time.Time]int{}
+	for _, t := range tasks {
+		if t.completed && t.completedAt != nil {
+			perDay[civilDate(t.completedAt.In(loc))]++
+		}
+	}
+
+	stats.Days = make([]DayCount, 0, max(r.Days, 0))
+	for i := r.Days - 1; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i)
+		stats.Days = append(stats.Days, DayCount{Date: day.Format("2006-01-02"), Completed: perDay[day]})
+	}
+
+	perWeek := map[time.Time]int{}
+	for day, n := range perDay {
+		perWeek[weekStart(day)] += n
+	}
+	stats.Weeks = make([]WeekCount, 0, max(r.Weeks, 0))
+	for i := r.Weeks - 1; i >= 0; i-- {
+		week := weekStart(today).AddDate(0, 0, -7*i)
+		stats.Weeks = append(stats.Weeks, WeekCount{WeekStart: week.Format("2006-01-02"), Completed: perWeek[week]})
+	}
+
+	stats.CurrentStreak, stats.LongestStreak = streaks(perDay, settings.DailyGoal, today)
+	stats.ByProject = projectStats(tasks)
+	stats.ByLabel = labelStats(labelled)
+
+	var hours float64
+	var completions int
+	for _, t := range tasks {
+		if t.completed && t.completedAt != nil {
+			hours += t.completedAt.Sub(t.createdAt).Hours()
+			completions++
+		}
+		if !t.completed && isOverdue(t, now) {
+			stats.Overdue++
+		}
+	}
+	if completions > 0 {
+		average := hours / float64(completions)
+		stats.AverageCompletionHours = &average
+	}
+	return stats, nil
+}
+
+// streaks returns the current and longest runs of consecutive days with at least goal
+// completions. The current run is still alive if it ended yesterday.
+func streaks(perDay map[time.Time]int, goal int, today time.Time) (current, longest int) {
+	var days []time.Time
+	for day, n := range perDay {
+		if n >= goal {
+			days = append(days, day)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	length := 0
+	for i, day := range days {
+		if i > 0 && days[i-1].AddDate(0, 0, 1).Equal(day) {
+			length++
+		} else {
+			length = 1
+		}
+		longest = max(longest, length)
+		last := i == len(days)-1 || !day.AddDate(0, 0, 1).Equal(days[i+1])
+		if last && !day.Before(today.AddDate(0, 0, -1)) {
+			current = max(current, length)
+		}
+	}
+	return current, longest
+}
+
+// projectStats counts completed and open tasks per project, most completions first.
+func projectStats(tasks []statsTask) []ProjectStats {
+	byProject := map[uuid.UUID]*ProjectStats{}
+	var none *ProjectStats
+	for _, t := range tasks {
+		var ps *ProjectStats
+		if t.projectID == nil {
+			if none == nil {
+				none = &ProjectStats{}
+			}
+			ps = none
+		} else {
+			if byProject[*t.projectID] == nil {
+				byProject[*t.projectID] = &ProjectStats{ProjectID: t.projectID, ProjectName: t.projectName}
+			}
+			ps = byProject[*t.projectID]
+		}
+		if t.completed {
+			ps.Completed++
+		} else {
+			ps.Open++
+		}
+	}
+
+	result := []ProjectStats{}
+	if none != nil {
+		result = append(result, *none)
+	}
+	for _, ps := range byProject {
+		result = append(result, *ps)
+	}
+	// Ordered by completions, then by name with unnamed projects first.
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Completed != b.Completed {
+			return a.Completed > b.Completed
+		}
+		if (a.ProjectName == nil) != (b.ProjectName == nil) {
+			return a.ProjectName == nil
+		}
+		if a.ProjectName != nil && *a.ProjectName != *b.ProjectName {
+			return *a.ProjectName < *b.ProjectName
+		}
+		if (a.ProjectID == nil) != (b.ProjectID == nil) {
+			return a.ProjectID == nil
+		}
+		return a.ProjectID != nil && lessUUID(*a.ProjectID, *b.ProjectID)
+	})
+	return result
+}
+
+// labelStats counts completed and open tasks per label, most completions first.
+func labelStats(labelled []statsLabelUse) []LabelStats {
+	byLabel := map[uuid.UUID]*LabelStats{}
+	for _, use := range labelled {
+		ls := byLabel[use.labelID]
+		if ls == nil {
+			ls = &LabelStats{LabelID: use.labelID, Name: use.name}
+			byLabel[use.labelID] = ls
+		}
+		if use.completed {
+			ls.Completed++
+		} else {
+			ls.Open++
+		}
+	}
+
+	result := []LabelStats{}
+	for _, ls := range byLabel {
+		result = append(result, *ls)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Completed != b.Completed {
+			return a.Completed > b.Completed
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return lessUUID(a.LabelID, b.LabelID)
+	})
+	return result
+}
+
+// isOverdue reports whether t's due date has passed at now, or it is due today and its due
+// time has passed.
+func isOverdue(t statsTask, now time.Time) bool {
+	if t.dueDate == nil {
+		return false
+	}
+	due, today := civilDate(*t.dueDate), civilDate(now)
+	if due.Before(today) {
+		return true
+	}
+	return due.Equal(today) && t.dueDatetime != nil && timeOfDay(*t.dueDatetime) < timeOfDay(now)
+}
+
+// civilDate returns t's calendar date, in t's location, as midnight UTC.
+func civilDate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// weekStart returns the Monday of the week holding day.
+func weekStart(day time.Time) time.Time {
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// timeOfDay returns how far into its day t is, in t's location.
+func timeOfDay(t time.Time) time.Duration {
+	return t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()))
+}
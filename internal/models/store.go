@@ -0,0 +1,110 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// TaskStore stores tasks together with their labels, dependencies, history and archive.
+// TaskModel implements it on Postgres, SQLiteStore on SQLite and MemoryStore in memory; all
+// three are held to the same behaviour by the conformance suite in internal/models/storetest.
+type TaskStore interface {
+	AddTask(input NewTask, userID uuid.UUID) (Task, error)
+	EditTaskByID(task Task) (Task, error)
+	GetTasksByUserID(userID uuid.UUID, filter TaskFilter) ([]Task, error)
+	ToggleTaskCompleted(taskID, userID uuid.UUID, rules CompletionRules) (Task, error)
+	SetTaskCompleted(taskID, userID uuid.UUID, completed bool, completedAt *time.Time, rules CompletionRules) (task Task, changed bool, err error)
+	DeleteTaskByID(taskID, userID uuid.UUID) (int64, error)
+
+	BulkUpdateTaskOrder(userID uuid.UUID, updates []TaskOrderUpdate) (TaskOrderRejection, error)
+	PositionTask(taskID, userID uuid.UUID, pos TaskPosition) (Task, error)
+	MoveTask(taskID, userID uuid.UUID, move TaskMove) (Task, error)
+	DropTask(userID uuid.UUID, drop TaskDrop) (TaskDropResult, error)
+	DuplicateTask(taskID, userID uuid.UUID, opts DuplicateOptions) (Task, error)
+	RebalanceTaskOrders(ctx context.Context) (int64, error)
+
+	AddDependency(taskID, blockedByID, userID uuid.UUID) (TaskDependency, error)
+	RemoveDependency(taskID, blockedByID, userID uuid.UUID) (int64, error)
+	GetTaskDependencies(taskID, userID uuid.UUID) (TaskDependencies, error)
+	GetProjectTasksInTopologicalOrder(projectID, userID uuid.UUID) ([]Task, error)
+
+	GetTaskHistory(taskID, userID uuid.UUID) ([]TaskEvent, error)
+	BulkLabelTasks(userID uuid.UUID, req BulkLabel) (BulkLabelResult, error)
+	GetCompletedTasks(userID uuid.UUID, filter CompletedTaskFilter) ([]CompletedTask, error)
+	ArchiveCompletedTasks(ctx context.Context, cutoff time.Time) (int64, error)
+	GetStats(userID uuid.UUID, settings UserSettings, r StatsRange) (Stats, error)
+
+	// Atomic runs fn against a store whose changes are kept only if fn returns nil. Calling
+	// Atomic on that store nests, so an inner failure only undoes the inner changes.
+	Atomic(ctx context.Context, fn func(TaskStore) error) error
+}
+
+// ProjectStore stores projects and their members.
+type ProjectStore interface {
+	AddProject(project Project) (Project, error)
+	EditProjectByID(project Project) (Project, error)
+	GetProjectsByUserID(userID uuid.UUID, includeArchived bool) ([]Project, error)
+	ArchiveProject(projectID, userID uuid.UUID, archived bool) ([]Project, error)
+	BulkUpdateProjectOrder(userID uuid.UUID, updates []ProjectOrderUpdate) error
+	DeleteProjectByID(projectID, userID uuid.UUID) (int64, error)
+	DuplicateProject(projectID, userID uuid.UUID, opts DuplicateOptions) (Project, error)
+
+	AddProjectMember(projectID, ownerID, memberID uuid.UUID) (ProjectMember, error)
+	GetProjectMembers(projectID, userID uuid.UUID) ([]ProjectMember, error)
+	RemoveProjectMember(projectID, ownerID, memberID uuid.UUID) (int64, error)
+	IsProjectMember(projectID, userID uuid.UUID) (bool, error)
+}
+
+// LabelStore stores labels.
+type LabelStore interface {
+	AddLabel(label Label) (Label, error)
+	EditLabelByID(label Label) (Label, error)
+	GetLabelsByUserID(userID uuid.UUID) ([]Label, error)
+	ReorderLabels(userID uuid.UUID, labelIDs []uuid.UUID) ([]Label, error)
+	DeleteLabelByID(labelID, userID uuid.UUID) (int64, error)
+	MergeLabels(userID, targetID uuid.UUID, sourceIDs []uuid.UUID) (LabelMergeResult, error)
+}
+
+// SettingsStore stores per-user settings.
+type SettingsStore interface {
+	GetSettings(userID uuid.UUID) (UserSettings, error)
+	UpdateSettings(settings UserSettings) (UserSettings, error)
+}
+
+// TemplateStore stores project templates.
+type TemplateStore interface {
+	SaveProjectAsTemplate(projectID, userID uuid.UUID, name string, description *string, anchor *time.Time) (Template, error)
+	ImportTemplate(userID uuid.UUID, file TemplateFile) (Template, error)
+	GetTemplatesByUserID(userID uuid.UUID) ([]Template, error)
+	GetTemplateByID(templateID, userID uuid.UUID) (Template, error)
+	DeleteTemplateByID(templateID, userID uuid.UUID) (int64, error)
+	InstantiateTemplate(templateID, userID uuid.UUID, anchor time.Time, projectName *string) (Project, error)
+}
+
+// IdempotencyStore stores the responses replayed for Idempotency-Key retries.
+type IdempotencyStore interface {
+	Reserve(userID uuid.UUID, key, requestHash string, lease time.Duration) (bool, IdempotentResponse, error)
+	Save(userID uuid.UUID, key string, response IdempotentResponse, ttl time.Duration) error
+	Release(userID uuid.UUID, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+var (
+	_ TaskStore        = (*TaskModel)(nil)
+	_ ProjectStore     = (*ProjectModel)(nil)
+	_ LabelStore       = (*LabelModel)(nil)
+	_ SettingsStore    = (*SettingsModel)(nil)
+	_ TemplateStore    = (*TemplateModel)(nil)
+	_ IdempotencyStore = (*IdempotencyModel)(nil)
+)
+
+// Atomic runs fn against a TaskModel bound to a new transaction, or to a savepoint when m is
+// already bound to one. The transaction commits if fn returns nil and rolls back otherwise.
+func (m *TaskModel) Atomic(ctx context.Context, fn func(TaskStore) error) error {
+	return pgx.BeginFunc(ctx, m.DB, func(tx pgx.Tx) error {
+		return fn(m.WithTx(tx))
+	})
+}
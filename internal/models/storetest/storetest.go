@@ -0,0 +1,1166 @@
+// Package storetest is the conformance suite for the task, project and label stores. Every
+// backend runs it from its own tests, so they all keep the behaviour the handlers rely on:
+// ownership scoping, cascading deletes and sibling ordering.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dmcleish91/go_todo_api/internal/models"
+	"github.com/google/uuid"
+)
+
+// Stores is one backend under test.
+type Stores struct {
+	Tasks    models.TaskStore
+	Projects models.ProjectStore
+	Labels   models.LabelStore
+
+	// NewUser returns a user ID the backend has never seen and accepts as an owner.
+	NewUser func(t *testing.T) uuid.UUID
+}
+
+// Run runs the suite. open is called once per test and may return stores shared with other
+// tests, since every test works with fresh users. Tests that exercise the global maintenance
+// jobs only check the rows of their own users.
+func Run(t *testing.T, open func(t *testing.T) Stores) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s Stores)
+	}{
+		{"TaskOwnership", testTaskOwnership},
+		{"AssigneeVisibility", testAssigneeVisibility},
+		{"AppendOrder", testAppendOrder},
+		{"PositionTask", testPositionTask},
+		{"BulkUpdateTaskOrder", testBulkUpdateTaskOrder},
+		{"MoveTask", testMoveTask},
+		{"DropTask", testDropTask},
+		{"RebalanceTaskOrders", testRebalanceTaskOrders},
+		{"DuplicateTask", testDuplicateTask},
+		{"CompletionRules", testCompletionRules},
+		{"Dependencies", testDependencies},
+		{"TaskCascades", testTaskCascades},
+		{"ProjectCascades", testProjectCascades},
+		{"Labels", testLabels},
+		{"MergeLabels", testMergeLabels},
+		{"BulkLabelTasks", testBulkLabelTasks},
+		{"Projects", testProjects},
+		{"ProjectMembers", testProjectMembers},
+		{"DuplicateProject", testDuplicateProject},
+		{"DuplicateInbox", testDuplicateInbox},
+		{"ArchiveCompletedTasks", testArchiveCompletedTasks},
+		{"Atomic", testAtomic},
+		{"Stats", testStats},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+func testTaskOwnership(t *testing.T, s Stores) {
+	owner, other := s.NewUser(t), s.NewUser(t)
+	task := addTask(t, s, owner, models.NewTask{Content: "write report"})
+
+	if task.UserID != owner || task.CreatedBy != owner || task.Content != "write report" || task.IsCompleted {
+		t.Fatalf("unexpected task %+v", task)
+	}
+	if len(task.Labels) != 0 || len(task.LabelIDs) != 0 {
+		t.Fatalf("new task has labels %v", task.Labels)
+	}
+
+	if got := listTasks(t, s, other); len(got) != 0 {
+		t.Fatalf("other user sees %d tasks", len(got))
+	}
+
+	foreign := task
+	foreign.UserID = other
+	_, err := s.Tasks.EditTaskByID(foreign)
+	wantErr(t, err, models.ErrNoRecord)
+
+	n, err := s.Tasks.DeleteTaskByID(task.TaskID, other)
+	check(t, err)
+	if n != 0 {
+		t.Fatalf("other user deleted %d tasks", n)
+	}
+
+	// Edits leave the completion state alone; it only changes through the completion methods.
+	task.Content = "write final report"
+	task.IsCompleted = true
+	edited, err := s.Tasks.EditTaskByID(task)
+	check(t, err)
+	if edited.Content != "write final report" {
+		t.Fatalf("edit not applied: %+v", edited)
+	}
+	if edited.IsCompleted || edited.CompletedAt != nil {
+		t.Fatalf("edit completed the task: %+v", edited)
+	}
+
+	_, err = s.Tasks.ToggleTaskCompleted(task.TaskID, other, models.CompletionRules{})
+	wantErr(t, err, models.ErrNoRecord)
+	completed, err := s.Tasks.ToggleTaskCompleted(task.TaskID, owner, models.CompletionRules{})
+	check(t, err)
+	if !completed.IsCompleted || completed.CompletedAt == nil {
+		t.Fatalf("task not completed: %+v", completed)
+	}
+
+	if got := listTasks(t, s, owner); len(got) != 0 {
+		t.Fatalf("completed task listed without IncludeCompleted")
+	}
+	all, err := s.Tasks.GetTasksByUserID(owner, models.TaskFilter{IncludeCompleted: true})
+	check(t, err)
+	if len(all) != 1 {
+		t.Fatalf("got %d tasks with IncludeCompleted, want 1", len(all))
+	}
+
+	n, err = s.Tasks.DeleteTaskByID(task.TaskID, owner)
+	check(t, err)
+	if n != 1 {
+		t.Fatalf("deleted %d tasks, want 1", n)
+	}
+}
+
+func testAssigneeVisibility(t *testing.T, s Stores) {
+	owner, assignee := s.NewUser(t), s.NewUser(t)
+	task := addTask(t, s, owner, models.NewTask{})
+	addTask(t, s, owner, models.NewTask{})
+
+	task.AssigneeID = &assignee
+	task, err := s.Tasks.EditTaskByID(task)
+	check(t, err)
+
+	visible := listTasks(t, s, assignee)
+	if len(visible) != 1 || visible[0].TaskID != task.TaskID {
+		t.Fatalf("assignee sees %v, want only %s", taskIDs(visible), task.TaskID)
+	}
+	mine, err := s.Tasks.GetTasksByUserID(owner, models.TaskFilter{AssigneeID: &assignee})
+	check(t, err)
+	if len(mine) != 1 {
+		t.Fatalf("assignee filter returned %d tasks, want 1", len(mine))
+	}
+
+	// Assignees may complete a task but not edit or delete it.
+	_, err = s.Tasks.ToggleTaskCompleted(task.TaskID, assignee, models.CompletionRules{})
+	check(t, err)
+	edit := task
+	edit.UserID = assignee
+	_, err = s.Tasks.EditTaskByID(edit)
+	wantErr(t, err, models.ErrNoRecord)
+	n, err := s.Tasks.DeleteTaskByID(task.TaskID, assignee)
+	check(t, err)
+	if n != 0 {
+		t.Fatalf("assignee deleted the task")
+	}
+
+	history, err := s.Tasks.GetTaskHistory(task.TaskID, assignee)
+	check(t, err)
+	if len(history) != 1 || history[0].EventType != models.TaskEventReassigned || history[0].OldValue != nil ||
+		history[0].NewValue == nil || *history[0].NewValue != assignee.String() {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	history, err = s.Tasks.GetTaskHistory(task.TaskID, s.NewUser(t))
+	check(t, err)
+	if len(history) != 0 {
+		t.Fatalf("stranger sees %d history events", len(history))
+	}
+}
+
+func testAppendOrder(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	a := addTask(t, s, user, models.NewTask{})
+	b := addTask(t, s, user, models.NewTask{})
+	sub := addTask(t, s, user, models.NewTask{ParentTaskID: &a.TaskID})
+	c := addTask(t, s, user, models.NewTask{})
+
+	wantOrders(t, []models.Task{a, b, c}, 0, models.OrderGap, 2*models.OrderGap)
+	if sub.Order != 0 {
+		t.Fatalf("first subtask has order %d, want 0", sub.Order)
+	}
+
+	// Another user's list is independent.
+	other := addTask(t, s, s.NewUser(t), models.NewTask{})
+	if other.Order != 0 {
+		t.Fatalf("other user's first task has order %d, want 0", other.Order)
+	}
+}
+
+func testPositionTask(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	a := addTask(t, s, user, models.NewTask{})
+	b := addTask(t, s, user, models.NewTask{})
+	c := addTask(t, s, user, models.NewTask{})
+	d := addTask(t, s, user, models.NewTask{})
+
+	moved, err := s.Tasks.PositionTask(c.TaskID, user, models.TaskPosition{AfterID: &a.TaskID, BeforeID: &b.TaskID})
+	check(t, err)
+	if moved.Order != models.OrderGap/2 {
+		t.Fatalf("positioned between 0 and %d at %d", models.OrderGap, moved.Order)
+	}
+	wantListOrder(t, s, user, nil, a.TaskID, c.TaskID, b.TaskID, d.TaskID)
+
+	_, err = s.Tasks.PositionTask(a.TaskID, user, models.TaskPosition{BeforeID: &a.TaskID})
+	wantErr(t, err, models.ErrInvalidPosition)
+	_, err = s.Tasks.PositionTask(d.TaskID, user, models.TaskPosition{AfterID: &a.TaskID, BeforeID: &b.TaskID})
+	wantErr(t, err, models.ErrInvalidPosition)
+
+	sub := addTask(t, s, user, models.NewTask{ParentTaskID: &a.TaskID})
+	_, err = s.Tasks.PositionTask(b.TaskID, user, models.TaskPosition{BeforeID: &sub.TaskID})
+	wantErr(t, err, models.ErrNotSiblings)
+	missing := uuid.New()
+	_, err = s.Tasks.PositionTask(b.TaskID, user, models.TaskPosition{BeforeID: &missing})
+	wantErr(t, err, models.ErrNoRecord)
+	_, err = s.Tasks.PositionTask(b.TaskID, s.NewUser(t), models.TaskPosition{})
+	wantErr(t, err, models.ErrNoRecord)
+
+	// With no room between the neighbours the list is rebalanced first.
+	project := addProject(t, s, user, "tight", nil)
+	x := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID, Order: ptr(0)})
+	y := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID, Order: ptr(1)})
+	z := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID})
+	_, err = s.Tasks.PositionTask(z.TaskID, user, models.TaskPosition{AfterID: &x.TaskID})
+	check(t, err)
+	list := wantListOrder(t, s, user, &project.ProjectID, x.TaskID, z.TaskID, y.TaskID)
+	wantOrders(t, list, 0, models.OrderGap/2, models.OrderGap)
+
+	// Without neighbours the task goes to the end.
+	_, err = s.Tasks.PositionTask(x.TaskID, user, models.TaskPosition{})
+	check(t, err)
+	wantListOrder(t, s, user, &project.ProjectID, z.TaskID, y.TaskID, x.TaskID)
+}
+
+func testBulkUpdateTaskOrder(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	project := addProject(t, s, user, "list", nil)
+	a := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID})
+	b := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID})
+	c := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID})
+
+	_, err := s.Tasks.BulkUpdateTaskOrder(user, taskOrders(c.TaskID, 0, a.TaskID, 1, b.TaskID, 2))
+	check(t, err)
+	wantListOrder(t, s, user, &project.ProjectID, c.TaskID, a.TaskID, b.TaskID)
+
+	r, err := s.Tasks.BulkUpdateTaskOrder(user, taskOrders(a.TaskID, 5, b.TaskID, 6))
+	wantErr(t, err, models.ErrInvalidReorder)
+	wantIDs(t, "missing", r.Missing, c.TaskID)
+
+	r, err = s.Tasks.BulkUpdateTaskOrder(user, taskOrders(a.TaskID, 1, b.TaskID, 1, c.TaskID, 2))
+	wantErr(t, err, models.ErrInvalidReorder)
+	if !slices.Equal(r.DuplicateOrders, []int{1}) {
+		t.Fatalf("duplicate orders %v, want [1]", r.DuplicateOrders)
+	}
+
+	stranger := addTask(t, s, s.NewUser(t), models.NewTask{})
+	r, err = s.Tasks.BulkUpdateTaskOrder(user, taskOrders(a.TaskID, 0, b.TaskID, 1, c.TaskID, 2, stranger.TaskID, 3))
+	wantErr(t, err, models.ErrInvalidReorder)
+	wantIDs(t, "rejected", r.Rejected, stranger.TaskID)
+
+	// Rejected reorders change nothing.
+	wantListOrder(t, s, user, &project.ProjectID, c.TaskID, a.TaskID, b.TaskID)
+}
+
+func testMoveTask(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	project := addProject(t, s, user, "destination", nil)
+	x := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID})
+	y := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID})
+	a := addTask(t, s, user, models.NewTask{})
+	sub := addTask(t, s, user, models.NewTask{ParentTaskID: &a.TaskID})
+
+	_, err := s.Tasks.MoveTask(a.TaskID, user, models.TaskMove{ParentTaskID: &a.TaskID})
+	wantErr(t, err, models.ErrMoveIntoSubtree)
+	_, err = s.Tasks.MoveTask(a.TaskID, user, models.TaskMove{ParentTaskID: &sub.TaskID})
+	wantErr(t, err, models.ErrMoveIntoSubtree)
+	foreign := addProject(t, s, s.NewUser(t), "foreign", nil)
+	_, err = s.Tasks.MoveTask(a.TaskID, user, models.TaskMove{ProjectID: &foreign.ProjectID})
+	wantErr(t, err, models.ErrNoRecord)
+
+	moved, err := s.Tasks.MoveTask(a.TaskID, user, models.TaskMove{ProjectID: &project.ProjectID, Position: ptr(1)})
+	check(t, err)
+	if moved.ProjectID == nil || *moved.ProjectID != project.ProjectID {
+		t.Fatalf("task not moved into the project: %+v", moved)
+	}
+	wantListOrder(t, s, user, &project.ProjectID, x.TaskID, a.TaskID, y.TaskID)
+
+	// The subtree follows the task into its new project.
+	subtask := findTask(t, s, user, sub.TaskID)
+	if subtask.ProjectID == nil || *subtask.ProjectID != project.ProjectID {
+		t.Fatalf("subtask stayed in project %v", subtask.ProjectID)
+	}
+
+	// Under a parent the task joins the parent's project, whatever ProjectID says.
+	moved, err = s.Tasks.MoveTask(y.TaskID, user, models.TaskMove{ParentTaskID: &x.TaskID, ProjectID: &foreign.ProjectID})
+	check(t, err)
+	if moved.ParentTaskID == nil || *moved.ParentTaskID != x.TaskID || *moved.ProjectID != project.ProjectID {
+		t.Fatalf("unexpected move result %+v", moved)
+	}
+}
+
+func testDropTask(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	source := addProject(t, s, user, "source", nil)
+	dest := addProject(t, s, user, "dest", nil)
+	a := addTask(t, s, user, models.NewTask{ProjectID: &source.ProjectID})
+	b := addTask(t, s, user, models.NewTask{ProjectID: &source.ProjectID})
+	c := addTask(t, s, user, models.NewTask{ProjectID: &source.ProjectID})
+	x := addTask(t, s, user, models.NewTask{ProjectID: &dest.ProjectID})
+	y := addTask(t, s, user, models.NewTask{ProjectID: &dest.ProjectID})
+
+	result, err := s.Tasks.DropTask(user, models.TaskDrop{TaskID: b.TaskID, NewProjectID: &dest.ProjectID, AfterID: &x.TaskID})
+	check(t, err)
+	wantIDs(t, "siblings", taskIDs(result.Siblings), x.TaskID, b.TaskID, y.TaskID)
+	wantOrders(t, result.Siblings, 0, models.OrderGap, 2*models.OrderGap)
+	wantIDs(t, "source siblings", taskIDs(result.SourceSiblings), a.TaskID, c.TaskID)
+	wantOrders(t, result.SourceSiblings, 0, models.OrderGap)
+	if result.Task.TaskID != b.TaskID || *result.Task.ProjectID != dest.ProjectID {
+		t.Fatalf("unexpected dropped task %+v", result.Task)
+	}
+
+	// A drop within the same list reports no source list.
+	result, err = s.Tasks.DropTask(user, models.TaskDrop{TaskID: y.TaskID, NewProjectID: &dest.ProjectID, BeforeID: &x.TaskID})
+	check(t, err)
+	wantIDs(t, "siblings", taskIDs(result.Siblings), y.TaskID, x.TaskID, b.TaskID)
+	if len(result.SourceSiblings) != 0 {
+		t.Fatalf("same-list drop returned source siblings")
+	}
+
+	_, err = s.Tasks.DropTask(user, models.TaskDrop{TaskID: a.TaskID, NewProjectID: &dest.ProjectID, BeforeID: &c.TaskID})
+	wantErr(t, err, models.ErrNotSiblings)
+}
+
+func testRebalanceTaskOrders(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	project := addProject(t, s, user, "crowded", nil)
+	a := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID, Order: ptr(0)})
+	b := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID, Order: ptr(1)})
+	c := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID, Order: ptr(2)})
+
+	n, err := s.Tasks.RebalanceTaskOrders(context.Background())
+	check(t, err)
+	if n < 2 {
+		t.Fatalf("renumbered %d tasks, want at least 2", n)
+	}
+	list := wantListOrder(t, s, user, &project.ProjectID, a.TaskID, b.TaskID, c.TaskID)
+	wantOrders(t, list, 0, models.OrderGap, 2*models.OrderGap)
+}
+
+func testDuplicateTask(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	label := addLabel(t, s, user, "errand")
+	due := time.Date(2030, 3, 14, 0, 0, 0, 0, time.UTC)
+	root := addTask(t, s, user, models.NewTask{Content: "root", LabelIDs: []uuid.UUID{label.LabelID}, DueDate: &due})
+	addTask(t, s, user, models.NewTask{})
+	sub := addTask(t, s, user, models.NewTask{Content: "sub", ParentTaskID: &root.TaskID})
+	_, _, err := s.Tasks.SetTaskCompleted(sub.TaskID, user, true, nil, models.CompletionRules{})
+	check(t, err)
+
+	copied, err := s.Tasks.DuplicateTask(root.TaskID, user, models.DuplicateOptions{DueDateOffsetDays: 7})
+	check(t, err)
+	if copied.TaskID == root.TaskID || copied.Content != "root" || copied.IsCompleted {
+		t.Fatalf("unexpected copy %+v", copied)
+	}
+	if copied.Order != 2*models.OrderGap {
+		t.Fatalf("copy has order %d, want it appended at %d", copied.Order, 2*models.OrderGap)
+	}
+	if !slices.Equal(copied.LabelIDs, []uuid.UUID{label.LabelID}) {
+		t.Fatalf("copy has labels %v", copied.LabelIDs)
+	}
+	if copied.DueDate == nil || !sameDate(*copied.DueDate, due.AddDate(0, 0, 7)) {
+		t.Fatalf("copy due %v, want %v", copied.DueDate, due.AddDate(0, 0, 7))
+	}
+	// Copied subtasks start out open.
+	if copied.SubtaskCount != 1 || copied.CompletedSubtaskCount != 0 {
+		t.Fatalf("copy has %d/%d subtasks completed", copied.CompletedSubtaskCount, copied.SubtaskCount)
+	}
+
+	_, err = s.Tasks.DuplicateTask(root.TaskID, s.NewUser(t), models.DuplicateOptions{})
+	wantErr(t, err, models.ErrNoRecord)
+}
+
+func testCompletionRules(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	parentRule := models.CompletionRules{CompleteParent: true}
+
+	parent := addTask(t, s, user, models.NewTask{})
+	c1 := addTask(t, s, user, models.NewTask{ParentTaskID: &parent.TaskID})
+	c2 := addTask(t, s, user, models.NewTask{ParentTaskID: &parent.TaskID})
+
+	_, _, err := s.Tasks.SetTaskCompleted(c1.TaskID, user, true, nil, parentRule)
+	check(t, err)
+	if findTask(t, s, user, parent.TaskID).IsCompleted {
+		t.Fatalf("parent completed while a subtask is open")
+	}
+	_, _, err = s.Tasks.SetTaskCompleted(c2.TaskID, user, true, nil, parentRule)
+	check(t, err)
+	if p := findTask(t, s, user, parent.TaskID); !p.IsCompleted || p.CompletedSubtaskCount != 2 {
+		t.Fatalf("parent not completed with its last subtask: %+v", p)
+	}
+	_, err = s.Tasks.ToggleTaskCompleted(c1.TaskID, user, parentRule)
+	check(t, err)
+	if findTask(t, s, user, parent.TaskID).IsCompleted {
+		t.Fatalf("parent still completed after a subtask was reopened")
+	}
+
+	top := addTask(t, s, user, models.NewTask{})
+	mid := addTask(t, s, user, models.NewTask{ParentTaskID: &top.TaskID})
+	leaf := addTask(t, s, user, models.NewTask{ParentTaskID: &mid.TaskID})
+	_, _, err = s.Tasks.SetTaskCompleted(top.TaskID, user, true, nil, models.CompletionRules{CompleteSubtasks: true})
+	check(t, err)
+	for _, id := range []uuid.UUID{mid.TaskID, leaf.TaskID} {
+		if !findTask(t, s, user, id).IsCompleted {
+			t.Fatalf("descendant %s not completed with its ancestor", id)
+		}
+	}
+
+	// Setting the state a task already has changes nothing.
+	at := time.Now().Add(-time.Hour)
+	same, changed, err := s.Tasks.SetTaskCompleted(top.TaskID, user, true, &at, models.CompletionRules{})
+	check(t, err)
+	if changed {
+		t.Fatalf("completing an already completed task reported a change")
+	}
+	if same.CompletedAt == nil || same.CompletedAt.Before(at.Add(time.Minute)) {
+		t.Fatalf("completion time of an already completed task changed to %v", same.CompletedAt)
+	}
+}
+
+func testDependencies(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	project := addProject(t, s, user, "deps", nil)
+	a := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID})
+	b := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID})
+	c := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID})
+
+	_, err := s.Tasks.AddDependency(a.TaskID, b.TaskID, user)
+	check(t, err)
+	_, err = s.Tasks.AddDependency(b.TaskID, c.TaskID, user)
+	check(t, err)
+	_, err = s.Tasks.AddDependency(c.TaskID, a.TaskID, user)
+	wantErr(t, err, models.ErrDependencyCycle)
+	_, err = s.Tasks.AddDependency(a.TaskID, a.TaskID, user)
+	wantErr(t, err, models.ErrDependencyCycle)
+	stranger := addTask(t, s, s.NewUser(t), models.NewTask{})
+	_, err = s.Tasks.AddDependency(a.TaskID, stranger.TaskID, user)
+	wantErr(t, err, models.ErrNoRecord)
+
+	deps, err := s.Tasks.GetTaskDependencies(b.TaskID, user)
+	check(t, err)
+	wantIDs(t, "blocked by", deps.BlockedBy, c.TaskID)
+	wantIDs(t, "blocks", deps.Blocks, a.TaskID)
+
+	ordered, err := s.Tasks.GetProjectTasksInTopologicalOrder(project.ProjectID, user)
+	check(t, err)
+	wantIDs(t, "topological order", taskIDs(ordered), c.TaskID, b.TaskID, a.TaskID)
+
+	rules := models.CompletionRules{RefuseIfBlocked: true}
+	if !findTask(t, s, user, a.TaskID).Blocked {
+		t.Fatalf("task with an open blocker is not blocked")
+	}
+	_, _, err = s.Tasks.SetTaskCompleted(a.TaskID, user, true, nil, rules)
+	wantErr(t, err, models.ErrTaskBlocked)
+	_, _, err = s.Tasks.SetTaskCompleted(b.TaskID, user, true, nil, models.CompletionRules{})
+	check(t, err)
+	_, _, err = s.Tasks.SetTaskCompleted(a.TaskID, user, true, nil, rules)
+	check(t, err)
+
+	n, err := s.Tasks.RemoveDependency(a.TaskID, b.TaskID, s.NewUser(t))
+	check(t, err)
+	if n != 0 {
+		t.Fatalf("stranger removed a dependency")
+	}
+	n, err = s.Tasks.RemoveDependency(a.TaskID, b.TaskID, user)
+	check(t, err)
+	if n != 1 {
+		t.Fatalf("removed %d dependencies, want 1", n)
+	}
+}
+
+func testTaskCascades(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	label := addLabel(t, s, user, "cascade")
+	parent := addTask(t, s, user, models.NewTask{})
+	child := addTask(t, s, user, models.NewTask{ParentTaskID: &parent.TaskID})
+	grandchild := addTask(t, s, user, models.NewTask{ParentTaskID: &child.TaskID, LabelIDs: []uuid.UUID{label.LabelID}})
+	other := addTask(t, s, user, models.NewTask{})
+	_, err := s.Tasks.AddDependency(other.TaskID, grandchild.TaskID, user)
+	check(t, err)
+
+	n, err := s.Tasks.DeleteTaskByID(parent.TaskID, user)
+	check(t, err)
+	if n != 1 {
+		t.Fatalf("deleted %d tasks, want 1", n)
+	}
+	wantIDs(t, "remaining tasks", taskIDs(listTasks(t, s, user)), other.TaskID)
+
+	deps, err := s.Tasks.GetTaskDependencies(other.TaskID, user)
+	check(t, err)
+	if len(deps.BlockedBy) != 0 {
+		t.Fatalf("dependency on a deleted task survived")
+	}
+	if findTask(t, s, user, other.TaskID).Blocked {
+		t.Fatalf("task still blocked by a deleted task")
+	}
+	labels, err := s.Labels.GetLabelsByUserID(user)
+	check(t, err)
+	if len(labels) != 1 || labels[0].TaskCount != 0 {
+		t.Fatalf("label still counts deleted tasks: %+v", labels)
+	}
+
+	// Deleting a label detaches it from its tasks.
+	labelled := addTask(t, s, user, models.NewTask{LabelIDs: []uuid.UUID{label.LabelID}})
+	n, err = s.Labels.DeleteLabelByID(label.LabelID, user)
+	check(t, err)
+	if n != 1 {
+		t.Fatalf("deleted %d labels, want 1", n)
+	}
+	if got := findTask(t, s, user, labelled.TaskID); len(got.LabelIDs) != 0 {
+		t.Fatalf("task keeps deleted label %v", got.LabelIDs)
+	}
+}
+
+func testProjectCascades(t *testing.T, s Stores) {
+	user, member := s.NewUser(t), s.NewUser(t)
+	project := addProject(t, s, user, "doomed", nil)
+	task := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID})
+	addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID, ParentTaskID: &task.TaskID})
+	kept := addTask(t, s, user, models.NewTask{})
+	_, err := s.Projects.AddProjectMember(project.ProjectID, user, member)
+	check(t, err)
+
+	n, err := s.Projects.DeleteProjectByID(project.ProjectID, member)
+	check(t, err)
+	if n != 0 {
+		t.Fatalf("member deleted the project")
+	}
+
+	n, err = s.Projects.DeleteProjectByID(project.ProjectID, user)
+	check(t, err)
+	if n != 1 {
+		t.Fatalf("deleted %d projects, want 1", n)
+	}
+	wantIDs(t, "remaining tasks", taskIDs(listTasks(t, s, user)), kept.TaskID)
+	ok, err := s.Projects.IsProjectMember(project.ProjectID, member)
+	check(t, err)
+	if ok {
+		t.Fatalf("membership survived the project")
+	}
+
+	// Sub-projects are not deleted with their parent.
+	parent := addProject(t, s, user, "parent", nil)
+	addProject(t, s, user, "child", &parent.ProjectID)
+	if _, err := s.Projects.DeleteProjectByID(parent.ProjectID, user); err == nil {
+		t.Fatalf("deleted a project that has sub-projects")
+	}
+}
+
+func testLabels(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	a := addLabel(t, s, user, "Work")
+	b := addLabel(t, s, user, "home")
+	c := addLabel(t, s, user, "errands")
+	if a.Order != 0 || b.Order != 1 || c.Order != 2 {
+		t.Fatalf("labels ordered %d, %d, %d; want 0, 1, 2", a.Order, b.Order, c.Order)
+	}
+
+	_, err := s.Labels.AddLabel(models.Label{UserID: user, Name: "work"})
+	wantErr(t, err, models.ErrDuplicateLabel)
+	if _, err := s.Labels.AddLabel(models.Label{UserID: s.NewUser(t), Name: "work"}); err != nil {
+		t.Fatalf("label names should only be unique per user: %v", err)
+	}
+	b.Name = "WORK"
+	_, err = s.Labels.EditLabelByID(b)
+	wantErr(t, err, models.ErrDuplicateLabel)
+	foreign := a
+	foreign.UserID = s.NewUser(t)
+	_, err = s.Labels.EditLabelByID(foreign)
+	wantErr(t, err, models.ErrNoRecord)
+
+	labels, err := s.Labels.ReorderLabels(user, []uuid.UUID{c.LabelID})
+	check(t, err)
+	wantIDs(t, "labels", labelIDs(labels), c.LabelID, a.LabelID, b.LabelID)
+	_, err = s.Labels.ReorderLabels(user, []uuid.UUID{uuid.New()})
+	wantErr(t, err, models.ErrUnknownLabel)
+
+	open := addTask(t, s, user, models.NewTask{LabelIDs: []uuid.UUID{a.LabelID, b.LabelID}})
+	done := addTask(t, s, user, models.NewTask{LabelIDs: []uuid.UUID{a.LabelID}})
+	_, _, err = s.Tasks.SetTaskCompleted(done.TaskID, user, true, nil, models.CompletionRules{})
+	check(t, err)
+	if !slices.Equal(open.Labels, []string{"Work", "home"}) && !slices.Equal(open.Labels, []string{"home", "Work"}) {
+		t.Fatalf("task labels %v", open.Labels)
+	}
+
+	labels, err = s.Labels.GetLabelsByUserID(user)
+	check(t, err)
+	for _, l := range labels {
+		if l.LabelID == a.LabelID && (l.TaskCount != 2 || l.OpenTaskCount != 1) {
+			t.Fatalf("label counts %d/%d, want 2 tasks with 1 open", l.TaskCount, l.OpenTaskCount)
+		}
+	}
+
+	// A task referencing another user's label is refused as a whole.
+	strangerLabel := addLabel(t, s, s.NewUser(t), "theirs")
+	_, err = s.Tasks.AddTask(models.NewTask{TaskID: uuid.New(), Content: "x", Description: ptr(""), Priority: ptr(int16(1)), LabelIDs: []uuid.UUID{strangerLabel.LabelID}}, user)
+	wantErr(t, err, models.ErrUnknownLabel)
+	all, err := s.Tasks.GetTasksByUserID(user, models.TaskFilter{IncludeCompleted: true})
+	check(t, err)
+	if len(all) != 2 {
+		t.Fatalf("refused task was stored: %d tasks", len(all))
+	}
+}
+
+func testMergeLabels(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	target := addLabel(t, s, user, "target")
+	source := addLabel(t, s, user, "source")
+	addTask(t, s, user, models.NewTask{LabelIDs: []uuid.UUID{target.LabelID}})
+	moved := addTask(t, s, user, models.NewTask{LabelIDs: []uuid.UUID{source.LabelID}})
+	addTask(t, s, user, models.NewTask{LabelIDs: []uuid.UUID{target.LabelID, source.LabelID}})
+
+	_, err := s.Labels.MergeLabels(user, target.LabelID, []uuid.UUID{addLabel(t, s, s.NewUser(t), "theirs").LabelID})
+	wantErr(t, err, models.ErrUnknownLabel)
+
+	result, err := s.Labels.MergeLabels(user, target.LabelID, []uuid.UUID{source.LabelID})
+	check(t, err)
+	// The task that already had the target still counts, since it loses the source label.
+	if result.TasksUpdated != 2 || result.LabelsMerged != 1 || result.Label.TaskCount != 3 {
+		t.Fatalf("unexpected merge result %+v", result)
+	}
+	if got := findTask(t, s, user, moved.TaskID); !slices.Equal(got.LabelIDs, []uuid.UUID{target.LabelID}) {
+		t.Fatalf("merged task has labels %v", got.LabelIDs)
+	}
+	labels, err := s.Labels.GetLabelsByUserID(user)
+	check(t, err)
+	wantIDs(t, "labels", labelIDs(labels), target.LabelID)
+}
+
+func testBulkLabelTasks(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	label := addLabel(t, s, user, "bulk")
+	project := addProject(t, s, user, "bulk", nil)
+	t1 := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID})
+	t2 := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID})
+	outside := addTask(t, s, user, models.NewTask{})
+
+	result, err := s.Tasks.BulkLabelTasks(user, models.BulkLabel{ProjectID: &project.ProjectID, AddLabelIDs: []uuid.UUID{label.LabelID}})
+	check(t, err)
+	if result.Matched != 2 || result.Added[label.LabelID] != 2 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	result, err = s.Tasks.BulkLabelTasks(user, models.BulkLabel{ProjectID: &project.ProjectID, AddLabelIDs: []uuid.UUID{label.LabelID}})
+	check(t, err)
+	if result.Added[label.LabelID] != 0 {
+		t.Fatalf("relabelling counted %d additions", result.Added[label.LabelID])
+	}
+	result, err = s.Tasks.BulkLabelTasks(user, models.BulkLabel{TaskIDs: []uuid.UUID{t1.TaskID}, RemoveLabelIDs: []uuid.UUID{label.LabelID}})
+	check(t, err)
+	if result.Matched != 1 || result.Removed[label.LabelID] != 1 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if len(findTask(t, s, user, t2.TaskID).LabelIDs) != 1 || len(findTask(t, s, user, outside.TaskID).LabelIDs) != 0 {
+		t.Fatalf("labels changed outside the selection")
+	}
+
+	// Other users' tasks are never matched.
+	result, err = s.Tasks.BulkLabelTasks(s.NewUser(t), models.BulkLabel{TaskIDs: []uuid.UUID{t2.TaskID}})
+	check(t, err)
+	if result.Matched != 0 {
+		t.Fatalf("matched another user's task")
+	}
+	_, err = s.Tasks.BulkLabelTasks(user, models.BulkLabel{TaskIDs: []uuid.UUID{t2.TaskID}, AddLabelIDs: []uuid.UUID{uuid.New()}})
+	wantErr(t, err, models.ErrUnknownLabel)
+}
+
+func testProjects(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	a := addProject(t, s, user, "a", nil)
+	b := addProject(t, s, user, "b", nil)
+	child := addProject(t, s, user, "child", &a.ProjectID)
+	if a.Order != 0 || b.Order != 1 || child.Order != 0 {
+		t.Fatalf("orders %d, %d, %d; want 0, 1, 0", a.Order, b.Order, child.Order)
+	}
+
+	err := s.Projects.BulkUpdateProjectOrder(user, projectOrders(a.ProjectID, 0, child.ProjectID, 1))
+	wantErr(t, err, models.ErrNotSiblings)
+	foreign := addProject(t, s, s.NewUser(t), "foreign", nil)
+	err = s.Projects.BulkUpdateProjectOrder(user, projectOrders(a.ProjectID, 0, foreign.ProjectID, 1))
+	wantErr(t, err, models.ErrNoRecord)
+	err = s.Projects.BulkUpdateProjectOrder(user, projectOrders(a.ProjectID, 0, a.ProjectID, 1))
+	wantErr(t, err, models.ErrInvalidReorder)
+	err = s.Projects.BulkUpdateProjectOrder(user, projectOrders(b.ProjectID, 0, a.ProjectID, 1))
+	check(t, err)
+	projects, err := s.Projects.GetProjectsByUserID(user, false)
+	check(t, err)
+	wantIDs(t, "projects", projectIDs(projects), b.ProjectID, child.ProjectID, a.ProjectID)
+
+	_, err = s.Projects.ArchiveProject(a.ProjectID, s.NewUser(t), true)
+	wantErr(t, err, models.ErrNoRecord)
+	archived, err := s.Projects.ArchiveProject(a.ProjectID, user, true)
+	check(t, err)
+	if len(archived) != 2 {
+		t.Fatalf("archived %d projects, want the project and its child", len(archived))
+	}
+	projects, err = s.Projects.GetProjectsByUserID(user, false)
+	check(t, err)
+	wantIDs(t, "active projects", projectIDs(projects), b.ProjectID)
+	projects, err = s.Projects.GetProjectsByUserID(user, true)
+	check(t, err)
+	if len(projects) != 3 {
+		t.Fatalf("got %d projects including archived, want 3", len(projects))
+	}
+	_, err = s.Projects.ArchiveProject(a.ProjectID, user, false)
+	check(t, err)
+
+	a.ProjectName = "renamed"
+	a.IsFavorite = true
+	edited, err := s.Projects.EditProjectByID(a)
+	check(t, err)
+	if edited.ProjectName != "renamed" || !edited.IsFavorite || edited.IsArchived {
+		t.Fatalf("unexpected edit result %+v", edited)
+	}
+	a.UserID = s.NewUser(t)
+	if _, err := s.Projects.EditProjectByID(a); err == nil {
+		t.Fatalf("another user edited the project")
+	}
+}
+
+func testProjectMembers(t *testing.T, s Stores) {
+	owner, member, stranger := s.NewUser(t), s.NewUser(t), s.NewUser(t)
+	project := addProject(t, s, owner, "shared", nil)
+
+	_, err := s.Projects.AddProjectMember(project.ProjectID, member, stranger)
+	wantErr(t, err, models.ErrNoRecord)
+	added, err := s.Projects.AddProjectMember(project.ProjectID, owner, member)
+	check(t, err)
+	again, err := s.Projects.AddProjectMember(project.ProjectID, owner, member)
+	check(t, err)
+	if !again.CreatedAt.Equal(added.CreatedAt) {
+		t.Fatalf("re-adding a member changed when they joined")
+	}
+
+	members, err := s.Projects.GetProjectMembers(project.ProjectID, member)
+	check(t, err)
+	if len(members) != 1 || members[0].UserID != member {
+		t.Fatalf("unexpected members %+v", members)
+	}
+	members, err = s.Projects.GetProjectMembers(project.ProjectID, stranger)
+	check(t, err)
+	if len(members) != 0 {
+		t.Fatalf("stranger sees %d members", len(members))
+	}
+
+	for user, want := range map[uuid.UUID]bool{owner: true, member: true, stranger: false} {
+		ok, err := s.Projects.IsProjectMember(project.ProjectID, user)
+		check(t, err)
+		if ok != want {
+			t.Fatalf("IsProjectMember(%s) = %v, want %v", user, ok, want)
+		}
+	}
+
+	n, err := s.Projects.RemoveProjectMember(project.ProjectID, member, member)
+	check(t, err)
+	if n != 0 {
+		t.Fatalf("a member removed themselves without owning the project")
+	}
+	n, err = s.Projects.RemoveProjectMember(project.ProjectID, owner, member)
+	check(t, err)
+	if n != 1 {
+		t.Fatalf("removed %d members, want 1", n)
+	}
+}
+
+func testDuplicateProject(t *testing.T, s Stores) {
+	user, assignee := s.NewUser(t), s.NewUser(t)
+	project := addProject(t, s, user, "launch", nil)
+	child := addProject(t, s, user, "launch docs", &project.ProjectID)
+	task := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID, AssigneeID: &assignee})
+	addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID, ParentTaskID: &task.TaskID})
+	addTask(t, s, user, models.NewTask{ProjectID: &child.ProjectID})
+
+	_, err := s.Projects.DuplicateProject(project.ProjectID, s.NewUser(t), models.DuplicateOptions{})
+	wantErr(t, err, models.ErrNoRecord)
+
+	copied, err := s.Projects.DuplicateProject(project.ProjectID, user, models.DuplicateOptions{})
+	check(t, err)
+	if copied.ProjectID == project.ProjectID || copied.ProjectName != "Copy of launch" || copied.Order != 1 {
+		t.Fatalf("unexpected copy %+v", copied)
+	}
+
+	projects, err := s.Projects.GetProjectsByUserID(user, false)
+	check(t, err)
+	if len(projects) != 4 {
+		t.Fatalf("got %d projects after duplicating, want 4", len(projects))
+	}
+	var copiedChild *models.Project
+	for i, p := range projects {
+		if p.ParentProjectID != nil && *p.ParentProjectID == copied.ProjectID {
+			copiedChild = &projects[i]
+		}
+	}
+	if copiedChild == nil || copiedChild.ProjectName != "launch docs" {
+		t.Fatalf("sub-project was not copied under the copy")
+	}
+
+	tasks, err := s.Tasks.GetProjectTasksInTopologicalOrder(copied.ProjectID, user)
+	check(t, err)
+	if len(tasks) != 2 {
+		t.Fatalf("copy has %d tasks, want 2", len(tasks))
+	}
+	for _, task := range tasks {
+		if task.AssigneeID != nil {
+			t.Fatalf("copied task kept its assignee")
+		}
+	}
+	childTasks, err := s.Tasks.GetProjectTasksInTopologicalOrder(copiedChild.ProjectID, user)
+	check(t, err)
+	if len(childTasks) != 1 {
+		t.Fatalf("copied sub-project has %d tasks, want 1", len(childTasks))
+	}
+}
+
+func testDuplicateInbox(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	inbox, err := s.Projects.AddProject(models.Project{UserID: user, ProjectName: "Inbox", IsInbox: ptr(true)})
+	check(t, err)
+	addProject(t, s, user, "someday", &inbox.ProjectID)
+
+	copied, err := s.Projects.DuplicateProject(inbox.ProjectID, user, models.DuplicateOptions{})
+	check(t, err)
+	if copied.IsInbox != nil && *copied.IsInbox {
+		t.Fatalf("the copy of the inbox is an inbox too")
+	}
+
+	projects, err := s.Projects.GetProjectsByUserID(user, false)
+	check(t, err)
+	inboxes := 0
+	for _, p := range projects {
+		if p.IsInbox != nil && *p.IsInbox {
+			inboxes++
+		}
+	}
+	if len(projects) != 4 || inboxes != 1 {
+		t.Fatalf("got %d projects with %d inboxes after duplicating, want 4 with 1", len(projects), inboxes)
+	}
+}
+
+func testArchiveCompletedTasks(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	label := addLabel(t, s, user, "old")
+	longAgo := time.Now().Add(-48 * time.Hour)
+	all := models.CompletionRules{CompleteSubtasks: true}
+
+	root := addTask(t, s, user, models.NewTask{Content: "old tree", LabelIDs: []uuid.UUID{label.LabelID}})
+	sub := addTask(t, s, user, models.NewTask{ParentTaskID: &root.TaskID})
+	_, _, err := s.Tasks.SetTaskCompleted(root.TaskID, user, true, &longAgo, all)
+	check(t, err)
+
+	// A tree with an open subtask stays live.
+	partial := addTask(t, s, user, models.NewTask{})
+	addTask(t, s, user, models.NewTask{ParentTaskID: &partial.TaskID})
+	_, _, err = s.Tasks.SetTaskCompleted(partial.TaskID, user, true, &longAgo, models.CompletionRules{})
+	check(t, err)
+	recent := addTask(t, s, user, models.NewTask{})
+	_, _, err = s.Tasks.SetTaskCompleted(recent.TaskID, user, true, nil, models.CompletionRules{})
+	check(t, err)
+
+	n, err := s.Tasks.ArchiveCompletedTasks(context.Background(), time.Now().Add(-24*time.Hour))
+	check(t, err)
+	if n < 2 {
+		t.Fatalf("archived %d tasks, want at least 2", n)
+	}
+
+	live, err := s.Tasks.GetTasksByUserID(user, models.TaskFilter{IncludeCompleted: true})
+	check(t, err)
+	for _, task := range live {
+		if task.TaskID == root.TaskID || task.TaskID == sub.TaskID {
+			t.Fatalf("archived task %s is still live", task.TaskID)
+		}
+	}
+
+	completed, err := s.Tasks.GetCompletedTasks(user, models.CompletedTaskFilter{Limit: 100})
+	check(t, err)
+	byID := map[uuid.UUID]models.CompletedTask{}
+	for _, task := range completed {
+		byID[task.TaskID] = task
+	}
+	if len(completed) != 4 {
+		t.Fatalf("got %d completed tasks, want 4", len(completed))
+	}
+	if r := byID[root.TaskID]; !r.Archived || r.ArchivedAt == nil || !slices.Equal(r.Labels, []string{"old"}) {
+		t.Fatalf("unexpected archived root %+v", r)
+	}
+	if !byID[sub.TaskID].Archived || byID[partial.TaskID].Archived || byID[recent.TaskID].Archived {
+		t.Fatalf("wrong tasks archived")
+	}
+	if completed[0].TaskID != recent.TaskID {
+		t.Fatalf("completed tasks are not most recent first")
+	}
+
+	from := time.Now().Add(-time.Hour)
+	filtered, err := s.Tasks.GetCompletedTasks(user, models.CompletedTaskFilter{From: &from, Limit: 100})
+	check(t, err)
+	wantIDs(t, "recently completed", completedIDs(filtered), recent.TaskID)
+	limited, err := s.Tasks.GetCompletedTasks(user, models.CompletedTaskFilter{Limit: 1})
+	check(t, err)
+	if len(limited) != 1 {
+		t.Fatalf("limit ignored: %d tasks", len(limited))
+	}
+}
+
+func testAtomic(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	ctx := context.Background()
+	errRollback := errors.New("roll back")
+
+	var kept, dropped models.Task
+	err := s.Tasks.Atomic(ctx, func(tx models.TaskStore) error {
+		kept = addTask(t, Stores{Tasks: tx}, user, models.NewTask{Content: "kept"})
+		err := tx.Atomic(ctx, func(inner models.TaskStore) error {
+			dropped = addTask(t, Stores{Tasks: inner}, user, models.NewTask{Content: "dropped"})
+			return errRollback
+		})
+		wantErr(t, err, errRollback)
+
+		// Changes made so far are visible inside the transaction.
+		if got := listTasks(t, Stores{Tasks: tx}, user); len(got) != 1 {
+			t.Errorf("transaction sees %d tasks, want 1", len(got))
+		}
+		return nil
+	})
+	check(t, err)
+	wantIDs(t, "tasks", taskIDs(listTasks(t, s, user)), kept.TaskID)
+
+	err = s.Tasks.Atomic(ctx, func(tx models.TaskStore) error {
+		addTask(t, Stores{Tasks: tx}, user, models.NewTask{})
+		_, err := tx.DeleteTaskByID(kept.TaskID, user)
+		check(t, err)
+		return errRollback
+	})
+	wantErr(t, err, errRollback)
+	wantIDs(t, "tasks", taskIDs(listTasks(t, s, user)), kept.TaskID)
+	if dropped.TaskID == kept.TaskID {
+		t.Fatalf("inner task was never created")
+	}
+}
+
+func testStats(t *testing.T, s Stores) {
+	user := s.NewUser(t)
+	settings := models.DefaultSettings(user)
+	settings.DailyGoal = 2
+	project := addProject(t, s, user, "stats", nil)
+	label := addLabel(t, s, user, "focus")
+
+	for i := 0; i < 2; i++ {
+		task := addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID, LabelIDs: []uuid.UUID{label.LabelID}})
+		_, _, err := s.Tasks.SetTaskCompleted(task.TaskID, user, true, nil, models.CompletionRules{})
+		check(t, err)
+	}
+	addTask(t, s, user, models.NewTask{ProjectID: &project.ProjectID})
+	due := time.Now().UTC().AddDate(0, 0, -3)
+	due = time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+	addTask(t, s, user, models.NewTask{DueDate: &due})
+
+	stats, err := s.Tasks.GetStats(user, settings, models.StatsRange{Days: 7, Weeks: 4})
+	check(t, err)
+	if len(stats.Days) != 7 || len(stats.Weeks) != 4 {
+		t.Fatalf("got %d days and %d weeks, want 7 and 4", len(stats.Days), len(stats.Weeks))
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	if last := stats.Days[6]; last.Date != today || last.Completed != 2 {
+		t.Fatalf("last day %+v, want 2 completions on %s", last, today)
+	}
+	if stats.Weeks[3].Completed != 2 {
+		t.Fatalf("current week has %d completions, want 2", stats.Weeks[3].Completed)
+	}
+	if stats.CurrentStreak != 1 || stats.LongestStreak != 1 {
+		t.Fatalf("streaks %d/%d, want 1/1", stats.CurrentStreak, stats.LongestStreak)
+	}
+	if len(stats.ByProject) != 2 || stats.ByProject[0].ProjectID == nil || *stats.ByProject[0].ProjectID != project.ProjectID ||
+		stats.ByProject[0].Completed != 2 || stats.ByProject[0].Open != 1 {
+		t.Fatalf("unexpected project stats %+v", stats.ByProject)
+	}
+	if len(stats.ByLabel) != 1 || stats.ByLabel[0].LabelID != label.LabelID || stats.ByLabel[0].Completed != 2 {
+		t.Fatalf("unexpected label stats %+v", stats.ByLabel)
+	}
+	if stats.AverageCompletionHours == nil || stats.Overdue != 1 {
+		t.Fatalf("average %v and overdue %d, want an average and 1 overdue", stats.AverageCompletionHours, stats.Overdue)
+	}
+}
+
+// addTask creates a task for userID, filling in the fields every backend needs.
+func addTask(t *testing.T, s Stores, userID uuid.UUID, input models.NewTask) models.Task {
+	t.Helper()
+	input.TaskID = uuid.New()
+	if input.Content == "" {
+		input.Content = "task"
+	}
+	if input.Description == nil {
+		input.Description = ptr("")
+	}
+	if input.Priority == nil {
+		input.Priority = ptr(int16(1))
+	}
+	task, err := s.Tasks.AddTask(input, userID)
+	if err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+	return task
+}
+
+func addProject(t *testing.T, s Stores, userID uuid.UUID, name string, parentID *uuid.UUID) models.Project {
+	t.Helper()
+	project, err := s.Projects.AddProject(models.Project{UserID: userID, ProjectName: name, ParentProjectID: parentID})
+	if err != nil {
+		t.Fatalf("AddProject: %v", err)
+	}
+	return project
+}
+
+func addLabel(t *testing.T, s Stores, userID uuid.UUID, name string) models.Label {
+	t.Helper()
+	label, err := s.Labels.AddLabel(models.Label{UserID: userID, Name: name})
+	if err != nil {
+		t.Fatalf("AddLabel: %v", err)
+	}
+	return label
+}
+
+// listTasks returns the user's open tasks.
+func listTasks(t *testing.T, s Stores, userID uuid.UUID) []models.Task {
+	t.Helper()
+	tasks, err := s.Tasks.GetTasksByUserID(userID, models.TaskFilter{})
+	check(t, err)
+	return tasks
+}
+
+// findTask returns one of the user's tasks, completed or not.
+func findTask(t *testing.T, s Stores, userID, taskID uuid.UUID) models.Task {
+	t.Helper()
+	tasks, err := s.Tasks.GetTasksByUserID(userID, models.TaskFilter{IncludeCompleted: true})
+	check(t, err)
+	for _, task := range tasks {
+		if task.TaskID == taskID {
+			return task
+		}
+	}
+	t.Fatalf("task %s not found", taskID)
+	return models.Task{}
+}
+
+// wantListOrder checks the display order of a sibling list and returns its tasks in that order.
+// A nil projectID means the user's top-level tasks outside any project.
+func wantListOrder(t *testing.T, s Stores, userID uuid.UUID, projectID *uuid.UUID, want ...uuid.UUID) []models.Task {
+	t.Helper()
+	tasks, err := s.Tasks.GetTasksByUserID(userID, models.TaskFilter{IncludeCompleted: true})
+	check(t, err)
+	var list []models.Task
+	for _, task := range tasks {
+		if task.ParentTaskID == nil && sameProject(task.ProjectID, projectID) {
+			list = append(list, task)
+		}
+	}
+	slices.SortStableFunc(list, func(a, b models.Task) int { return a.Order - b.Order })
+	wantIDs(t, "list order", taskIDs(list), want...)
+	return list
+}
+
+func wantOrders(t *testing.T, tasks []models.Task, want ...int) {
+	t.Helper()
+	got := make([]int, len(tasks))
+	for i, task := range tasks {
+		got[i] = task.Order
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("orders %v, want %v", got, want)
+	}
+}
+
+func wantIDs(t *testing.T, what string, got []uuid.UUID, want ...uuid.UUID) {
+	t.Helper()
+	if !slices.Equal(got, want) {
+		t.Fatalf("%s: got %v, want %v", what, got, want)
+	}
+}
+
+func wantErr(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("got error %v, want %v", err, target)
+	}
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// taskOrders builds a reorder request from alternating task IDs and orders.
+func taskOrders(pairs ...any) []models.TaskOrderUpdate {
+	var updates []models.TaskOrderUpdate
+	for i := 0; i < len(pairs); i += 2 {
+		updates = append(updates, models.TaskOrderUpdate{TaskID: pairs[i].(uuid.UUID), Order: pairs[i+1].(int)})
+	}
+	return updates
+}
+
+// projectOrders builds a reorder request from alternating project IDs and orders.
+func projectOrders(pairs ...any) []models.ProjectOrderUpdate {
+	var updates []models.ProjectOrderUpdate
+	for i := 0; i < len(pairs); i += 2 {
+		updates = append(updates, models.ProjectOrderUpdate{ProjectID: pairs[i].(uuid.UUID), Order: pairs[i+1].(int)})
+	}
+	return updates
+}
+
+func taskIDs(tasks []models.Task) []uuid.UUID {
+	ids := make([]uuid.UUID, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.TaskID
+	}
+	return ids
+}
+
+func completedIDs(tasks []models.CompletedTask) []uuid.UUID {
+	ids := make([]uuid.UUID, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.TaskID
+	}
+	return ids
+}
+
+func projectIDs(projects []models.Project) []uuid.UUID {
+	ids := make([]uuid.UUID, len(projects))
+	for i, p := range projects {
+		ids[i] = p.ProjectID
+	}
+	return ids
+}
+
+func labelIDs(labels []models.Label) []uuid.UUID {
+	ids := make([]uuid.UUID, len(labels))
+	for i, l := range labels {
+		ids[i] = l.LabelID
+	}
+	return ids
+}
+
+func sameProject(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// sameDate compares calendar dates; backends may return dates in different locations.
+func sameDate(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
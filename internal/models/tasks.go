@@ -2,32 +2,107 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 )
 
 type Task struct {
-	TaskID       uuid.UUID  `json:"task_id"`
-	ProjectID    *uuid.UUID `json:"project_id"`
-	UserID       uuid.UUID  `json:"user_id"`
-	Content      string     `json:"content"`
-	Description  string     `json:"description"`
-	DueDate      *time.Time `json:"due_date"`     // nullable time.Time: use nil for null
-	DueDatetime  *time.Time `json:"due_datetime"` // nullable time.Time: use nil for null
-	Priority     int16      `json:"priority"`
-	IsCompleted  bool       `json:"is_completed"`
-	CompletedAt  *time.Time `json:"completed_at"` // nullable time.Time: use nil for null
-	ParentTaskID *uuid.UUID `json:"parent_task_id"`
-	Order        int        `json:"order"`
-	Labels       []string   `json:"labels"`
-	CreatedAt    time.Time  `json:"created_at"`
+	TaskID       uuid.UUID   `json:"task_id"`
+	ProjectID    *uuid.UUID  `json:"project_id"`
+	UserID       uuid.UUID   `json:"user_id"`
+	Content      string      `json:"content"`
+	Description  string      `json:"description"`
+	DueDate      *time.Time  `json:"due_date"`     // nullable time.Time: use nil for null
+	DueDatetime  *time.Time  `json:"due_datetime"` // nullable time.Time: use nil for null
+	Priority     int16       `json:"priority"`
+	IsCompleted  bool        `json:"is_completed"`
+	CompletedAt  *time.Time  `json:"completed_at"` // nullable time.Time: use nil for null
+	ParentTaskID *uuid.UUID  `json:"parent_task_id"`
+	Order        int         `json:"order"`
+	Labels       []string    `json:"labels"`      // label names, read-only; derived from LabelIDs
+	LabelIDs     []uuid.UUID `json:"label_ids"`   // labels attached to the task; replaced on edit
+	AssigneeID   *uuid.UUID  `json:"assignee_id"` // nullable: unassigned tasks have no owner
+	CreatedBy    uuid.UUID   `json:"created_by"`
+	CreatedAt    time.Time   `json:"created_at"`
+	Blocked      bool        `json:"blocked"` // computed: true while any blocking task is still open
+
+	// Progress of the task's direct subtasks, computed on read.
+	SubtaskCount          int `json:"subtask_count"`
+	CompletedSubtaskCount int `json:"completed_subtask_count"`
 }
 
 type TaskModel struct {
-	DB *pgxpool.Pool
+	DB DBTX
+}
+
+// WithTx returns a TaskModel whose methods all run inside tx.
+func (m *TaskModel) WithTx(tx pgx.Tx) *TaskModel {
+	return &TaskModel{DB: tx}
+}
+
+// TaskFilter narrows the tasks returned by GetTasksByUserID.
+// A nil field means the filter is not applied.
+type TaskFilter struct {
+	AssigneeID *uuid.UUID
+	// IncludeCompleted also returns completed tasks, which are left out by default.
+	IncludeCompleted bool
+}
+
+// taskColumns is the column list shared by every query that returns a full Task.
+// It must stay in sync with scanTask, and the queries using it must not alias the tasks table.
+const taskColumns = `task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, "order", ` + taskLabelNamesColumn + `, ` + taskLabelIDsColumn + `, assignee_id, created_by, created_at, ` + taskBlockedColumn + `, ` + taskProgressColumns
+
+// taskLabelNamesColumn and taskLabelIDsColumn compute Task.Labels and Task.LabelIDs from task_labels,
+// both sorted by label name so the two slices line up.
+const taskLabelNamesColumn = `COALESCE((
+	SELECT array_agg(l.name ORDER BY l.name) FROM task_labels tl JOIN labels l ON l.label_id = tl.label_id
+	WHERE tl.task_id = tasks.task_id
+), '{}')`
+
+const taskLabelIDsColumn = `COALESCE((
+	SELECT array_agg(l.label_id ORDER BY l.name) FROM task_labels tl JOIN labels l ON l.label_id = tl.label_id
+	WHERE tl.task_id = tasks.task_id
+), '{}')`
+
+// taskBlockedColumn computes Task.Blocked from the task's open blockers.
+const taskBlockedColumn = `EXISTS (
+	SELECT 1 FROM task_dependencies d
+	JOIN tasks b ON b.task_id = d.blocked_by_task_id
+	WHERE d.task_id = tasks.task_id AND NOT b.is_completed
+)`
+
+// taskProgressColumns computes Task.SubtaskCount and Task.CompletedSubtaskCount.
+const taskProgressColumns = `(SELECT count(*) FROM tasks s WHERE s.parent_task_id = tasks.task_id),
+	(SELECT count(*) FROM tasks s WHERE s.parent_task_id = tasks.task_id AND s.is_completed)`
+
+// scanTask scans a row selected with taskColumns into task.
+func scanTask(row pgx.Row, task *Task) error {
+	return row.Scan(
+		&task.TaskID,
+		&task.ProjectID,
+		&task.UserID,
+		&task.Content,
+		&task.Description,
+		&task.DueDate,
+		&task.DueDatetime,
+		&task.Priority,
+		&task.IsCompleted,
+		&task.CompletedAt,
+		&task.ParentTaskID,
+		&task.Order,
+		&task.Labels,
+		&task.LabelIDs,
+		&task.AssigneeID,
+		&task.CreatedBy,
+		&task.CreatedAt,
+		&task.Blocked,
+		&task.SubtaskCount,
+		&task.CompletedSubtaskCount,
+	)
 }
 
 // NewTask is used for creating a new task from API input
@@ -36,37 +111,51 @@ type TaskModel struct {
 // user_id is not included; it comes from JWT
 // task_id is required; must be provided by frontend
 type NewTask struct {
-	TaskID       uuid.UUID  `json:"task_id"`              // REQUIRED: Frontend must provide task_id
-	ProjectID    *uuid.UUID `json:"project_id,omitempty"`
-	Content      string     `json:"content"`
-	Description  *string    `json:"description,omitempty"`
-	DueDate      *time.Time `json:"due_date,omitempty"`
-	DueDatetime  *time.Time `json:"due_datetime,omitempty"`
-	Priority     *int16     `json:"priority,omitempty"`
-	ParentTaskID *uuid.UUID `json:"parent_task_id,omitempty"`
-	Labels       []string   `json:"labels,omitempty"`
-	Order        *int       `json:"order,omitempty"`
+	TaskID       uuid.UUID   `json:"task_id"` // REQUIRED: Frontend must provide task_id
+	ProjectID    *uuid.UUID  `json:"project_id,omitempty"`
+	Content      string      `json:"content"`
+	Description  *string     `json:"description,omitempty"`
+	DueDate      *time.Time  `json:"due_date,omitempty"`
+	DueDatetime  *time.Time  `json:"due_datetime,omitempty"`
+	Priority     *int16      `json:"priority,omitempty"`
+	ParentTaskID *uuid.UUID  `json:"parent_task_id,omitempty"`
+	LabelIDs     []uuid.UUID `json:"label_ids,omitempty"`
+	Order        *int        `json:"order,omitempty"`
+	AssigneeID   *uuid.UUID  `json:"assignee_id,omitempty"`
 }
 
 // AddTask inserts a new task into the database using NewTask and userID
+// The authenticated user is recorded as both the task's user_id and created_by.
 func (m *TaskModel) AddTask(input NewTask, userID uuid.UUID) (Task, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return Task{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
 		INSERT INTO tasks (
-			task_id, project_id, user_id, content, description, due_date, due_datetime, priority, parent_task_id, "order", labels
+			task_id, project_id, user_id, content, description, due_date, due_datetime, priority, parent_task_id, "order", assignee_id, created_by
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
-		) RETURNING task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, "order", labels
-	`
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $3
+		)`
 
-	var createdTask Task
-	orderValue := 0
+	// Without an explicit order the task is appended after its siblings.
+	var orderValue int
 	if input.Order != nil {
 		orderValue = *input.Order
+	} else {
+		list := siblingList{userID: userID, projectID: input.ProjectID, parentTaskID: input.ParentTaskID}
+		if orderValue, err = list.nextOrder(ctx, tx); err != nil {
+			return Task{}, err
+		}
 	}
-	err := m.DB.QueryRow(
-		context.Background(),
+	_, err = tx.Exec(
+		ctx,
 		query,
-		input.TaskID,        // Use the provided task_id
+		input.TaskID, // Use the provided task_id
 		input.ProjectID,
 		userID,
 		input.Content,
@@ -76,31 +165,50 @@ func (m *TaskModel) AddTask(input NewTask, userID uuid.UUID) (Task, error) {
 		input.Priority,
 		input.ParentTaskID,
 		orderValue,
-		input.Labels,
-	).Scan(
-		&createdTask.TaskID,
-		&createdTask.ProjectID,
-		&createdTask.UserID,
-		&createdTask.Content,
-		&createdTask.Description,
-		&createdTask.DueDate,
-		&createdTask.DueDatetime,
-		&createdTask.Priority,
-		&createdTask.IsCompleted,
-		&createdTask.CompletedAt,
-		&createdTask.ParentTaskID,
-		&createdTask.Order,
-		&createdTask.Labels,
+		input.AssigneeID,
 	)
 
 	if err != nil {
 		return Task{}, fmt.Errorf("unable to execute query: %v", err)
 	}
 
+	if err := setTaskLabels(ctx, tx, input.TaskID, userID, input.LabelIDs); err != nil {
+		return Task{}, err
+	}
+
+	createdTask, err := getTask(ctx, tx, input.TaskID)
+	if err != nil {
+		return Task{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Task{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return createdTask, nil
 }
 
+// EditTaskByID updates a task owned by task.UserID, replacing its labels with task.LabelIDs.
+// IsCompleted and CompletedAt are ignored; completion goes through SetTaskCompleted and
+// ToggleTaskCompleted so that the completion rules apply. When the assignee changes, a reassignment event is written to the task history in the same transaction.
 func (m *TaskModel) EditTaskByID(task Task) (Task, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return Task{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var previousAssignee *uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT assignee_id FROM tasks WHERE task_id = $1 AND user_id = $2 FOR UPDATE`, task.TaskID, task.UserID).Scan(&previousAssignee)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Task{}, ErrNoRecord
+		}
+		return Task{}, fmt.Errorf("unable to fetch task: %w", err)
+	}
+
 	query := `
 		UPDATE tasks SET
 			project_id = $3,
@@ -109,18 +217,13 @@ func (m *TaskModel) EditTaskByID(task Task) (Task, error) {
 			due_date = $6,
 			due_datetime = $7,
 			priority = $8,
-			is_completed = $9,
-			completed_at = $10,
-			parent_task_id = $11,
-			"order" = $12,
-			labels = $13
-		WHERE task_id = $1 AND user_id = $2
-		RETURNING task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, "order", labels
-	`
-
-	var updatedTask Task
-	err := m.DB.QueryRow(
-		context.Background(),
+			parent_task_id = $9,
+			"order" = $10,
+			assignee_id = $11
+		WHERE task_id = $1 AND user_id = $2`
+
+	_, err = tx.Exec(
+		ctx,
 		query,
 		task.TaskID,
 		task.UserID,
@@ -130,42 +233,65 @@ func (m *TaskModel) EditTaskByID(task Task) (Task, error) {
 		task.DueDate,
 		task.DueDatetime,
 		task.Priority,
-		task.IsCompleted,
-		task.CompletedAt,
 		task.ParentTaskID,
 		task.Order,
-		task.Labels,
-	).Scan(
-		&updatedTask.TaskID,
-		&updatedTask.ProjectID,
-		&updatedTask.UserID,
-		&updatedTask.Content,
-		&updatedTask.Description,
-		&updatedTask.DueDate,
-		&updatedTask.DueDatetime,
-		&updatedTask.Priority,
-		&updatedTask.IsCompleted,
-		&updatedTask.CompletedAt,
-		&updatedTask.ParentTaskID,
-		&updatedTask.Order,
-		&updatedTask.Labels,
+		task.AssigneeID,
 	)
 
 	if err != nil {
 		return Task{}, fmt.Errorf("unable to execute query: %v", err)
 	}
 
+	if err := setTaskLabels(ctx, tx, task.TaskID, task.UserID, task.LabelIDs); err != nil {
+		return Task{}, err
+	}
+
+	if !sameUUID(previousAssignee, task.AssigneeID) {
+		event := TaskEvent{
+			TaskID:    task.TaskID,
+			UserID:    task.UserID,
+			EventType: TaskEventReassigned,
+			OldValue:  uuidString(previousAssignee),
+			NewValue:  uuidString(task.AssigneeID),
+		}
+		if err := insertTaskEvent(ctx, tx, event); err != nil {
+			return Task{}, err
+		}
+	}
+
+	updatedTask, err := getTask(ctx, tx, task.TaskID)
+	if err != nil {
+		return Task{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Task{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return updatedTask, nil
 }
 
-func (m *TaskModel) GetTasksByUserID(userID uuid.UUID) ([]Task, error) {
+// getTask re-reads a full task inside a transaction, after writes that RETURNING can't see
+// (such as label changes made by later statements).
+func getTask(ctx context.Context, tx pgx.Tx, taskID uuid.UUID) (Task, error) {
+	var task Task
+	if err := scanTask(tx.QueryRow(ctx, `SELECT `+taskColumns+` FROM tasks WHERE task_id = $1`, taskID), &task); err != nil {
+		return Task{}, fmt.Errorf("unable to fetch task: %w", err)
+	}
+	return task, nil
+}
+
+// GetTasksByUserID returns the tasks a user owns or is assigned to, narrowed by filter.
+func (m *TaskModel) GetTasksByUserID(userID uuid.UUID, filter TaskFilter) ([]Task, error) {
 	query := `
-		SELECT task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, "order", labels, created_at
+		SELECT ` + taskColumns + `
 		FROM tasks
-		WHERE user_id = $1
+		WHERE (user_id = $1 OR assignee_id = $1)
+			AND ($2::uuid IS NULL OR assignee_id = $2)
+			AND ($3 OR NOT is_completed)
 		ORDER BY created_at ASC`
 
-	rows, err := m.DB.Query(context.Background(), query, userID)
+	rows, err := m.DB.Query(context.Background(), query, userID, filter.AssigneeID, filter.IncludeCompleted)
 	if err != nil {
 		return nil, fmt.Errorf("unable to query tasks: %v", err)
 	}
@@ -175,23 +301,7 @@ func (m *TaskModel) GetTasksByUserID(userID uuid.UUID) ([]Task, error) {
 
 	for rows.Next() {
 		var task Task
-		err := rows.Scan(
-			&task.TaskID,
-			&task.ProjectID,
-			&task.UserID,
-			&task.Content,
-			&task.Description,
-			&task.DueDate,
-			&task.DueDatetime,
-			&task.Priority,
-			&task.IsCompleted,
-			&task.CompletedAt,
-			&task.ParentTaskID,
-			&task.Order,
-			&task.Labels,
-			&task.CreatedAt,
-		)
-		if err != nil {
+		if err := scanTask(rows, &task); err != nil {
 			return nil, fmt.Errorf("unable to scan row: %v", err)
 		}
 		tasks = append(tasks, task)
@@ -200,39 +310,158 @@ func (m *TaskModel) GetTasksByUserID(userID uuid.UUID) ([]Task, error) {
 	return tasks, nil
 }
 
-func (m *TaskModel) ToggleTaskCompleted(taskID uuid.UUID, userID uuid.UUID) (Task, error) {
-	query := `
-		UPDATE tasks
-		SET completed_at = CASE WHEN is_completed THEN NULL ELSE CURRENT_TIMESTAMP END, is_completed = NOT is_completed
-		WHERE task_id = $1 AND user_id = $2
-		RETURNING task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, labels`
-
-	var updatedTask Task
-	err := m.DB.QueryRow(
-		context.Background(),
-		query,
-		taskID,
-		userID,
-	).Scan(
-		&updatedTask.TaskID,
-		&updatedTask.ProjectID,
-		&updatedTask.UserID,
-		&updatedTask.Content,
-		&updatedTask.Description,
-		&updatedTask.DueDate,
-		&updatedTask.DueDatetime,
-		&updatedTask.Priority,
-		&updatedTask.IsCompleted,
-		&updatedTask.CompletedAt,
-		&updatedTask.ParentTaskID,
-		&updatedTask.Labels,
-	)
+// CompletionRules control how completing or reopening a task affects related tasks.
+type CompletionRules struct {
+	// RefuseIfBlocked makes completing a task with open blockers fail with ErrTaskBlocked.
+	RefuseIfBlocked bool
+	// CompleteSubtasks completes every open descendant when a task is completed.
+	CompleteSubtasks bool
+	// CompleteParent completes a parent once its last open subtask is completed (repeating up
+	// the tree), and reopens completed ancestors when a subtask is reopened.
+	CompleteParent bool
+}
 
+// ToggleTaskCompleted flips the completion state of a task owned by or assigned to userID,
+// applying rules to its subtasks and ancestors in the same transaction.
+// Reopening a task is never refused.
+func (m *TaskModel) ToggleTaskCompleted(taskID uuid.UUID, userID uuid.UUID, rules CompletionRules) (Task, error) {
+	task, _, err := m.updateCompletion(taskID, userID, nil, nil, rules)
+	return task, err
+}
+
+// SetTaskCompleted completes or reopens a task owned by or assigned to userID, applying rules
+// to its subtasks and ancestors in the same transaction. A task already in the requested state
+// is returned unchanged; the bool reports whether the state changed. completedAt overrides the
+// completion time (for example for completions made offline); nil means now. Reopening a task
+// is never refused.
+func (m *TaskModel) SetTaskCompleted(taskID, userID uuid.UUID, completed bool, completedAt *time.Time, rules CompletionRules) (Task, bool, error) {
+	return m.updateCompletion(taskID, userID, &completed, completedAt, rules)
+}
+
+// updateCompletion sets a task's completion state to target, or flips it when target is nil,
+// and returns the full task and whether its state changed.
+func (m *TaskModel) updateCompletion(taskID, userID uuid.UUID, target *bool, completedAt *time.Time, rules CompletionRules) (Task, bool, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
 	if err != nil {
-		return Task{}, fmt.Errorf("unable to execute query: %v", err)
+		return Task{}, false, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	return updatedTask, nil
+	var isCompleted, blocked bool
+	err = tx.QueryRow(ctx, `
+		SELECT is_completed, `+taskBlockedColumn+`
+		FROM tasks
+		WHERE task_id = $1 AND (user_id = $2 OR assignee_id = $2)
+		FOR UPDATE`, taskID, userID).Scan(&isCompleted, &blocked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Task{}, false, ErrNoRecord
+		}
+		return Task{}, false, fmt.Errorf("unable to fetch task: %w", err)
+	}
+
+	completed := !isCompleted
+	if target != nil {
+		completed = *target
+	}
+	changed := completed != isCompleted
+
+	if changed {
+		if rules.RefuseIfBlocked && completed && blocked {
+			return Task{}, false, ErrTaskBlocked
+		}
+
+		query := `
+			UPDATE tasks
+			SET is_completed = $2, completed_at = CASE WHEN $2 THEN COALESCE($3, CURRENT_TIMESTAMP) END
+			WHERE task_id = $1`
+
+		if _, err := tx.Exec(ctx, query, taskID, completed, completedAt); err != nil {
+			return Task{}, false, fmt.Errorf("unable to update task: %w", err)
+		}
+	}
+
+	task, err := getTask(ctx, tx, taskID)
+	if err != nil {
+		return Task{}, false, err
+	}
+	if changed {
+		if err := applyCompletionRules(ctx, tx, task, rules); err != nil {
+			return Task{}, false, err
+		}
+		// The rules may have changed the task's subtask counts.
+		if task, err = getTask(ctx, tx, taskID); err != nil {
+			return Task{}, false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Task{}, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return task, changed, nil
+}
+
+// applyCompletionRules propagates task's new completion state to its descendants and ancestors.
+// It must run in the transaction that changed task, after the change.
+func applyCompletionRules(ctx context.Context, tx pgx.Tx, task Task, rules CompletionRules) error {
+	if task.IsCompleted && rules.CompleteSubtasks {
+		query := `
+			WITH RECURSIVE descendants(task_id) AS (
+				SELECT task_id FROM tasks WHERE parent_task_id = $1
+				UNION
+				SELECT t.task_id FROM tasks t JOIN descendants d ON t.parent_task_id = d.task_id
+			)
+			UPDATE tasks SET is_completed = true, completed_at = $2
+			WHERE task_id IN (SELECT task_id FROM descendants) AND NOT is_completed`
+
+		if _, err := tx.Exec(ctx, query, task.TaskID, task.CompletedAt); err != nil {
+			return fmt.Errorf("unable to complete subtasks: %w", err)
+		}
+	}
+
+	if task.ParentTaskID == nil || !rules.CompleteParent {
+		return nil
+	}
+
+	if task.IsCompleted {
+		// Walk up from the parent, stopping at the first ancestor that still has another open child.
+		query := `
+			WITH RECURSIVE ancestors(task_id, parent_task_id) AS (
+				SELECT p.task_id, p.parent_task_id FROM tasks p
+				WHERE p.task_id = $1
+					AND NOT EXISTS (SELECT 1 FROM tasks c WHERE c.parent_task_id = p.task_id AND NOT c.is_completed)
+				UNION
+				SELECT p.task_id, p.parent_task_id FROM tasks p
+				JOIN ancestors a ON p.task_id = a.parent_task_id
+				WHERE NOT EXISTS (
+					SELECT 1 FROM tasks c
+					WHERE c.parent_task_id = p.task_id AND c.task_id <> a.task_id AND NOT c.is_completed
+				)
+			)
+			UPDATE tasks SET is_completed = true, completed_at = $2
+			WHERE task_id IN (SELECT task_id FROM ancestors) AND NOT is_completed`
+
+		if _, err := tx.Exec(ctx, query, *task.ParentTaskID, task.CompletedAt); err != nil {
+			return fmt.Errorf("unable to complete parent tasks: %w", err)
+		}
+		return nil
+	}
+
+	query := `
+		WITH RECURSIVE ancestors(task_id, parent_task_id) AS (
+			SELECT task_id, parent_task_id FROM tasks WHERE task_id = $1
+			UNION
+			SELECT p.task_id, p.parent_task_id FROM tasks p JOIN ancestors a ON p.task_id = a.parent_task_id
+		)
+		UPDATE tasks SET is_completed = false, completed_at = NULL
+		WHERE task_id IN (SELECT task_id FROM ancestors) AND is_completed`
+
+	if _, err := tx.Exec(ctx, query, *task.ParentTaskID); err != nil {
+		return fmt.Errorf("unable to reopen parent tasks: %w", err)
+	}
+	return nil
 }
 
 func (m *TaskModel) DeleteTaskByID(taskID uuid.UUID, userID uuid.UUID) (int64, error) {
@@ -261,110 +490,85 @@ func ValidateTask(task *Task, v *Validator) {
 	}
 }
 
-// BulkUpdateTaskOrder updates the order of sibling tasks for a user, project, and parent_task_id.
-// All tasks must belong to the same user, project, and parent_task_id.
+// TaskOrderUpdate sets the order of one task in BulkUpdateTaskOrder.
 type TaskOrderUpdate struct {
-	TaskID string `json:"task_id"`
-	Order  int    `json:"order"`
+	TaskID uuid.UUID `json:"task_id"`
+	Order  int       `json:"order"`
 }
 
-func (m *TaskModel) BulkUpdateTaskOrder(userID uuid.UUID, projectID *uuid.UUID, parentTaskID *uuid.UUID, updates []TaskOrderUpdate) error {
-	if len(updates) == 0 {
-		return nil
-	}
-
-	tx, err := m.DB.Begin(context.Background())
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer func() {
-		if err != nil {
-			tx.Rollback(context.Background())
-		}
-	}()
-
-	// Build the CASE statement and collect task IDs
-	caseStmt := "CASE"
-	taskIDs := make([]string, 0, len(updates))
-	for _, upd := range updates {
-		caseStmt += " WHEN task_id = '" + upd.TaskID + "' THEN " + fmt.Sprintf("%d", upd.Order)
-		taskIDs = append(taskIDs, "'"+upd.TaskID+"'")
-	}
-	caseStmt += " END"
-
-	// Build the WHERE clause for sibling tasks
-	where := "user_id = $1"
-	args := []interface{}{userID}
-	argIdx := 2
-	if projectID != nil {
-		where += fmt.Sprintf(" AND project_id = $%d", argIdx)
-		args = append(args, *projectID)
-		argIdx++
-	} else {
-		where += fmt.Sprintf(" AND project_id IS NULL")
-	}
-	if parentTaskID != nil {
-		where += fmt.Sprintf(" AND parent_task_id = $%d", argIdx)
-		args = append(args, *parentTaskID)
-		argIdx++
-	} else {
-		where += fmt.Sprintf(" AND parent_task_id IS NULL")
-	}
-
-	where += fmt.Sprintf(" AND task_id IN (%s)", joinStrings(taskIDs, ", "))
-
-	query := fmt.Sprintf(`UPDATE tasks SET "order" = %s WHERE %s`, caseStmt, where)
-
-	_, err = tx.Exec(context.Background(), query, args...)
-	if err != nil {
-		tx.Rollback(context.Background())
-		return fmt.Errorf("failed to update task order: %w", err)
-	}
-
-	if err = tx.Commit(context.Background()); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-	return nil
+// TaskOrderRejection explains why BulkUpdateTaskOrder refused a reorder.
+type TaskOrderRejection struct {
+	// Rejected lists IDs that don't exist, aren't owned by the user or aren't siblings of the first owned task.
+	Rejected []uuid.UUID `json:"rejected"`
+	// Missing lists siblings that were left out of the request.
+	Missing []uuid.UUID `json:"missing"`
+	// DuplicateIDs and DuplicateOrders list values that appear more than once in the request.
+	DuplicateIDs    []uuid.UUID `json:"duplicate_ids"`
+	DuplicateOrders []int       `json:"duplicate_orders"`
 }
 
-// joinStrings joins a slice of strings with a separator.
-func joinStrings(strs []string, sep string) string {
-	if len(strs) == 0 {
-		return ""
+// BulkUpdateTaskOrder sets the order of a complete sibling list in a single statement. The list is
+// the one holding the first task in updates that userID owns; every task in it must appear exactly
+// once, with distinct orders. Otherwise nothing is updated and ErrInvalidReorder is returned
+// together with the offending IDs and orders.
+func (m *TaskModel) BulkUpdateTaskOrder(userID uuid.UUID, updates []TaskOrderUpdate) (TaskOrderRejection, error) {
+	ids := make([]uuid.UUID, len(updates))
+	orders := make([]int32, len(updates))
+	for i, u := range updates {
+		ids[i] = u.TaskID
+		orders[i] = int32(u.Order)
 	}
-	result := strs[0]
-	for i := 1; i < len(strs); i++ {
-		result += sep + strs[i]
-	}
-	return result
-}
 
-// GetTaskByID fetches a single task by task_id and user_id
-func (m *TaskModel) GetTaskByID(taskID uuid.UUID, userID uuid.UUID) (Task, error) {
+	// The checks and the update share one snapshot; the update only runs when every check is empty.
 	query := `
-		SELECT task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, "order", labels, created_at
-		FROM tasks
-		WHERE task_id = $1 AND user_id = $2
-	`
-	var task Task
-	err := m.DB.QueryRow(context.Background(), query, taskID, userID).Scan(
-		&task.TaskID,
-		&task.ProjectID,
-		&task.UserID,
-		&task.Content,
-		&task.Description,
-		&task.DueDate,
-		&task.DueDatetime,
-		&task.Priority,
-		&task.IsCompleted,
-		&task.CompletedAt,
-		&task.ParentTaskID,
-		&task.Order,
-		&task.Labels,
-		&task.CreatedAt,
-	)
+		WITH input AS (
+			SELECT task_id, new_order, idx
+			FROM unnest($2::uuid[], $3::int[]) WITH ORDINALITY AS u(task_id, new_order, idx)
+		), anchor AS (
+			SELECT t.project_id, t.parent_task_id
+			FROM tasks t JOIN input i ON i.task_id = t.task_id
+			WHERE t.user_id = $1
+			ORDER BY i.idx
+			LIMIT 1
+		), siblings AS (
+			SELECT t.task_id
+			FROM tasks t, anchor a
+			WHERE t.user_id = $1
+				AND t.project_id IS NOT DISTINCT FROM a.project_id
+				AND t.parent_task_id IS NOT DISTINCT FROM a.parent_task_id
+		), rejected AS (
+			SELECT DISTINCT task_id FROM input WHERE task_id NOT IN (SELECT task_id FROM siblings)
+		), missing AS (
+			SELECT task_id FROM siblings WHERE task_id NOT IN (SELECT task_id FROM input)
+		), duplicate_ids AS (
+			SELECT task_id FROM input GROUP BY task_id HAVING count(*) > 1
+		), duplicate_orders AS (
+			SELECT new_order FROM input GROUP BY new_order HAVING count(*) > 1
+		), updated AS (
+			UPDATE tasks t SET "order" = i.new_order
+			FROM input i
+			WHERE t.task_id = i.task_id AND t.user_id = $1
+				AND NOT EXISTS (SELECT 1 FROM rejected)
+				AND NOT EXISTS (SELECT 1 FROM missing)
+				AND NOT EXISTS (SELECT 1 FROM duplicate_ids)
+				AND NOT EXISTS (SELECT 1 FROM duplicate_orders)
+			RETURNING t.task_id
+		)
+		SELECT
+			COALESCE((SELECT array_agg(task_id) FROM rejected), '{}'),
+			COALESCE((SELECT array_agg(task_id) FROM missing), '{}'),
+			COALESCE((SELECT array_agg(task_id) FROM duplicate_ids), '{}'),
+			COALESCE((SELECT array_agg(new_order ORDER BY new_order) FROM duplicate_orders), '{}'),
+			(SELECT count(*) FROM updated)`
+
+	var r TaskOrderRejection
+	var updated int
+	err := m.DB.QueryRow(context.Background(), query, userID, ids, orders).Scan(&r.Rejected, &r.Missing, &r.DuplicateIDs, &r.DuplicateOrders, &updated)
 	if err != nil {
-		return Task{}, fmt.Errorf("unable to fetch task: %w", err)
+		return TaskOrderRejection{}, fmt.Errorf("failed to update task order: %w", err)
 	}
-	return task, nil
+	if len(r.Rejected)+len(r.Missing)+len(r.DuplicateIDs)+len(r.DuplicateOrders) > 0 {
+		return r, ErrInvalidReorder
+	}
+	return TaskOrderRejection{}, nil
 }
@@ -0,0 +1,439 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TemplateVersion is the version of the template format written by this server.
+const TemplateVersion = 1
+
+// Template is a named, reusable snapshot of a project tree.
+type Template struct {
+	TemplateID  uuid.UUID       `json:"template_id"`
+	UserID      uuid.UUID       `json:"user_id"`
+	Name        string          `json:"name"`
+	Description *string         `json:"description"`
+	Project     TemplateProject `json:"project"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// TemplateProject is a project inside a template. Slice order is the order tasks and
+// sub-projects are recreated in.
+type TemplateProject struct {
+	ProjectName string            `json:"project_name"`
+	Color       *string           `json:"color,omitempty"`
+	Tasks       []TemplateTask    `json:"tasks"`
+	SubProjects []TemplateProject `json:"sub_projects,omitempty"`
+}
+
+// TemplateTask is a task inside a template. DueOffsetDays is relative to the anchor date
+// chosen when the template is instantiated.
+type TemplateTask struct {
+	Content       string         `json:"content"`
+	Description   string         `json:"description,omitempty"`
+	Priority      int16          `json:"priority,omitempty"`
+	DueOffsetDays *int           `json:"due_offset_days,omitempty"`
+	Labels        []string       `json:"labels,omitempty"`
+	Subtasks      []TemplateTask `json:"subtasks,omitempty"`
+}
+
+// TemplateFile is the portable JSON form used to export and import templates.
+type TemplateFile struct {
+	Version     int             `json:"version"`
+	Name        string          `json:"name"`
+	Description *string         `json:"description,omitempty"`
+	Project     TemplateProject `json:"project"`
+}
+
+type TemplateModel struct {
+	DB *pgxpool.Pool
+}
+
+// SaveProjectAsTemplate snapshots a project, its sub-projects and their tasks as a template.
+// Due dates are stored as day offsets from anchor; when anchor is nil the earliest due date
+// in the project is used.
+func (m *TemplateModel) SaveProjectAsTemplate(projectID, userID uuid.UUID, name string, description *string, anchor *time.Time) (Template, error) {
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return Template{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	projectQuery := `
+		WITH RECURSIVE subtree(project_id) AS (
+			SELECT project_id FROM projects WHERE project_id = $1 AND user_id = $2
+			UNION
+			SELECT p.project_id FROM projects p JOIN subtree s ON p.parent_project_id = s.project_id
+		)
+		SELECT ` + projectColumns + `
+		FROM projects
+		WHERE project_id IN (SELECT project_id FROM subtree)
+		ORDER BY created_at ASC`
+
+	rows, err := tx.Query(ctx, projectQuery, projectID, userID)
+	if err != nil {
+		return Template{}, fmt.Errorf("unable to query projects: %w", err)
+	}
+	projects, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Project, error) {
+		var p Project
+		err := scanProject(row, &p)
+		return p, err
+	})
+	if err != nil {
+		return Template{}, fmt.Errorf("unable to scan row: %w", err)
+	}
+	if len(projects) == 0 {
+		return Template{}, ErrNoRecord
+	}
+
+	projectIDs := make([]uuid.UUID, 0, len(projects))
+	for _, p := range projects {
+		projectIDs = append(projectIDs, p.ProjectID)
+	}
+
+	taskQuery := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE project_id = ANY($1) AND user_id = $2
+		ORDER BY "order" ASC, created_at ASC`
+
+	tasks, err := queryTasks(ctx, tx, taskQuery, projectIDs, userID)
+	if err != nil {
+		return Template{}, err
+	}
+
+	if anchor == nil {
+		for _, task := range tasks {
+			if task.DueDate != nil && (anchor == nil || task.DueDate.Before(*anchor)) {
+				anchor = task.DueDate
+			}
+		}
+	}
+
+	template := Template{
+		UserID:      userID,
+		Name:        name,
+		Description: description,
+		Project:     buildTemplateProject(projectID, projects, tasks, anchor),
+	}
+
+	query := `
+		INSERT INTO templates (user_id, name, description, content)
+		VALUES ($1, $2, $3, $4)
+		RETURNING template_id, created_at`
+
+	err = tx.QueryRow(ctx, query, userID, name, description, template.Project).Scan(&template.TemplateID, &template.CreatedAt)
+	if err != nil {
+		return Template{}, fmt.Errorf("unable to save template: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Template{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return template, nil
+}
+
+// ImportTemplate stores a template read from a TemplateFile.
+func (m *TemplateModel) ImportTemplate(userID uuid.UUID, file TemplateFile) (Template, error) {
+	query := `
+		INSERT INTO templates (user_id, name, description, content)
+		VALUES ($1, $2, $3, $4)
+		RETURNING template_id, user_id, name, description, content, created_at`
+
+	var template Template
+	err := m.DB.QueryRow(context.Background(), query, userID, file.Name, file.Description, file.Project).Scan(
+		&template.TemplateID,
+		&template.UserID,
+		&template.Name,
+		&template.Description,
+		&template.Project,
+		&template.CreatedAt,
+	)
+	if err != nil {
+		return Template{}, fmt.Errorf("unable to import template: %w", err)
+	}
+	return template, nil
+}
+
+func (m *TemplateModel) GetTemplatesByUserID(userID uuid.UUID) ([]Template, error) {
+	query := `
+		SELECT template_id, user_id, name, description, content, created_at
+		FROM templates
+		WHERE user_id = $1
+		ORDER BY name ASC`
+
+	rows, err := m.DB.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []Template
+	for rows.Next() {
+		var template Template
+		err := rows.Scan(
+			&template.TemplateID,
+			&template.UserID,
+			&template.Name,
+			&template.Description,
+			&template.Project,
+			&template.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan template: %w", err)
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+func (m *TemplateModel) GetTemplateByID(templateID, userID uuid.UUID) (Template, error) {
+	query := `
+		SELECT template_id, user_id, name, description, content, created_at
+		FROM templates
+		WHERE template_id = $1 AND user_id = $2`
+
+	var template Template
+	err := m.DB.QueryRow(context.Background(), query, templateID, userID).Scan(
+		&template.TemplateID,
+		&template.UserID,
+		&template.Name,
+		&template.Description,
+		&template.Project,
+		&template.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Template{}, ErrNoRecord
+	}
+	if err != nil {
+		return Template{}, fmt.Errorf("unable to fetch template: %w", err)
+	}
+	return template, nil
+}
+
+func (m *TemplateModel) DeleteTemplateByID(templateID, userID uuid.UUID) (int64, error) {
+	query := `DELETE FROM templates WHERE template_id = $1 AND user_id = $2`
+
+	result, err := m.DB.Exec(context.Background(), query, templateID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete template: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// InstantiateTemplate creates a new project tree from a template in a single transaction.
+// Each task's due date is anchor plus its offset. projectName overrides the root project's name.
+func (m *TemplateModel) InstantiateTemplate(templateID, userID uuid.UUID, anchor time.Time, projectName *string) (Project, error) {
+	template, err := m.GetTemplateByID(templateID, userID)
+	if err != nil {
+		return Project{}, err
+	}
+
+	root := template.Project
+	if projectName != nil {
+		root.ProjectName = *projectName
+	}
+
+	ctx := context.Background()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return Project{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rootID, err := instantiateTemplateProject(ctx, tx, userID, root, nil, anchor)
+	if err != nil {
+		return Project{}, err
+	}
+
+	var project Project
+	err = scanProject(tx.QueryRow(ctx, `SELECT `+projectColumns+` FROM projects WHERE project_id = $1`, rootID), &project)
+	if err != nil {
+		return Project{}, fmt.Errorf("unable to fetch project: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Project{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return project, nil
+}
+
+// instantiateTemplateProject inserts tp (and, recursively, its sub-projects and tasks) under parentID.
+func instantiateTemplateProject(ctx context.Context, tx pgx.Tx, userID uuid.UUID, tp TemplateProject, parentID *uuid.UUID, anchor time.Time) (uuid.UUID, error) {
+	projectID := uuid.New()
+	_, err := tx.Exec(ctx, `
+		INSERT INTO projects (project_id, user_id, project_name, color, parent_project_id, "order")
+		VALUES ($1, $2, $3, $4, $5,
+			(SELECT COALESCE(max("order") + 1, 0) FROM projects WHERE user_id = $2 AND parent_project_id IS NOT DISTINCT FROM $5::uuid))`,
+		projectID, userID, tp.ProjectName, tp.Color, parentID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unable to create project: %w", err)
+	}
+
+	labelIDs, err := ensureLabels(ctx, tx, userID, templateLabelNames(tp.Tasks))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// insertTaskCopy maps template-local IDs to fresh ones, so every template task gets a
+	// placeholder ID and its subtasks point at it.
+	idMap := map[uuid.UUID]uuid.UUID{}
+	projectMap := map[uuid.UUID]uuid.UUID{projectID: projectID}
+	var insert func(tasks []TemplateTask, parent *uuid.UUID) error
+	insert = func(tasks []TemplateTask, parent *uuid.UUID) error {
+		for i, tt := range tasks {
+			placeholder := uuid.New()
+			task := Task{
+				TaskID:       placeholder,
+				ProjectID:    &projectID,
+				Content:      tt.Content,
+				Description:  tt.Description,
+				Priority:     tt.Priority,
+				ParentTaskID: parent,
+				Order:        i * OrderGap,
+			}
+			for _, name := range tt.Labels {
+				task.LabelIDs = append(task.LabelIDs, labelIDs[strings.ToLower(name)])
+			}
+			if tt.DueOffsetDays != nil {
+				due := anchor.AddDate(0, 0, *tt.DueOffsetDays)
+				task.DueDate = &due
+			}
+			if err := insertTaskCopy(ctx, tx, task, userID, idMap, projectMap, DuplicateOptions{}); err != nil {
+				return err
+			}
+			if err := insert(tt.Subtasks, &placeholder); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := insert(tp.Tasks, nil); err != nil {
+		return uuid.Nil, err
+	}
+
+	for _, sub := range tp.SubProjects {
+		if _, err := instantiateTemplateProject(ctx, tx, userID, sub, &projectID, anchor); err != nil {
+			return uuid.Nil, err
+		}
+	}
+	return projectID, nil
+}
+
+// templateLabelNames collects the label names used anywhere in tasks and their subtasks.
+func templateLabelNames(tasks []TemplateTask) []string {
+	var names []string
+	for _, tt := range tasks {
+		names = append(names, tt.Labels...)
+		names = append(names, templateLabelNames(tt.Subtasks)...)
+	}
+	return names
+}
+
+// buildTemplateProject converts the project rooted at rootID into its template form.
+func buildTemplateProject(rootID uuid.UUID, projects []Project, tasks []Task, anchor *time.Time) TemplateProject {
+	tasksByProject := map[uuid.UUID][]Task{}
+	for _, task := range tasks {
+		tasksByProject[*task.ProjectID] = append(tasksByProject[*task.ProjectID], task)
+	}
+
+	var build func(p Project) TemplateProject
+	build = func(p Project) TemplateProject {
+		tp := TemplateProject{
+			ProjectName: p.ProjectName,
+			Color:       p.Color,
+			Tasks:       buildTemplateTasks(tasksByProject[p.ProjectID], anchor),
+		}
+		for _, child := range projects {
+			if child.ParentProjectID != nil && *child.ParentProjectID == p.ProjectID {
+				tp.SubProjects = append(tp.SubProjects, build(child))
+			}
+		}
+		return tp
+	}
+
+	for _, p := range projects {
+		if p.ProjectID == rootID {
+			return build(p)
+		}
+	}
+	return TemplateProject{}
+}
+
+// buildTemplateTasks nests a project's tasks under their parents, keeping sibling order.
+func buildTemplateTasks(tasks []Task, anchor *time.Time) []TemplateTask {
+	present := make(map[uuid.UUID]bool, len(tasks))
+	for _, task := range tasks {
+		present[task.TaskID] = true
+	}
+	children := map[uuid.UUID][]Task{}
+	var roots []Task
+	for _, task := range tasks {
+		if task.ParentTaskID != nil && present[*task.ParentTaskID] {
+			children[*task.ParentTaskID] = append(children[*task.ParentTaskID], task)
+		} else {
+			roots = append(roots, task)
+		}
+	}
+
+	var build func(siblings []Task) []TemplateTask
+	build = func(siblings []Task) []TemplateTask {
+		sort.SliceStable(siblings, func(i, j int) bool { return siblings[i].Order < siblings[j].Order })
+		out := make([]TemplateTask, 0, len(siblings))
+		for _, task := range siblings {
+			tt := TemplateTask{
+				Content:     task.Content,
+				Description: task.Description,
+				Priority:    task.Priority,
+				Labels:      task.Labels,
+				Subtasks:    build(children[task.TaskID]),
+			}
+			if task.DueDate != nil && anchor != nil {
+				offset := int(task.DueDate.Sub(*anchor).Hours() / 24)
+				tt.DueOffsetDays = &offset
+			}
+			out = append(out, tt)
+		}
+		return out
+	}
+	return build(roots)
+}
+
+// ValidateTemplateFile checks an imported template before it is stored.
+func ValidateTemplateFile(file *TemplateFile, v *Validator) {
+	v.Check(file.Version == TemplateVersion, "version", fmt.Sprintf("Template version must be %d", TemplateVersion))
+	v.Check(file.Name != "", "name", "Template name is required")
+	validateTemplateProject(&file.Project, "project", v)
+}
+
+func validateTemplateProject(tp *TemplateProject, field string, v *Validator) {
+	v.Check(tp.ProjectName != "", field+".project_name", "Project name is required")
+	for i := range tp.Tasks {
+		validateTemplateTask(&tp.Tasks[i], fmt.Sprintf("%s.tasks[%d]", field, i), v)
+	}
+	for i := range tp.SubProjects {
+		validateTemplateProject(&tp.SubProjects[i], fmt.Sprintf("%s.sub_projects[%d]", field, i), v)
+	}
+}
+
+func validateTemplateTask(tt *TemplateTask, field string, v *Validator) {
+	v.Check(tt.Content != "", field+".content", "Content is required")
+	for i, name := range tt.Labels {
+		validateLabelName(name, fmt.Sprintf("%s.labels[%d]", field, i), v)
+	}
+	for i := range tt.Subtasks {
+		validateTemplateTask(&tt.Subtasks[i], fmt.Sprintf("%s.subtasks[%d]", field, i), v)
+	}
+}
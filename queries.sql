@@ -1,98 +1,158 @@
 -- The queries.sql file is a reference for all the SQL statements used in the application.
 
-CREATE TABLE IF NOT EXISTS public.projects (
-    project_id uuid NOT NULL DEFAULT gen_random_uuid(),
-    user_id uuid NOT NULL,
-    project_name character varying NOT NULL,
-    color character varying,
-    is_inbox boolean DEFAULT false,
-    parent_project_id uuid,
-    created_at timestamp with time zone NOT NULL DEFAULT now(),
-    CONSTRAINT projects_pkey PRIMARY KEY (project_id),
-    CONSTRAINT projects_parent_project_id_fkey FOREIGN KEY (parent_project_id) REFERENCES public.projects(project_id),
-    CONSTRAINT projects_user_id_fkey FOREIGN KEY (user_id) REFERENCES auth.users(id)
-);
-
-CREATE TABLE IF NOT EXISTS public.labels (
-    label_id uuid NOT NULL DEFAULT gen_random_uuid(),
-    user_id uuid NOT NULL,
-    name character varying NOT NULL,
-    created_at timestamp with time zone NOT NULL DEFAULT now(),
-    CONSTRAINT labels_pkey PRIMARY KEY (label_id),
-    CONSTRAINT labels_user_id_fkey FOREIGN KEY (user_id) REFERENCES auth.users(id),
-    CONSTRAINT labels_user_name_unique UNIQUE (user_id, name)
-);
-
-CREATE TABLE IF NOT EXISTS public.tasks (
-    task_id uuid NOT NULL,
-    project_id uuid,
-    user_id uuid NOT NULL,
-    content text NOT NULL,
-    description text,
-    due_date date,
-    due_datetime time without time zone,
-    priority smallint,
-    is_completed boolean DEFAULT false,
-    completed_at timestamp with time zone,
-    parent_task_id uuid,
-    "order" integer NOT NULL DEFAULT 0,
-    labels jsonb DEFAULT '[]'::jsonb,
-    created_at timestamp with time zone NOT NULL DEFAULT now(),
-    CONSTRAINT tasks_pkey PRIMARY KEY (task_id),
-    CONSTRAINT tasks_parent_task_id_fkey FOREIGN KEY (parent_task_id) REFERENCES public.tasks(task_id) ON UPDATE CASCADE ON DELETE CASCADE,
-    CONSTRAINT tasks_project_id_fkey FOREIGN KEY (project_id) REFERENCES public.projects(project_id) ON DELETE CASCADE,
-    CONSTRAINT tasks_user_id_fkey FOREIGN KEY (user_id) REFERENCES auth.users(id)
-);
+-- The schema is defined by the versioned migrations in internal/migrations; run `migrate up`
+-- to apply them. This file only lists the queries.
 
 
 -- The queries below are used in the projects model.
 
 -- AddProject
 INSERT INTO projects (
-    user_id, project_name, color, is_inbox, parent_project_id
+    user_id, project_name, color, is_inbox, parent_project_id, is_favorite, "order"
 ) VALUES (
-    $1, $2, $3, $4, $5
-) RETURNING project_id, user_id, project_name, color, is_inbox, parent_project_id;
+    $1, $2, $3, $4, $5, $6,
+    (SELECT COALESCE(max("order") + 1, 0) FROM projects WHERE user_id = $1 AND parent_project_id IS NOT DISTINCT FROM $5::uuid)
+) RETURNING project_id, user_id, project_name, color, is_inbox, parent_project_id, is_archived, is_favorite, "order", created_at;
 
 -- EditProjectByID
 UPDATE projects SET
     project_name = $3,
     color = $4,
     is_inbox = $5,
-    parent_project_id = $6
+    parent_project_id = $6,
+    is_favorite = $7
 WHERE project_id = $1 AND user_id = $2
-RETURNING project_id, user_id, project_name, color, is_inbox, parent_project_id;
+RETURNING project_id, user_id, project_name, color, is_inbox, parent_project_id, is_archived, is_favorite, "order", created_at;
 
--- GetProjectsByUserID
-SELECT project_id, user_id, project_name, color, is_inbox, parent_project_id
+-- GetProjectsByUserID ($2 includes archived projects)
+SELECT project_id, user_id, project_name, color, is_inbox, parent_project_id, is_archived, is_favorite, "order", created_at
 FROM projects
-WHERE user_id = $1
-ORDER BY project_name ASC;
+WHERE user_id = $1 AND ($2 OR NOT is_archived)
+ORDER BY "order" ASC, created_at ASC;
+
+-- ArchiveProject (archives or unarchives the project and all of its sub-projects)
+WITH RECURSIVE subtree(project_id) AS (
+    SELECT project_id FROM projects WHERE project_id = $1 AND user_id = $2
+    UNION
+    SELECT p.project_id FROM projects p JOIN subtree s ON p.parent_project_id = s.project_id
+)
+UPDATE projects SET is_archived = $3
+WHERE project_id IN (SELECT project_id FROM subtree)
+RETURNING project_id, user_id, project_name, color, is_inbox, parent_project_id, is_archived, is_favorite, "order", created_at;
+
+-- BulkUpdateProjectOrder (runs in a transaction after checking ownership and a shared parent)
+SELECT count(*), count(DISTINCT COALESCE(parent_project_id, '00000000-0000-0000-0000-000000000000'))
+FROM projects
+WHERE project_id = ANY($1) AND user_id = $2;
+
+UPDATE projects p SET "order" = u.new_order
+FROM unnest($1::uuid[], $2::int[]) AS u(project_id, new_order)
+WHERE p.project_id = u.project_id AND p.user_id = $3;
 
 -- DeleteProjectByID
 DELETE FROM projects WHERE project_id = $1 AND user_id = $2;
 
+-- DuplicateProject (runs in a transaction; projects, then their tasks, are re-inserted with fresh IDs)
+WITH RECURSIVE subtree(project_id, depth) AS (
+    SELECT project_id, 0 FROM projects WHERE project_id = $1 AND user_id = $2
+    UNION
+    SELECT p.project_id, s.depth + 1 FROM projects p JOIN subtree s ON p.parent_project_id = s.project_id
+)
+SELECT project_id, user_id, project_name, color, is_inbox, parent_project_id, is_archived, is_favorite, "order", created_at
+FROM projects
+JOIN subtree USING (project_id)
+ORDER BY depth ASC, created_at ASC;
+
+SELECT task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, "order", labels, label_ids, assignee_id, created_by, created_at
+FROM tasks
+WHERE project_id = ANY($1) AND user_id = $2;
+
+-- AddProjectMember
+INSERT INTO project_members (project_id, user_id)
+SELECT project_id, $3 FROM projects WHERE project_id = $1 AND user_id = $2
+ON CONFLICT (project_id, user_id) DO UPDATE SET created_at = project_members.created_at
+RETURNING project_id, user_id, created_at;
+
+-- GetProjectMembers
+SELECT pm.project_id, pm.user_id, pm.created_at
+FROM project_members pm
+JOIN projects p ON p.project_id = pm.project_id
+WHERE pm.project_id = $1
+    AND (p.user_id = $2 OR EXISTS (
+        SELECT 1 FROM project_members me WHERE me.project_id = pm.project_id AND me.user_id = $2
+    ))
+ORDER BY pm.created_at ASC;
+
+-- RemoveProjectMember
+DELETE FROM project_members pm
+USING projects p
+WHERE pm.project_id = p.project_id
+    AND pm.project_id = $1 AND p.user_id = $2 AND pm.user_id = $3;
+
+-- IsProjectMember
+SELECT EXISTS (SELECT 1 FROM projects WHERE project_id = $1 AND user_id = $2)
+    OR EXISTS (SELECT 1 FROM project_members WHERE project_id = $1 AND user_id = $2);
+
 
 -- The queries below are used in the labels model.
 
--- AddLabel
+-- The label columns include computed usage counts:
+--   (SELECT count(*) FROM task_labels tl WHERE tl.label_id = labels.label_id) AS task_count,
+--   (SELECT count(*) FROM task_labels tl JOIN tasks t ON t.task_id = tl.task_id
+--    WHERE tl.label_id = labels.label_id AND NOT t.is_completed) AS open_task_count
+
+-- AddLabel (appended after the user's other labels; a 23505 unique violation is reported as a duplicate name)
 INSERT INTO labels (
-    user_id, name
+    user_id, name, color, is_favorite, "order"
 ) VALUES (
-    $1, $2
-) RETURNING label_id, user_id, name, created_at;
+    $1, $2, $3, $4, (SELECT COALESCE(max("order") + 1, 0) FROM labels WHERE user_id = $1)
+) RETURNING label_id, user_id, name, color, is_favorite, "order", task_count, open_task_count, created_at;
 
 -- EditLabelByID
 UPDATE labels SET
-    name = $3
+    name = $3,
+    color = $4,
+    is_favorite = $5
 WHERE label_id = $1 AND user_id = $2
-RETURNING label_id, user_id, name, created_at;
+RETURNING label_id, user_id, name, color, is_favorite, "order", task_count, open_task_count, created_at;
 
 -- GetLabelsByUserID
-SELECT label_id, user_id, name, created_at
+SELECT label_id, user_id, name, color, is_favorite, "order", task_count, open_task_count, created_at
 FROM labels
 WHERE user_id = $1
-ORDER BY name ASC;
+ORDER BY "order" ASC, name ASC;
+
+-- ReorderLabels ($2 lists label IDs in their new order; unlisted labels follow in their current order)
+WITH input AS (
+    SELECT label_id, position FROM unnest($2::uuid[]) WITH ORDINALITY AS i(label_id, position)
+), ranked AS (
+    SELECT l.label_id, ROW_NUMBER() OVER (ORDER BY i.position ASC NULLS LAST, l."order" ASC, l.name ASC) - 1 AS position
+    FROM labels l
+    LEFT JOIN input i ON i.label_id = l.label_id
+    WHERE l.user_id = $1
+)
+UPDATE labels SET "order" = ranked.position
+FROM ranked
+WHERE labels.label_id = ranked.label_id AND labels."order" <> ranked.position;
+
+-- MergeLabels ($1 target, $2 sources; runs in one transaction, deleting sources cascades to task_labels)
+INSERT INTO task_labels (task_id, label_id)
+SELECT DISTINCT task_id, $1::uuid FROM task_labels WHERE label_id = ANY($2)
+ON CONFLICT (task_id, label_id) DO NOTHING;
+DELETE FROM labels WHERE label_id = ANY($2) AND user_id = $3;
+
+-- BulkLabelTasks (match tasks, then one statement per label added or removed)
+SELECT task_id FROM tasks
+WHERE user_id = $1
+    AND ($2::uuid[] IS NULL OR task_id = ANY($2))
+    AND ($3::uuid IS NULL OR project_id = $3)
+    AND ($4::uuid IS NULL OR EXISTS (SELECT 1 FROM task_labels tl WHERE tl.task_id = tasks.task_id AND tl.label_id = $4))
+    AND ($5::boolean IS NULL OR is_completed = $5)
+FOR UPDATE;
+INSERT INTO task_labels (task_id, label_id)
+SELECT unnest($1::uuid[]), $2
+ON CONFLICT (task_id, label_id) DO NOTHING;
+DELETE FROM task_labels WHERE task_id = ANY($1) AND label_id = $2;
 
 -- DeleteLabelByID
 DELETE FROM labels WHERE label_id = $1 AND user_id = $2;
@@ -100,14 +160,27 @@ DELETE FROM labels WHERE label_id = $1 AND user_id = $2;
 
 -- The queries below are used in the tasks model.
 
--- AddTask
+-- NextOrder (appends after the siblings; $4 is the order gap)
+SELECT COALESCE(max("order") + $4, 0) FROM tasks
+WHERE user_id = $1 AND project_id IS NOT DISTINCT FROM $2::uuid AND parent_task_id IS NOT DISTINCT FROM $3::uuid;
+
+-- AddTask (runs in a transaction with SetTaskLabels, then re-reads the task; "order" defaults to NextOrder)
 INSERT INTO tasks (
-    task_id, project_id, user_id, content, description, due_date, due_datetime, priority, parent_task_id, "order", labels
+    task_id, project_id, user_id, content, description, due_date, due_datetime, priority, parent_task_id, "order", assignee_id, created_by
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
-) RETURNING task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, "order", labels;
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $3
+);
+
+-- SetTaskLabels ($2 is the full list of label IDs, all owned by user $3)
+SELECT count(*) FROM labels WHERE label_id = ANY($2) AND user_id = $3;
+DELETE FROM task_labels WHERE task_id = $1 AND label_id <> ALL($2);
+INSERT INTO task_labels (task_id, label_id)
+SELECT $1, unnest($2::uuid[])
+ON CONFLICT (task_id, label_id) DO NOTHING;
+
+-- EditTaskByID (runs in a transaction with the reassignment check below)
+SELECT assignee_id FROM tasks WHERE task_id = $1 AND user_id = $2 FOR UPDATE;
 
--- EditTaskByID
 UPDATE tasks SET
     project_id = $3,
     content = $4,
@@ -119,24 +192,385 @@ UPDATE tasks SET
     completed_at = $10,
     parent_task_id = $11,
     "order" = $12,
-    labels = $13
-WHERE task_id = $1 AND user_id = $2
-RETURNING task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, order, labels;
+    assignee_id = $13
+WHERE task_id = $1 AND user_id = $2;
+
+-- RecordTaskEvent (e.g. event_type 'reassigned' when assignee_id changes)
+INSERT INTO task_history (task_id, user_id, event_type, old_value, new_value)
+VALUES ($1, $2, $3, $4, $5);
 
 -- GetTasksByUserID
-SELECT task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, order, labels, created_at
+-- Every query returning a full task also computes "blocked":
+--   EXISTS (SELECT 1 FROM task_dependencies d JOIN tasks b ON b.task_id = d.blocked_by_task_id
+--           WHERE d.task_id = tasks.task_id AND NOT b.is_completed)
+-- its label names and IDs, sorted by name:
+--   COALESCE((SELECT array_agg(l.name ORDER BY l.name) FROM task_labels tl JOIN labels l ON l.label_id = tl.label_id
+--             WHERE tl.task_id = tasks.task_id), '{}') AS labels,
+--   COALESCE((SELECT array_agg(l.label_id ORDER BY l.name) FROM task_labels tl JOIN labels l ON l.label_id = tl.label_id
+--             WHERE tl.task_id = tasks.task_id), '{}') AS label_ids
+-- and the progress of its direct subtasks:
+--   (SELECT count(*) FROM tasks s WHERE s.parent_task_id = tasks.task_id) AS subtask_count,
+--   (SELECT count(*) FROM tasks s WHERE s.parent_task_id = tasks.task_id AND s.is_completed) AS completed_subtask_count
+SELECT task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, "order", labels, label_ids, assignee_id, created_by, created_at, blocked, subtask_count, completed_subtask_count
 FROM tasks
-WHERE user_id = $1
+WHERE (user_id = $1 OR assignee_id = $1)
+    AND ($2::uuid IS NULL OR assignee_id = $2)
+    AND ($3 OR NOT is_completed)
 ORDER BY created_at ASC;
 
--- ToggleTaskCompleted
-UPDATE tasks SET is_completed = NOT is_completed, completed_at = CASE WHEN is_completed THEN NULL ELSE CURRENT_TIMESTAMP END WHERE task_id = $1 AND user_id = $2;
+-- GetCompletedTasks (live and archived completed tasks; $3 is the exclusive upper bound)
+SELECT task_id, project_id, user_id, content, description, due_date, due_datetime, priority, completed_at, parent_task_id, "order", labels, assignee_id, created_by, created_at, NULL::timestamptz AS archived_at
+FROM tasks
+WHERE (user_id = $1 OR assignee_id = $1) AND is_completed
+    AND ($2::timestamptz IS NULL OR completed_at >= $2)
+    AND ($3::timestamptz IS NULL OR completed_at < $3)
+    AND ($4::uuid IS NULL OR project_id = $4)
+UNION ALL
+SELECT task_id, project_id, user_id, content, description, due_date, due_datetime, priority, completed_at, parent_task_id, "order", labels, assignee_id, created_by, created_at, archived_at
+FROM archived_tasks
+WHERE (user_id = $1 OR assignee_id = $1)
+    AND ($2::timestamptz IS NULL OR completed_at >= $2)
+    AND ($3::timestamptz IS NULL OR completed_at < $3)
+    AND ($4::uuid IS NULL OR project_id = $4)
+ORDER BY completed_at DESC, task_id
+LIMIT $5;
+
+-- ArchiveCompletedTasks (top-level trees whose every task was completed before $1)
+WITH RECURSIVE tree(root_id, task_id) AS (
+    SELECT task_id, task_id FROM tasks
+    WHERE parent_task_id IS NULL AND is_completed AND completed_at < $1
+    UNION
+    SELECT tr.root_id, t.task_id FROM tasks t JOIN tree tr ON t.parent_task_id = tr.task_id
+), eligible AS (
+    SELECT tr.root_id
+    FROM tree tr
+    JOIN tasks t ON t.task_id = tr.task_id
+    GROUP BY tr.root_id
+    HAVING bool_and(t.is_completed AND t.completed_at < $1)
+), archived AS (
+    INSERT INTO archived_tasks (task_id, project_id, user_id, content, description, due_date, due_datetime, priority, completed_at, parent_task_id, "order", labels, assignee_id, created_by, created_at)
+    SELECT task_id, project_id, user_id, content, description, due_date, due_datetime, priority, completed_at, parent_task_id, "order", labels, assignee_id, created_by, created_at
+    FROM tasks
+    WHERE task_id IN (SELECT task_id FROM tree WHERE root_id IN (SELECT root_id FROM eligible))
+    RETURNING task_id
+)
+DELETE FROM tasks WHERE task_id IN (SELECT task_id FROM archived);
+
+-- ToggleTaskCompleted / SetTaskCompleted (optionally refused while the task is blocked; the
+-- toggle passes NOT is_completed as $2). Nothing is written when the task is already in the
+-- requested state. The full task is then re-read with the task columns.
+SELECT is_completed, blocked FROM tasks WHERE task_id = $1 AND (user_id = $2 OR assignee_id = $2) FOR UPDATE;
+UPDATE tasks SET is_completed = $2, completed_at = CASE WHEN $2 THEN COALESCE($3, CURRENT_TIMESTAMP) END WHERE task_id = $1;
+
+-- CompleteSubtasks (completion rule, same transaction as the completion change)
+WITH RECURSIVE descendants(task_id) AS (
+    SELECT task_id FROM tasks WHERE parent_task_id = $1
+    UNION
+    SELECT t.task_id FROM tasks t JOIN descendants d ON t.parent_task_id = d.task_id
+)
+UPDATE tasks SET is_completed = true, completed_at = $2
+WHERE task_id IN (SELECT task_id FROM descendants) AND NOT is_completed;
+
+-- CompleteParentWithLastSubtask (completion rule, $1 is the completed task's parent)
+WITH RECURSIVE ancestors(task_id, parent_task_id) AS (
+    SELECT p.task_id, p.parent_task_id FROM tasks p
+    WHERE p.task_id = $1
+        AND NOT EXISTS (SELECT 1 FROM tasks c WHERE c.parent_task_id = p.task_id AND NOT c.is_completed)
+    UNION
+    SELECT p.task_id, p.parent_task_id FROM tasks p
+    JOIN ancestors a ON p.task_id = a.parent_task_id
+    WHERE NOT EXISTS (
+        SELECT 1 FROM tasks c
+        WHERE c.parent_task_id = p.task_id AND c.task_id <> a.task_id AND NOT c.is_completed
+    )
+)
+UPDATE tasks SET is_completed = true, completed_at = $2
+WHERE task_id IN (SELECT task_id FROM ancestors) AND NOT is_completed;
+
+-- ReopenAncestors (completion rule, $1 is the reopened task's parent)
+WITH RECURSIVE ancestors(task_id, parent_task_id) AS (
+    SELECT task_id, parent_task_id FROM tasks WHERE task_id = $1
+    UNION
+    SELECT p.task_id, p.parent_task_id FROM tasks p JOIN ancestors a ON p.task_id = a.parent_task_id
+)
+UPDATE tasks SET is_completed = false, completed_at = NULL
+WHERE task_id IN (SELECT task_id FROM ancestors) AND is_completed;
+
+-- MoveTask (runs in a transaction; the order comes from NextOrder or the PositionTask queries)
+UPDATE tasks SET project_id = $2, parent_task_id = $3, "order" = $4 WHERE task_id = $1;
+
+-- Subtasks follow the task into its new project
+WITH RECURSIVE descendants(task_id) AS (
+    SELECT task_id FROM tasks WHERE parent_task_id = $1
+    UNION
+    SELECT t.task_id FROM tasks t JOIN descendants d ON t.parent_task_id = d.task_id
+)
+UPDATE tasks SET project_id = $2
+WHERE task_id IN (SELECT task_id FROM descendants) AND project_id IS DISTINCT FROM $2::uuid;
+
+-- PositionTask (runs in a transaction; the new order is the midpoint of the neighbours' orders)
+SELECT project_id, parent_task_id FROM tasks WHERE task_id = $1 AND user_id = $2 FOR UPDATE;
+
+-- Ranks of the after ($5) and before ($6) neighbours and the orders of the list without the task ($4)
+WITH siblings AS (
+    SELECT task_id, "order", ROW_NUMBER() OVER (ORDER BY "order" ASC, created_at ASC) AS rn
+    FROM tasks
+    WHERE user_id = $1 AND project_id IS NOT DISTINCT FROM $2::uuid AND parent_task_id IS NOT DISTINCT FROM $3::uuid AND task_id <> $4
+)
+SELECT
+    (SELECT rn FROM siblings WHERE task_id = $5),
+    (SELECT rn FROM siblings WHERE task_id = $6),
+    array_agg("order" ORDER BY rn)
+FROM siblings;
+
+-- Rebalance a list when its neighbours have no room left ($5 is the order gap)
+UPDATE tasks t SET "order" = s.position
+FROM (
+    SELECT task_id, (ROW_NUMBER() OVER (ORDER BY "order" ASC, created_at ASC) - 1) * $5 AS position
+    FROM tasks
+    WHERE user_id = $1 AND project_id IS NOT DISTINCT FROM $2::uuid AND parent_task_id IS NOT DISTINCT FROM $3::uuid
+        AND task_id <> $4
+) s
+WHERE t.task_id = s.task_id AND t."order" <> s.position;
+
+UPDATE tasks SET "order" = $2 WHERE task_id = $1;
+
+-- RebalanceTaskOrders (background job; respreads lists with ties, no room, or orders beyond $1)
+WITH ranked AS (
+    SELECT user_id, project_id, parent_task_id, "order",
+        "order" - lag("order") OVER (PARTITION BY user_id, project_id, parent_task_id ORDER BY "order" ASC, created_at ASC) AS gap
+    FROM tasks
+), crowded AS (
+    SELECT DISTINCT user_id, project_id, parent_task_id
+    FROM ranked
+    WHERE gap < 2 OR abs("order") > $1
+), renumbered AS (
+    SELECT t.task_id,
+        (ROW_NUMBER() OVER (PARTITION BY t.user_id, t.project_id, t.parent_task_id ORDER BY t."order" ASC, t.created_at ASC) - 1) * $2 AS position
+    FROM tasks t
+    JOIN crowded c ON c.user_id = t.user_id
+        AND c.project_id IS NOT DISTINCT FROM t.project_id
+        AND c.parent_task_id IS NOT DISTINCT FROM t.parent_task_id
+)
+UPDATE tasks t SET "order" = r.position
+FROM renumbered r
+WHERE t.task_id = r.task_id AND t."order" <> r.position;
+
+-- DropTask (runs in a transaction: the MoveTask queries with a PositionTask order, then both
+-- lists are rebalanced and re-read)
+SELECT task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, "order", created_at
+FROM tasks
+WHERE user_id = $1 AND project_id IS NOT DISTINCT FROM $2::uuid AND parent_task_id IS NOT DISTINCT FROM $3::uuid
+ORDER BY "order" ASC, created_at ASC;
+
+-- BulkUpdateTaskOrder ($2 and $3 are parallel task ID and order arrays; nothing is updated
+-- unless the input is exactly one complete sibling list with unique IDs and orders)
+WITH input AS (
+    SELECT task_id, new_order, idx
+    FROM unnest($2::uuid[], $3::int[]) WITH ORDINALITY AS u(task_id, new_order, idx)
+), anchor AS (
+    SELECT t.project_id, t.parent_task_id
+    FROM tasks t JOIN input i ON i.task_id = t.task_id
+    WHERE t.user_id = $1
+    ORDER BY i.idx
+    LIMIT 1
+), siblings AS (
+    SELECT t.task_id
+    FROM tasks t, anchor a
+    WHERE t.user_id = $1
+        AND t.project_id IS NOT DISTINCT FROM a.project_id
+        AND t.parent_task_id IS NOT DISTINCT FROM a.parent_task_id
+), rejected AS (
+    SELECT DISTINCT task_id FROM input WHERE task_id NOT IN (SELECT task_id FROM siblings)
+), missing AS (
+    SELECT task_id FROM siblings WHERE task_id NOT IN (SELECT task_id FROM input)
+), duplicate_ids AS (
+    SELECT task_id FROM input GROUP BY task_id HAVING count(*) > 1
+), duplicate_orders AS (
+    SELECT new_order FROM input GROUP BY new_order HAVING count(*) > 1
+), updated AS (
+    UPDATE tasks t SET "order" = i.new_order
+    FROM input i
+    WHERE t.task_id = i.task_id AND t.user_id = $1
+        AND NOT EXISTS (SELECT 1 FROM rejected)
+        AND NOT EXISTS (SELECT 1 FROM missing)
+        AND NOT EXISTS (SELECT 1 FROM duplicate_ids)
+        AND NOT EXISTS (SELECT 1 FROM duplicate_orders)
+    RETURNING t.task_id
+)
+SELECT
+    COALESCE((SELECT array_agg(task_id) FROM rejected), '{}'),
+    COALESCE((SELECT array_agg(task_id) FROM missing), '{}'),
+    COALESCE((SELECT array_agg(task_id) FROM duplicate_ids), '{}'),
+    COALESCE((SELECT array_agg(new_order ORDER BY new_order) FROM duplicate_orders), '{}'),
+    (SELECT count(*) FROM updated);
+
+-- DuplicateTask (runs in a transaction; each row of the subtree is re-inserted with a fresh task_id)
+WITH RECURSIVE subtree(task_id) AS (
+    SELECT task_id FROM tasks WHERE task_id = $1 AND user_id = $2
+    UNION
+    SELECT t.task_id FROM tasks t JOIN subtree s ON t.parent_task_id = s.task_id
+)
+SELECT task_id, project_id, user_id, content, description, due_date, due_datetime, priority, is_completed, completed_at, parent_task_id, "order", labels, label_ids, assignee_id, created_by, created_at
+FROM tasks
+WHERE task_id IN (SELECT task_id FROM subtree);
+
+-- GetTaskHistory
+SELECT h.event_id, h.task_id, h.user_id, h.event_type, h.old_value, h.new_value, h.created_at
+FROM task_history h
+JOIN tasks t ON t.task_id = h.task_id
+WHERE h.task_id = $1 AND (t.user_id = $2 OR t.assignee_id = $2)
+ORDER BY h.created_at ASC;
 
 -- DeleteTaskByID
 DELETE FROM tasks WHERE task_id = $1 AND user_id = $2;
 
 -- AddLabelToTask
-UPDATE tasks SET labels = labels || $2::jsonb WHERE task_id = $1 AND user_id = $3;
+INSERT INTO task_labels (task_id, label_id)
+SELECT t.task_id, l.label_id
+FROM tasks t JOIN labels l ON l.user_id = t.user_id
+WHERE t.task_id = $1 AND l.label_id = $2 AND t.user_id = $3
+ON CONFLICT (task_id, label_id) DO NOTHING;
 
 -- RemoveLabelFromTask
-UPDATE tasks SET labels = labels - $2 WHERE task_id = $1 AND user_id = $3;
+DELETE FROM task_labels tl
+USING tasks t
+WHERE tl.task_id = t.task_id AND tl.task_id = $1 AND tl.label_id = $2 AND t.user_id = $3;
+
+
+-- The queries below are used for task dependencies.
+
+-- AddDependency (runs in a transaction, serialized per user)
+SELECT pg_advisory_xact_lock(hashtextextended($1::text, 0));
+
+WITH RECURSIVE upstream(task_id) AS (
+    SELECT blocked_by_task_id FROM task_dependencies WHERE task_id = $1
+    UNION
+    SELECT d.blocked_by_task_id
+    FROM task_dependencies d
+    JOIN upstream u ON d.task_id = u.task_id
+)
+SELECT EXISTS (SELECT 1 FROM upstream WHERE task_id = $2);
+
+INSERT INTO task_dependencies (task_id, blocked_by_task_id, user_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (task_id, blocked_by_task_id) DO UPDATE SET created_at = task_dependencies.created_at
+RETURNING task_id, blocked_by_task_id, user_id, created_at;
+
+-- RemoveDependency
+DELETE FROM task_dependencies WHERE task_id = $1 AND blocked_by_task_id = $2 AND user_id = $3;
+
+-- GetTaskDependencies
+SELECT blocked_by_task_id, task_id
+FROM task_dependencies
+WHERE user_id = $2 AND (task_id = $1 OR blocked_by_task_id = $1)
+ORDER BY created_at ASC;
+
+
+-- The queries below are used in the settings model.
+
+-- GetSettings
+SELECT user_id, complete_subtasks_with_parent, complete_parent_with_last_subtask, daily_goal, timezone, updated_at
+FROM user_settings
+WHERE user_id = $1;
+
+-- UpdateSettings
+INSERT INTO user_settings (user_id, complete_subtasks_with_parent, complete_parent_with_last_subtask, daily_goal, timezone)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id) DO UPDATE SET
+    complete_subtasks_with_parent = EXCLUDED.complete_subtasks_with_parent,
+    complete_parent_with_last_subtask = EXCLUDED.complete_parent_with_last_subtask,
+    daily_goal = EXCLUDED.daily_goal,
+    timezone = EXCLUDED.timezone,
+    updated_at = now()
+RETURNING user_id, complete_subtasks_with_parent, complete_parent_with_last_subtask, daily_goal, timezone, updated_at;
+
+
+-- The queries below are used for stats. Each one starts from the user's live and archived tasks
+-- ($1 is the user, $2 the timezone from their settings):
+--   WITH user_tasks AS (
+--       SELECT task_id, project_id, is_completed, completed_at, created_at, due_date, due_datetime
+--       FROM tasks WHERE user_id = $1 OR assignee_id = $1
+--       UNION ALL
+--       SELECT task_id, project_id, true, completed_at, created_at, due_date, due_datetime
+--       FROM archived_tasks WHERE user_id = $1 OR assignee_id = $1
+--   )
+
+-- GetStats: completions per day ($3 days, today last)
+SELECT to_char(d.day, 'YYYY-MM-DD'), count(t.task_id)
+FROM generate_series((now() AT TIME ZONE $2)::date - ($3::int - 1), (now() AT TIME ZONE $2)::date, interval '1 day') AS d(day)
+LEFT JOIN user_tasks t ON t.is_completed AND (t.completed_at AT TIME ZONE $2)::date = d.day::date
+GROUP BY d.day
+ORDER BY d.day;
+
+-- GetStats: streaks (days with at least $3 completions; consecutive days share day - row_number)
+WITH goal_days AS (
+    SELECT (completed_at AT TIME ZONE $2)::date AS day FROM user_tasks WHERE is_completed GROUP BY 1 HAVING count(*) >= $3
+), runs AS (
+    SELECT max(day) AS last_day, count(*) AS length
+    FROM (SELECT day, day - (ROW_NUMBER() OVER (ORDER BY day))::int AS island FROM goal_days) g
+    GROUP BY island
+)
+SELECT COALESCE(max(length) FILTER (WHERE last_day >= (now() AT TIME ZONE $2)::date - 1), 0), COALESCE(max(length), 0)
+FROM runs;
+
+-- GetStats: average completion time and overdue count
+SELECT
+    avg(extract(epoch FROM completed_at - created_at)) FILTER (WHERE is_completed AND completed_at IS NOT NULL) / 3600,
+    count(*) FILTER (WHERE NOT is_completed AND (
+        due_date < (now() AT TIME ZONE $2)::date
+        OR (due_date = (now() AT TIME ZONE $2)::date AND due_datetime < (now() AT TIME ZONE $2)::time)
+    ))
+FROM user_tasks;
+
+
+-- The queries below are used in the templates model.
+
+-- SaveProjectAsTemplate / ImportTemplate
+INSERT INTO templates (user_id, name, description, content)
+VALUES ($1, $2, $3, $4)
+RETURNING template_id, user_id, name, description, content, created_at;
+
+-- GetTemplatesByUserID
+SELECT template_id, user_id, name, description, content, created_at
+FROM templates
+WHERE user_id = $1
+ORDER BY name ASC;
+
+-- GetTemplateByID
+SELECT template_id, user_id, name, description, content, created_at
+FROM templates
+WHERE template_id = $1 AND user_id = $2;
+
+-- DeleteTemplateByID
+DELETE FROM templates WHERE template_id = $1 AND user_id = $2;
+
+
+-- The queries below are used in the idempotency model.
+
+-- Reserve (claims a new or expired key; no row returned means the key is in use)
+INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, expires_at)
+VALUES ($1, $2, $3, now() + $4::interval)
+ON CONFLICT (user_id, idempotency_key) DO UPDATE SET
+    request_hash = EXCLUDED.request_hash,
+    status_code = NULL,
+    content_type = NULL,
+    response_body = NULL,
+    created_at = now(),
+    expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= now()
+RETURNING true;
+
+SELECT request_hash, status_code, COALESCE(content_type, ''), response_body
+FROM idempotency_keys
+WHERE user_id = $1 AND idempotency_key = $2;
+
+-- Save
+UPDATE idempotency_keys SET status_code = $3, content_type = $4, response_body = $5
+WHERE user_id = $1 AND idempotency_key = $2;
+
+-- Release
+DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2;
+
+-- DeleteExpired
+DELETE FROM idempotency_keys WHERE expires_at <= now();